@@ -0,0 +1,117 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// Page selects a window of a listing. A zero Limit returns everything after
+// Offset.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+func paginate[T any](items []T, page Page) []T {
+	if page.Offset >= len(items) {
+		return []T{}
+	}
+	items = items[max(page.Offset, 0):]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// RouteFilter narrows ListRoutes; empty fields match every route.
+type RouteFilter struct {
+	Service    string
+	Method     string
+	PathPrefix string
+}
+
+// ListRoutes returns the routes matching filter in key order, within page,
+// and how many match in total.
+func (r *Registry) ListRoutes(filter RouteFilter, page Page) ([]domain.RouteEntry, int) {
+	entries, err := r.repo.GetAllRoutes(context.Background())
+	if err != nil {
+		slog.Error("failed to get routes", "error", err)
+		return nil, 0
+	}
+
+	var matched []domain.RouteEntry
+	for _, entry := range entries {
+		if filter.Service != "" && entry.ServiceName != filter.Service {
+			continue
+		}
+		if filter.Method != "" && !strings.EqualFold(entry.Route.Method, filter.Method) {
+			continue
+		}
+		if filter.PathPrefix != "" && !strings.HasPrefix(entry.Route.FullPath(entry.BasePath), filter.PathPrefix) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Route.Key(matched[i].BasePath) < matched[j].Route.Key(matched[j].BasePath)
+	})
+	return paginate(matched, page), len(matched)
+}
+
+// InstanceFilter narrows ListInstances; empty fields match every instance.
+type InstanceFilter struct {
+	Service string
+	Status  domain.ServiceStatus
+}
+
+// ListInstances returns the instances matching filter ordered by service,
+// within page, and how many match in total.
+func (r *Registry) ListInstances(filter InstanceFilter, page Page) ([]*domain.ServiceInstance, int) {
+	services := r.GetAllServices()
+	names := make([]string, 0, len(services))
+	for name := range services {
+		if filter.Service == "" || name == filter.Service {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var matched []*domain.ServiceInstance
+	for _, name := range names {
+		for _, instance := range services[name] {
+			if filter.Status == "" || instance.Status == filter.Status {
+				matched = append(matched, instance)
+			}
+		}
+	}
+	return paginate(matched, page), len(matched)
+}
+
+// DrainInstance stops sending new requests to the instance, which keeps its
+// routes until it deregisters or its heartbeat expires.
+func (r *Registry) DrainInstance(instanceID string) error {
+	return r.SetInstanceStatus(instanceID, domain.StatusDraining)
+}
+
+// SetInstanceRotation puts the instance back in rotation as healthy, leaving
+// it to the health checks from then on, or takes it out as disabled.
+func (r *Registry) SetInstanceRotation(instanceID string, inRotation bool) error {
+	status := domain.StatusDisabled
+	if inRotation {
+		status = domain.StatusHealthy
+	}
+	return r.SetInstanceStatus(instanceID, status)
+}
+
+// SetInstanceWeight changes the load balancing weight of the instance.
+func (r *Registry) SetInstanceWeight(instanceID string, weight int) error {
+	if weight < 1 || weight > domain.MaxWeight {
+		return fmt.Errorf("%w: weight must be between 1 and %d", domain.ErrInvalidRequest, domain.MaxWeight)
+	}
+	return r.repo.UpdateInstanceWeight(context.Background(), instanceID, weight)
+}
@@ -0,0 +1,254 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func setupAdminRegistry(t *testing.T) (*Registry, map[string]string) {
+	t.Helper()
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	ids := make(map[string]string)
+	for _, req := range []*domain.RegisterRequest{
+		{
+			ServiceName: "orders",
+			Host:        "localhost",
+			Port:        8081,
+			BasePath:    "/orders",
+			Routes:      []domain.Route{{Method: "GET", Path: "/"}, {Method: "POST", Path: "/"}, {Method: "GET", Path: "/{id:[0-9]+}"}},
+		},
+		{
+			ServiceName: "users",
+			Host:        "localhost",
+			Port:        8082,
+			BasePath:    "/users",
+			Routes:      []domain.Route{{Method: "GET", Path: "/*"}},
+		},
+	} {
+		resp, err := registry.Register(req)
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+		ids[req.ServiceName] = resp.InstanceID
+	}
+	return registry, ids
+}
+
+func TestRegistry_ListRoutes(t *testing.T) {
+	registry, _ := setupAdminRegistry(t)
+
+	all, total := registry.ListRoutes(RouteFilter{}, Page{})
+	if total != 4 || len(all) != 4 {
+		t.Fatalf("expected 4 routes, got %d of %d", len(all), total)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Route.Key(all[i-1].BasePath) > all[i].Route.Key(all[i].BasePath) {
+			t.Fatal("expected routes in key order")
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter RouteFilter
+		want   int
+	}{
+		{"service", RouteFilter{Service: "orders"}, 3},
+		{"method ignores case", RouteFilter{Method: "get"}, 3},
+		{"path prefix", RouteFilter{PathPrefix: "/users"}, 1},
+		{"combined", RouteFilter{Service: "orders", Method: "POST"}, 1},
+		{"no match", RouteFilter{Service: "billing"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, total := registry.ListRoutes(tt.filter, Page{}); total != tt.want {
+				t.Errorf("expected %d routes, got %d", tt.want, total)
+			}
+		})
+	}
+
+	page, total := registry.ListRoutes(RouteFilter{}, Page{Limit: 2, Offset: 3})
+	if total != 4 || len(page) != 1 {
+		t.Errorf("expected the last route of 4, got %d of %d", len(page), total)
+	}
+	if page, _ := registry.ListRoutes(RouteFilter{}, Page{Offset: 10}); len(page) != 0 {
+		t.Errorf("expected no routes past the end, got %d", len(page))
+	}
+}
+
+func TestRegistry_ListInstances(t *testing.T) {
+	registry, ids := setupAdminRegistry(t)
+	_ = registry.SetInstanceRotation(ids["users"], false)
+
+	if _, total := registry.ListInstances(InstanceFilter{}, Page{}); total != 2 {
+		t.Errorf("expected 2 instances, got %d", total)
+	}
+	instances, total := registry.ListInstances(InstanceFilter{Status: domain.StatusDisabled}, Page{})
+	if total != 1 || instances[0].ID != ids["users"] {
+		t.Errorf("expected the disabled users instance, got %v", instances)
+	}
+	instances, _ = registry.ListInstances(InstanceFilter{Service: "orders"}, Page{})
+	if len(instances) != 1 || instances[0].ID != ids["orders"] {
+		t.Errorf("expected the orders instance, got %v", instances)
+	}
+}
+
+func TestRegistry_InstanceRotation(t *testing.T) {
+	registry, ids := setupAdminRegistry(t)
+	id := ids["orders"]
+
+	if err := registry.SetInstanceRotation(id, false); err != nil {
+		t.Fatalf("disable failed: %v", err)
+	}
+	if got := registry.GetInstance(id).Status; got != domain.StatusDisabled {
+		t.Errorf("expected disabled, got %s", got)
+	}
+	if len(registry.GetHealthyInstances("orders")) != 0 {
+		t.Error("expected a disabled instance out of rotation")
+	}
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/orders/")); entry == nil {
+		t.Error("expected a disabled instance to keep its routes")
+	}
+	if err := registry.SetInstanceRotation(id, true); err != nil {
+		t.Fatalf("enable failed: %v", err)
+	}
+	if len(registry.GetHealthyInstances("orders")) != 1 {
+		t.Error("expected the instance back in rotation")
+	}
+
+	if err := registry.SetInstanceRotation("missing", false); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_DrainInstance(t *testing.T) {
+	registry, ids := setupAdminRegistry(t)
+	id := ids["orders"]
+
+	if err := registry.DrainInstance(id); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	if got := registry.GetInstance(id).Status; got != domain.StatusDraining {
+		t.Errorf("expected draining, got %s", got)
+	}
+	if len(registry.GetHealthyInstances("orders")) != 0 {
+		t.Error("expected a draining instance out of rotation")
+	}
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/orders/")); entry == nil {
+		t.Error("expected a draining instance to keep its routes")
+	}
+	if err := registry.DrainInstance("missing"); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_SetInstanceWeight(t *testing.T) {
+	registry, ids := setupAdminRegistry(t)
+	id := ids["orders"]
+
+	if err := registry.SetInstanceWeight(id, 5); err != nil {
+		t.Fatalf("set weight failed: %v", err)
+	}
+	if got := registry.GetInstance(id).Weight; got != 5 {
+		t.Errorf("expected weight 5, got %d", got)
+	}
+
+	for _, weight := range []int{0, domain.MaxWeight + 1} {
+		if err := registry.SetInstanceWeight(id, weight); !errors.Is(err, domain.ErrInvalidRequest) {
+			t.Errorf("weight %d: expected ErrInvalidRequest, got %v", weight, err)
+		}
+	}
+	if err := registry.SetInstanceWeight("missing", 5); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+// racingRepository runs beforeWrite just before an instance is written, as
+// another replica changing or deleting the instance between a read and a write
+// would.
+type racingRepository struct {
+	*MemoryRepository
+	beforeWrite func(ctx context.Context, instanceID string)
+}
+
+func (r *racingRepository) SaveInstance(ctx context.Context, instance *domain.ServiceInstance) error {
+	r.beforeWrite(ctx, instance.ID)
+	return r.MemoryRepository.SaveInstance(ctx, instance)
+}
+
+func (r *racingRepository) UpdateInstanceWeight(ctx context.Context, instanceID string, weight int) error {
+	r.beforeWrite(ctx, instanceID)
+	return r.MemoryRepository.UpdateInstanceWeight(ctx, instanceID, weight)
+}
+
+func TestRegistry_SetInstanceWeightRacingOtherReplicas(t *testing.T) {
+	repo := &racingRepository{MemoryRepository: NewMemoryRepository(), beforeWrite: func(context.Context, string) {}}
+	registry := NewRegistryWithRepository(RegistryConfig{HeartbeatTTL: 30 * time.Second}, repo)
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "orders",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/orders",
+		Routes:      []domain.Route{{Method: "GET", Path: "/"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	id := resp.InstanceID
+
+	repo.beforeWrite = func(ctx context.Context, instanceID string) {
+		_ = repo.UpdateInstanceStatus(ctx, instanceID, domain.StatusDisabled)
+	}
+	if err := registry.SetInstanceWeight(id, 3); err != nil {
+		t.Fatalf("set weight failed: %v", err)
+	}
+	instance := registry.GetInstance(id)
+	if instance.Weight != 3 || instance.Status != domain.StatusDisabled {
+		t.Errorf("expected weight 3 and the status set meanwhile kept, got %d and %s", instance.Weight, instance.Status)
+	}
+
+	repo.beforeWrite = func(ctx context.Context, instanceID string) {
+		_ = repo.DeleteInstance(ctx, instanceID)
+	}
+	if err := registry.SetInstanceWeight(id, 5); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound for an instance deleted meanwhile, got %v", err)
+	}
+	if registry.GetInstance(id) != nil {
+		t.Error("setting the weight resurrected a deleted instance")
+	}
+}
+
+func TestRegistry_DumpRouteTable(t *testing.T) {
+	registry, _ := setupAdminRegistry(t)
+
+	dump := registry.DumpRouteTable()
+	get := dump.Methods["GET"]
+	if get == nil || get.Segment != "/" {
+		t.Fatalf("expected a GET tree rooted at /, got %v", dump.Methods)
+	}
+	if len(get.Children) != 2 || get.Children[0].Segment != "orders" || get.Children[1].Segment != "users" {
+		t.Fatalf("expected the orders and users segments in order, got %v", get.Children)
+	}
+
+	orders := get.Children[0]
+	if len(orders.Routes) != 1 || orders.Routes[0].Service != "orders" {
+		t.Errorf("expected GET /orders/ to end at the orders segment, got %v", orders.Routes)
+	}
+	if len(orders.Children) != 1 || orders.Children[0].Segment != "{param:^(?:[0-9]+)$}" {
+		t.Fatalf("expected a constrained parameter under orders, got %v", orders.Children[0].Segment)
+	}
+	if params := orders.Children[0].Routes[0].Params; len(params) != 1 || params[0] != "id" {
+		t.Errorf("expected the id parameter, got %v", params)
+	}
+
+	users := get.Children[1]
+	if len(users.Children) != 1 || users.Children[0].Segment != "*" {
+		t.Errorf("expected a catch-all under users, got %v", users.Children)
+	}
+	if _, ok := dump.Methods["POST"]; !ok {
+		t.Error("expected a POST tree")
+	}
+}
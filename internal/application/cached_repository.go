@@ -0,0 +1,262 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// CachedRepository serves the lookups made on every proxied request (routes,
+// healthy instances and traffic policies) from a local snapshot of a shared repository, so the
+// request path does not depend on the latency or availability of the backing
+// store. The snapshot is refreshed on an interval and after every write made
+// through this replica; all other operations go straight to the wrapped
+// repository, which stays the source of truth.
+type CachedRepository struct {
+	domain.Repository
+
+	interval  time.Duration
+	refreshMu sync.Mutex
+	snapshot  atomic.Pointer[registrySnapshot]
+	stopCh    chan struct{}
+}
+
+type registrySnapshot struct {
+	version uint64
+	routes  []domain.RouteEntry
+	byKey   map[string]domain.RouteEntry
+	healthy map[string][]*domain.ServiceInstance
+	traffic map[string]*domain.TrafficPolicy
+}
+
+func NewCachedRepository(repo domain.Repository, interval time.Duration) *CachedRepository {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &CachedRepository{
+		Repository: repo,
+		interval:   interval,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (c *CachedRepository) Start() {
+	if err := c.Refresh(context.Background()); err != nil {
+		slog.Warn("initial registry snapshot failed", "error", err)
+	}
+	go c.refreshLoop()
+}
+
+func (c *CachedRepository) Stop() {
+	close(c.stopCh)
+}
+
+func (c *CachedRepository) refreshLoop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Refresh(context.Background()); err != nil {
+				slog.Warn("registry snapshot refresh failed, serving previous snapshot", "error", err)
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Refresh reloads the snapshot from the wrapped repository. On error the
+// previous snapshot is kept.
+func (c *CachedRepository) Refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	routes, err := c.Repository.GetAllRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load routes: %w", err)
+	}
+	services, err := c.Repository.GetAllServices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load services: %w", err)
+	}
+
+	traffic, err := c.Repository.GetAllTrafficPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load traffic policies: %w", err)
+	}
+
+	snapshot := &registrySnapshot{
+		routes:  routes,
+		byKey:   make(map[string]domain.RouteEntry, len(routes)),
+		healthy: make(map[string][]*domain.ServiceInstance, len(services)),
+		traffic: traffic,
+	}
+	for _, entry := range routes {
+		snapshot.byKey[entry.Route.Key(entry.BasePath)] = entry
+	}
+	for _, serviceName := range services {
+		healthy, err := c.Repository.GetHealthyInstances(ctx, serviceName)
+		if err != nil {
+			return fmt.Errorf("failed to load instances of %s: %w", serviceName, err)
+		}
+		snapshot.healthy[serviceName] = healthy
+	}
+
+	snapshot.version = 1
+	if previous := c.snapshot.Load(); previous != nil {
+		snapshot.version = previous.version
+		if !reflect.DeepEqual(previous.byKey, snapshot.byKey) {
+			snapshot.version++
+		}
+	}
+	c.snapshot.Store(snapshot)
+	return nil
+}
+
+// RoutesVersion changes every time a refresh finds the routes changed, by
+// this replica or any other.
+func (c *CachedRepository) RoutesVersion() uint64 {
+	if snapshot := c.snapshot.Load(); snapshot != nil {
+		return snapshot.version
+	}
+	return 0
+}
+
+func (c *CachedRepository) current(ctx context.Context) (*registrySnapshot, error) {
+	if snapshot := c.snapshot.Load(); snapshot != nil {
+		return snapshot, nil
+	}
+	if err := c.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return c.snapshot.Load(), nil
+}
+
+// refreshAfterWrite makes a write visible to this replica immediately instead
+// of after the next tick.
+func (c *CachedRepository) refreshAfterWrite(ctx context.Context) {
+	if err := c.Refresh(ctx); err != nil {
+		slog.Warn("registry snapshot refresh after write failed", "error", err)
+	}
+}
+
+func (c *CachedRepository) GetRoute(ctx context.Context, method, path string) (*domain.RouteEntry, error) {
+	snapshot, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := snapshot.byKey[method+":"+path]
+	if !exists {
+		return nil, domain.ErrRouteNotFound
+	}
+	return &entry, nil
+}
+
+func (c *CachedRepository) GetAllRoutes(ctx context.Context) ([]domain.RouteEntry, error) {
+	snapshot, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]domain.RouteEntry(nil), snapshot.routes...), nil
+}
+
+func (c *CachedRepository) GetHealthyInstances(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	snapshot, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.healthy[serviceName], nil
+}
+
+func (c *CachedRepository) GetTrafficPolicy(ctx context.Context, serviceName string) (*domain.TrafficPolicy, error) {
+	snapshot, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy, exists := snapshot.traffic[serviceName]
+	if !exists {
+		return nil, domain.ErrTrafficPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (c *CachedRepository) SaveInstance(ctx context.Context, instance *domain.ServiceInstance) error {
+	if err := c.Repository.SaveInstance(ctx, instance); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) DeleteInstance(ctx context.Context, instanceID string) error {
+	if err := c.Repository.DeleteInstance(ctx, instanceID); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) UpdateInstanceStatus(ctx context.Context, instanceID string, status domain.ServiceStatus) error {
+	if err := c.Repository.UpdateInstanceStatus(ctx, instanceID, status); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) UpdateInstanceStatusIf(ctx context.Context, instanceID string, from, to domain.ServiceStatus) (bool, error) {
+	updated, err := c.Repository.UpdateInstanceStatusIf(ctx, instanceID, from, to)
+	if err != nil || !updated {
+		return updated, err
+	}
+	c.refreshAfterWrite(ctx)
+	return true, nil
+}
+
+func (c *CachedRepository) UpdateInstanceWeight(ctx context.Context, instanceID string, weight int) error {
+	if err := c.Repository.UpdateInstanceWeight(ctx, instanceID, weight); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) SaveRoutes(ctx context.Context, serviceName string, basePath string, routes []domain.Route) error {
+	if err := c.Repository.SaveRoutes(ctx, serviceName, basePath, routes); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) DeleteRoutesByService(ctx context.Context, serviceName string) error {
+	if err := c.Repository.DeleteRoutesByService(ctx, serviceName); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) SaveTrafficPolicy(ctx context.Context, serviceName string, policy *domain.TrafficPolicy) error {
+	if err := c.Repository.SaveTrafficPolicy(ctx, serviceName, policy); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
+
+func (c *CachedRepository) DeleteTrafficPolicy(ctx context.Context, serviceName string) error {
+	if err := c.Repository.DeleteTrafficPolicy(ctx, serviceName); err != nil {
+		return err
+	}
+	c.refreshAfterWrite(ctx)
+	return nil
+}
@@ -0,0 +1,96 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+type failingRepository struct {
+	*MemoryRepository
+	err error
+}
+
+func (f *failingRepository) GetAllRoutes(ctx context.Context) ([]domain.RouteEntry, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.MemoryRepository.GetAllRoutes(ctx)
+}
+
+func TestCachedRepository_ServesSnapshotUntilRefresh(t *testing.T) {
+	shared := NewMemoryRepository()
+	cache := NewCachedRepository(shared, time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.GetRoute(ctx, "GET", "/api/v1/users"); err != domain.ErrRouteNotFound {
+		t.Fatalf("expected ErrRouteNotFound, got %v", err)
+	}
+
+	// Written by another replica straight to the shared store.
+	_ = shared.SaveInstance(ctx, &domain.ServiceInstance{ID: "instance-1", ServiceName: "svc", Status: domain.StatusHealthy})
+	_ = shared.SaveRoutes(ctx, "svc", "/api/v1", []domain.Route{{Method: "GET", Path: "/users"}})
+
+	if _, err := cache.GetRoute(ctx, "GET", "/api/v1/users"); err != domain.ErrRouteNotFound {
+		t.Fatal("route from another replica should only appear after a refresh")
+	}
+
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if _, err := cache.GetRoute(ctx, "GET", "/api/v1/users"); err != nil {
+		t.Errorf("expected route after refresh, got %v", err)
+	}
+	if healthy, _ := cache.GetHealthyInstances(ctx, "svc"); len(healthy) != 1 {
+		t.Errorf("expected 1 healthy instance, got %d", len(healthy))
+	}
+}
+
+func TestCachedRepository_LocalWritesAreVisible(t *testing.T) {
+	cache := NewCachedRepository(NewMemoryRepository(), time.Minute)
+	ctx := context.Background()
+
+	_ = cache.SaveInstance(ctx, &domain.ServiceInstance{ID: "instance-1", ServiceName: "svc", Status: domain.StatusHealthy})
+	_ = cache.SaveRoutes(ctx, "svc", "/api/v1", []domain.Route{{Method: "GET", Path: "/users"}})
+
+	if routes, _ := cache.GetAllRoutes(ctx); len(routes) != 1 {
+		t.Fatalf("expected 1 route, got %d", len(routes))
+	}
+
+	_ = cache.UpdateInstanceStatus(ctx, "instance-1", domain.StatusUnhealthy)
+	if healthy, _ := cache.GetHealthyInstances(ctx, "svc"); len(healthy) != 0 {
+		t.Errorf("expected no healthy instances, got %d", len(healthy))
+	}
+
+	_ = cache.DeleteInstance(ctx, "instance-1")
+	if _, err := cache.GetRoute(ctx, "GET", "/api/v1/users"); err != domain.ErrRouteNotFound {
+		t.Errorf("routes of the last instance should be gone, got %v", err)
+	}
+}
+
+func TestCachedRepository_KeepsSnapshotWhenStoreFails(t *testing.T) {
+	store := &failingRepository{MemoryRepository: NewMemoryRepository()}
+	cache := NewCachedRepository(store, time.Minute)
+	ctx := context.Background()
+
+	_ = cache.SaveRoutes(ctx, "svc", "/api/v1", []domain.Route{{Method: "GET", Path: "/users"}})
+
+	store.err = errors.New("connection refused")
+	if err := cache.Refresh(ctx); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if _, err := cache.GetRoute(ctx, "GET", "/api/v1/users"); err != nil {
+		t.Errorf("expected previous snapshot to be served, got %v", err)
+	}
+}
+
+func TestCachedRepository_StartStop(t *testing.T) {
+	cache := NewCachedRepository(NewMemoryRepository(), 10*time.Millisecond)
+
+	cache.Start()
+	time.Sleep(25 * time.Millisecond)
+	cache.Stop()
+}
@@ -0,0 +1,267 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// breakerBuckets is how many slices the rolling window is divided into; older
+// slices are discarded as the window moves.
+const breakerBuckets = 10
+
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures trips the breaker after that many failures in a row.
+	// Zero disables the check.
+	ConsecutiveFailures int
+	// FailureRatio trips the breaker when failures make up at least that
+	// fraction of the requests seen in Window, once there are MinRequests of
+	// them. Zero disables the check.
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+	// OpenTimeout is how long an open breaker rejects traffic before letting
+	// HalfOpenRequests probes through. The breaker closes once they all
+	// succeed and opens again on the first failure.
+	OpenTimeout      time.Duration
+	HalfOpenRequests int
+}
+
+// CircuitBreakers keeps one breaker per upstream instance, so an instance that
+// times out or returns 5xx stops receiving traffic from this replica right
+// away instead of once its heartbeat expires.
+type CircuitBreakers struct {
+	config    CircuitBreakerConfig
+	mu        sync.Mutex
+	breakers  map[string]*breaker
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type breaker struct {
+	state       BreakerState
+	consecutive int
+	buckets     [breakerBuckets]breakerBucket
+	openedAt    time.Time
+	probes      int
+	probesOK    int
+	lastUsed    time.Time
+}
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+func NewCircuitBreakers(cfg CircuitBreakerConfig) *CircuitBreakers {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 1
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+
+	return &CircuitBreakers{
+		config:   cfg,
+		breakers: make(map[string]*breaker),
+		now:      time.Now,
+	}
+}
+
+// Filter returns the instances whose breaker currently lets requests through.
+// A half-open instance stays a candidate only while it has probes left.
+func (b *CircuitBreakers) Filter(instances []*domain.ServiceInstance) []*domain.ServiceInstance {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	available := make([]*domain.ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if br, exists := b.breakers[instance.ID]; !exists || br.available(now, b.config) {
+			available = append(available, instance)
+		}
+	}
+	return available
+}
+
+// Acquire reserves a request on the instance's breaker. It fails when the
+// breaker is open, or half-open with every probe already taken. Each
+// successful Acquire must be followed by Record or Release.
+func (b *CircuitBreakers) Acquire(instanceID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	br := b.breakers[instanceID]
+	if br == nil {
+		br = &breaker{state: BreakerClosed}
+		b.breakers[instanceID] = br
+	}
+	br.lastUsed = now
+
+	if !br.available(now, b.config) {
+		return false
+	}
+	if br.state == BreakerOpen {
+		br.state = BreakerHalfOpen
+		br.probes, br.probesOK = 0, 0
+	}
+	if br.state == BreakerHalfOpen {
+		br.probes++
+	}
+	return true
+}
+
+// Record reports the outcome of a request reserved with Acquire.
+func (b *CircuitBreakers) Record(instanceID string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	br := b.breakers[instanceID]
+	if br == nil {
+		return
+	}
+
+	switch br.state {
+	case BreakerHalfOpen:
+		if !success {
+			br.trip(now)
+			return
+		}
+		br.probesOK++
+		if br.probesOK >= b.config.HalfOpenRequests {
+			*br = breaker{state: BreakerClosed, lastUsed: now}
+		}
+	case BreakerClosed:
+		br.bucket(now, b.config.Window).add(success)
+		if success {
+			br.consecutive = 0
+			return
+		}
+		br.consecutive++
+		if b.shouldTrip(br, now) {
+			br.trip(now)
+		}
+	}
+}
+
+// Release gives back a request reserved with Acquire without counting it, for
+// outcomes that say nothing about the instance such as a client disconnect.
+func (b *CircuitBreakers) Release(instanceID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br := b.breakers[instanceID]; br != nil && br.state == BreakerHalfOpen && br.probes > 0 {
+		br.probes--
+	}
+}
+
+// State returns the state of the instance's breaker, as it would be seen by
+// the next request.
+func (b *CircuitBreakers) State(instanceID string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.breakers[instanceID]
+	if br == nil {
+		return BreakerClosed
+	}
+	if br.state == BreakerOpen && !b.now().Before(br.openedAt.Add(b.config.OpenTimeout)) {
+		return BreakerHalfOpen
+	}
+	return br.state
+}
+
+func (b *CircuitBreakers) shouldTrip(br *breaker, now time.Time) bool {
+	if b.config.ConsecutiveFailures > 0 && br.consecutive >= b.config.ConsecutiveFailures {
+		return true
+	}
+	if b.config.FailureRatio <= 0 {
+		return false
+	}
+
+	successes, failures := br.totals(now, b.config.Window)
+	total := successes + failures
+	return total >= b.config.MinRequests && float64(failures) >= b.config.FailureRatio*float64(total)
+}
+
+// sweep forgets breakers that no longer hold anything back: closed ones idle
+// for a whole window, and any left untouched past their open timeout, so
+// instances that left the registry do not accumulate. Callers must hold mu.
+func (b *CircuitBreakers) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.config.Window {
+		return
+	}
+	b.lastSweep = now
+
+	for id, br := range b.breakers {
+		idle := now.Sub(br.lastUsed)
+		if idle > b.config.Window+b.config.OpenTimeout || (br.state == BreakerClosed && idle > b.config.Window) {
+			delete(b.breakers, id)
+		}
+	}
+}
+
+func (br *breaker) available(now time.Time, cfg CircuitBreakerConfig) bool {
+	switch br.state {
+	case BreakerOpen:
+		return !now.Before(br.openedAt.Add(cfg.OpenTimeout))
+	case BreakerHalfOpen:
+		return br.probes < cfg.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+func (br *breaker) trip(now time.Time) {
+	*br = breaker{state: BreakerOpen, openedAt: now, lastUsed: now}
+}
+
+// bucket returns the window slice now falls in, resetting it if it last held
+// an older slice.
+func (br *breaker) bucket(now time.Time, window time.Duration) *breakerBucket {
+	width := max(window/breakerBuckets, time.Millisecond)
+	start := now.Truncate(width)
+	bucket := &br.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+func (br *breaker) totals(now time.Time, window time.Duration) (successes, failures int) {
+	for _, bucket := range br.buckets {
+		if now.Sub(bucket.start) < window {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	return successes, failures
+}
+
+func (bb *breakerBucket) add(success bool) {
+	if success {
+		bb.successes++
+	} else {
+		bb.failures++
+	}
+}
@@ -0,0 +1,163 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func newTestBreakers(cfg CircuitBreakerConfig) (*CircuitBreakers, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	breakers := NewCircuitBreakers(cfg)
+	breakers.now = func() time.Time { return now }
+	return breakers, &now
+}
+
+func fail(b *CircuitBreakers, id string, times int) {
+	for i := 0; i < times; i++ {
+		if b.Acquire(id) {
+			b.Record(id, false)
+		}
+	}
+}
+
+func TestCircuitBreakers_OpensAfterConsecutiveFailures(t *testing.T) {
+	breakers, _ := newTestBreakers(CircuitBreakerConfig{ConsecutiveFailures: 3})
+
+	fail(breakers, "a", 2)
+	if breakers.Acquire("a") {
+		breakers.Record("a", true)
+	}
+	fail(breakers, "a", 2)
+	if got := breakers.State("a"); got != BreakerClosed {
+		t.Fatalf("a success should reset the consecutive count, got %s", got)
+	}
+
+	fail(breakers, "a", 1)
+	if got := breakers.State("a"); got != BreakerOpen {
+		t.Fatalf("expected open after 3 consecutive failures, got %s", got)
+	}
+	if breakers.Acquire("a") {
+		t.Error("open breaker should reject requests")
+	}
+}
+
+func TestCircuitBreakers_OpensOnFailureRatio(t *testing.T) {
+	breakers, now := newTestBreakers(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  10,
+		Window:       10 * time.Second,
+	})
+
+	for i := 0; i < 9; i++ {
+		breakers.Acquire("a")
+		breakers.Record("a", i%2 == 0)
+	}
+	if got := breakers.State("a"); got != BreakerClosed {
+		t.Fatalf("should stay closed below MinRequests, got %s", got)
+	}
+
+	*now = now.Add(20 * time.Second)
+	breakers.Acquire("a")
+	breakers.Record("a", false)
+	if got := breakers.State("a"); got != BreakerClosed {
+		t.Fatalf("requests outside the window should not count, got %s", got)
+	}
+
+	for i := 0; i < 9; i++ {
+		breakers.Acquire("a")
+		breakers.Record("a", i%3 == 0)
+	}
+	if got := breakers.State("a"); got != BreakerOpen {
+		t.Fatalf("expected open with 7 of 10 requests failed, got %s", got)
+	}
+}
+
+func TestCircuitBreakers_HalfOpenProbeClosesOrReopens(t *testing.T) {
+	breakers, now := newTestBreakers(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenRequests:    1,
+	})
+
+	fail(breakers, "a", 1)
+	*now = now.Add(30 * time.Second)
+	if got := breakers.State("a"); got != BreakerHalfOpen {
+		t.Fatalf("expected half-open after the open timeout, got %s", got)
+	}
+
+	if !breakers.Acquire("a") {
+		t.Fatal("half-open breaker should let a probe through")
+	}
+	if breakers.Acquire("a") {
+		t.Fatal("half-open breaker should let a single probe through")
+	}
+	breakers.Record("a", false)
+	if got := breakers.State("a"); got != BreakerOpen {
+		t.Fatalf("failed probe should reopen the breaker, got %s", got)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if !breakers.Acquire("a") {
+		t.Fatal("half-open breaker should let a probe through")
+	}
+	breakers.Record("a", true)
+	if got := breakers.State("a"); got != BreakerClosed {
+		t.Fatalf("successful probe should close the breaker, got %s", got)
+	}
+}
+
+func TestCircuitBreakers_ReleaseFreesHalfOpenProbe(t *testing.T) {
+	breakers, now := newTestBreakers(CircuitBreakerConfig{ConsecutiveFailures: 1})
+
+	fail(breakers, "a", 1)
+	*now = now.Add(time.Minute)
+	if !breakers.Acquire("a") {
+		t.Fatal("half-open breaker should let a probe through")
+	}
+	breakers.Release("a")
+	if !breakers.Acquire("a") {
+		t.Error("released probe should be available again")
+	}
+}
+
+func TestCircuitBreakers_FilterSkipsOpenInstances(t *testing.T) {
+	breakers, _ := newTestBreakers(CircuitBreakerConfig{ConsecutiveFailures: 1})
+	instances := []*domain.ServiceInstance{{ID: "a"}, {ID: "b"}}
+
+	fail(breakers, "a", 1)
+	available := breakers.Filter(instances)
+	if len(available) != 1 || available[0].ID != "b" {
+		t.Errorf("expected only b to be available, got %v", available)
+	}
+}
+
+func TestCircuitBreakers_SweepForgetsIdleBreakers(t *testing.T) {
+	breakers, now := newTestBreakers(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		Window:              10 * time.Second,
+		OpenTimeout:         30 * time.Second,
+	})
+
+	breakers.Acquire("closed")
+	breakers.Record("closed", true)
+	fail(breakers, "open", 1)
+
+	*now = now.Add(20 * time.Second)
+	breakers.Acquire("active")
+	breakers.Record("active", true)
+	if _, exists := breakers.breakers["closed"]; exists {
+		t.Error("idle closed breaker should be forgotten")
+	}
+	if _, exists := breakers.breakers["open"]; !exists {
+		t.Error("open breaker should be kept until its timeout passes")
+	}
+
+	*now = now.Add(30 * time.Second)
+	breakers.Acquire("active")
+	breakers.Record("active", true)
+	if _, exists := breakers.breakers["open"]; exists {
+		t.Error("open breaker idle past its timeout should be forgotten")
+	}
+}
@@ -1,6 +1,8 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"time"
 
@@ -35,61 +37,58 @@ func (r *Registry) cleanupLoop() {
 }
 
 func (r *Registry) cleanup() {
+	ctx := context.Background()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	now := time.Now()
-	var toRemove []string
-
-	for id, instance := range r.instances {
-		elapsed := now.Sub(instance.LastHeartbeat)
-
-		if elapsed > r.config.HeartbeatTTL*2 {
-			toRemove = append(toRemove, id)
-			slog.Info("removing expired instance",
-				"instance_id", id,
-				"service", instance.ServiceName,
-				"last_heartbeat", instance.LastHeartbeat,
-			)
-		} else if elapsed > r.config.HeartbeatTTL && instance.Status == domain.StatusHealthy {
-			instance.Status = domain.StatusUnhealthy
-			slog.Warn("marking instance unhealthy",
-				"instance_id", id,
-				"service", instance.ServiceName,
-				"elapsed", elapsed,
-			)
-		}
-	}
-
-	for _, id := range toRemove {
-		r.removeInstanceLocked(id)
-	}
-}
-
-func (r *Registry) removeInstanceLocked(instanceID string) {
-	instance, exists := r.instances[instanceID]
-	if !exists {
+	services, err := r.repo.GetAllServices(ctx)
+	if err != nil {
+		slog.Error("failed to list services for cleanup", "error", err)
 		return
 	}
 
-	s := instance.ServiceName
+	now := time.Now()
+	var toRemove []string
 
-	instanceIDs := r.services[s]
-	for i, id := range instanceIDs {
-		if id == instanceID {
-			r.services[s] = append(instanceIDs[:i], instanceIDs[i+1:]...)
-			break
+	for _, serviceName := range services {
+		instances, err := r.repo.GetInstancesByService(ctx, serviceName)
+		if err != nil {
+			slog.Error("failed to list instances for cleanup", "service", serviceName, "error", err)
+			continue
 		}
-	}
 
-	if len(r.services[s]) == 0 {
-		for key, entry := range r.routes {
-			if entry.ServiceName == s {
-				delete(r.routes, key)
+		for _, instance := range instances {
+			elapsed := now.Sub(instance.LastHeartbeat)
+
+			if elapsed > r.config.HeartbeatTTL*2 {
+				toRemove = append(toRemove, instance.ID)
+				slog.Info("removing expired instance",
+					"instance_id", instance.ID,
+					"service", instance.ServiceName,
+					"last_heartbeat", instance.LastHeartbeat,
+				)
+			} else if elapsed > r.config.HeartbeatTTL && instance.Status == domain.StatusHealthy {
+				updated, err := r.repo.UpdateInstanceStatusIf(ctx, instance.ID, domain.StatusHealthy, domain.StatusUnhealthy)
+				if err != nil {
+					slog.Error("failed to mark instance unhealthy", "instance_id", instance.ID, "error", err)
+					continue
+				}
+				if !updated {
+					continue
+				}
+				slog.Warn("marking instance unhealthy",
+					"instance_id", instance.ID,
+					"service", instance.ServiceName,
+					"elapsed", elapsed,
+				)
 			}
 		}
-		delete(r.services, s)
 	}
 
-	delete(r.instances, instanceID)
+	for _, id := range toRemove {
+		if err := r.repo.DeleteInstance(ctx, id); err != nil && !errors.Is(err, domain.ErrInstanceNotFound) {
+			slog.Error("failed to remove expired instance", "instance_id", id, "error", err)
+		}
+	}
 }
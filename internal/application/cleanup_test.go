@@ -1,6 +1,7 @@
 package application
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -21,11 +22,11 @@ func TestCleanup_MarksUnhealthy(t *testing.T) {
 	}
 	resp, _ := registry.Register(req)
 
-	registry.instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-150 * time.Millisecond)
+	memoryRepo(registry).instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-150 * time.Millisecond)
 
 	registry.cleanup()
 
-	instance := registry.instances[resp.InstanceID]
+	instance := memoryRepo(registry).instances[resp.InstanceID]
 	if instance == nil {
 		t.Fatal("instance should still exist")
 	}
@@ -34,6 +35,43 @@ func TestCleanup_MarksUnhealthy(t *testing.T) {
 	}
 }
 
+// drainingRepository drains every instance it lists once the list is read,
+// as a service draining itself right after a cleanup sweep looked at it.
+type drainingRepository struct {
+	*MemoryRepository
+}
+
+func (d drainingRepository) GetInstancesByService(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	instances, err := d.MemoryRepository.GetInstancesByService(ctx, serviceName)
+	snapshot := make([]*domain.ServiceInstance, len(instances))
+	for i, instance := range instances {
+		copied := *instance
+		snapshot[i] = &copied
+		_ = d.UpdateInstanceStatus(ctx, instance.ID, domain.StatusDraining)
+	}
+	return snapshot, err
+}
+
+func TestCleanup_KeepsDrainAfterStaleRead(t *testing.T) {
+	repo := drainingRepository{NewMemoryRepository()}
+	registry := NewRegistryWithRepository(RegistryConfig{HeartbeatTTL: 100 * time.Millisecond}, repo)
+
+	resp, _ := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/test"}},
+	})
+	repo.instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-150 * time.Millisecond)
+
+	registry.cleanup()
+
+	if got := repo.instances[resp.InstanceID].Status; got != domain.StatusDraining {
+		t.Errorf("expected cleanup to leave a draining instance alone, got %s", got)
+	}
+}
+
 func TestCleanup_RemovesExpired(t *testing.T) {
 	registry := NewRegistry(RegistryConfig{
 		HeartbeatTTL: 100 * time.Millisecond,
@@ -48,18 +86,18 @@ func TestCleanup_RemovesExpired(t *testing.T) {
 	}
 	resp, _ := registry.Register(req)
 
-	registry.instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-250 * time.Millisecond)
+	memoryRepo(registry).instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-250 * time.Millisecond)
 
 	registry.cleanup()
 
-	if _, exists := registry.instances[resp.InstanceID]; exists {
+	if _, exists := memoryRepo(registry).instances[resp.InstanceID]; exists {
 		t.Error("expired instance should be removed")
 	}
-	if len(registry.routes) != 0 {
-		t.Errorf("routes should be removed, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 0 {
+		t.Errorf("routes should be removed, got %d", len(memoryRepo(registry).routes))
 	}
-	if len(registry.services["test-service"]) != 0 {
-		t.Errorf("service entry should be removed, got %d", len(registry.services["test-service"]))
+	if len(memoryRepo(registry).services["test-service"]) != 0 {
+		t.Errorf("service entry should be removed, got %d", len(memoryRepo(registry).services["test-service"]))
 	}
 }
 
@@ -79,7 +117,7 @@ func TestCleanup_KeepsHealthyInstances(t *testing.T) {
 
 	registry.cleanup()
 
-	instance := registry.instances[resp.InstanceID]
+	instance := memoryRepo(registry).instances[resp.InstanceID]
 	if instance == nil {
 		t.Fatal("healthy instance should still exist")
 	}
@@ -102,12 +140,12 @@ func TestCleanup_DoesNotMarkUnhealthyTwice(t *testing.T) {
 	}
 	resp, _ := registry.Register(req)
 
-	registry.instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-150 * time.Millisecond)
-	registry.instances[resp.InstanceID].Status = domain.StatusUnhealthy
+	memoryRepo(registry).instances[resp.InstanceID].LastHeartbeat = time.Now().Add(-150 * time.Millisecond)
+	memoryRepo(registry).instances[resp.InstanceID].Status = domain.StatusUnhealthy
 
 	registry.cleanup()
 
-	instance := registry.instances[resp.InstanceID]
+	instance := memoryRepo(registry).instances[resp.InstanceID]
 	if instance == nil {
 		t.Fatal("instance should still exist (not yet 2x TTL)")
 	}
@@ -1,60 +1,48 @@
 package application
 
 import (
-	"regexp"
-	"strings"
+	"context"
+	"fmt"
+	"slices"
 
 	"github.com/apascualco/gotway/internal/domain"
 )
 
-var paramPattern = regexp.MustCompile(`:[^/]+|\{[^}]+\}`)
-
-func normalizePath(path string) string {
-	return paramPattern.ReplaceAllString(path, "*")
+// checkPatternCollision reports the routes of other services whose pattern
+// overlaps path and whose conditions can match the same requests as route.
+func checkPatternCollision(serviceName string, route *domain.Route, path string, entries []domain.RouteEntry) []domain.RouteCollision {
+	return checkCollisions(serviceName, route, path, entries, domain.PatternCollision, func(entry *domain.RouteEntry) bool {
+		return pathsOverlap(path, entry.Route.FullPath(entry.BasePath)) && route.ConditionsCompatible(&entry.Route)
+	})
 }
 
-func (r *Registry) checkExactCollision(serviceName, method, path string) *domain.RouteCollision {
-	key := method + ":" + path
-	if entry, exists := r.routes[key]; exists {
-		if entry.ServiceName == serviceName {
-			return nil
-		}
-		return &domain.RouteCollision{
-			Method:        method,
-			Path:          path,
-			CollisionType: domain.ExactCollision,
-			RegisteredBy:  entry.ServiceName,
-			RegisteredAt:  entry.RegisteredAt,
-		}
-	}
-	return nil
+// checkConditionCollision reports the routes of other services on the same
+// path whose conditions match some request exactly as specifically as
+// route's, so neither can be preferred.
+func checkConditionCollision(serviceName string, route *domain.Route, path string, entries []domain.RouteEntry) []domain.RouteCollision {
+	return checkCollisions(serviceName, route, path, entries, domain.ConditionCollision, func(entry *domain.RouteEntry) bool {
+		return entry.Route.FullPath(entry.BasePath) == path && route.ConditionsAmbiguous(&entry.Route)
+	})
 }
 
-func (r *Registry) checkPatternCollision(serviceName, method, path string) []domain.RouteCollision {
+func checkCollisions(serviceName string, route *domain.Route, path string, entries []domain.RouteEntry, collisionType domain.CollisionType, collides func(*domain.RouteEntry) bool) []domain.RouteCollision {
 	var collisions []domain.RouteCollision
-	normalizedNew := normalizePath(path)
 
-	for key, entry := range r.routes {
+	for i := range entries {
+		entry := &entries[i]
 		if entry.ServiceName == serviceName {
 			continue
 		}
 
-		parts := strings.SplitN(key, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		existingMethod, existingPath := parts[0], parts[1]
-
-		if existingMethod != method {
+		if entry.Route.Method != route.Method {
 			continue
 		}
 
-		normalizedExisting := normalizePath(existingPath)
-		if pathsOverlap(normalizedNew, normalizedExisting) {
+		if collides(entry) {
 			collisions = append(collisions, domain.RouteCollision{
-				Method:        method,
+				Method:        route.Method,
 				Path:          path,
-				CollisionType: domain.PatternCollision,
+				CollisionType: collisionType,
 				RegisteredBy:  entry.ServiceName,
 				RegisteredAt:  entry.RegisteredAt,
 			})
@@ -64,48 +52,92 @@ func (r *Registry) checkPatternCollision(serviceName, method, path string) []dom
 	return collisions
 }
 
+// pathsOverlap reports whether some request path could match both patterns.
+// Two parameters are assumed to overlap whatever their constraints, and a
+// catch-all is taken to match any number of segments, so the answer errs on
+// the side of reporting a collision.
 func pathsOverlap(path1, path2 string) bool {
 	if path1 == path2 {
 		return true
 	}
 
-	segments1 := strings.Split(strings.Trim(path1, "/"), "/")
-	segments2 := strings.Split(strings.Trim(path2, "/"), "/")
-
-	if len(segments1) != len(segments2) {
+	segments1, err := domain.ParsePathPattern(path1)
+	if err != nil {
+		return false
+	}
+	segments2, err := domain.ParsePathPattern(path2)
+	if err != nil {
 		return false
 	}
+	return segmentsOverlap(segments1, segments2)
+}
 
-	for i := range segments1 {
-		s1, s2 := segments1[i], segments2[i]
-		if s1 == "*" || s2 == "*" {
-			continue
-		}
-		if s1 != s2 {
-			return false
-		}
+func segmentsOverlap(a, b []domain.PatternSegment) bool {
+	switch {
+	case len(a) > 0 && a[0].Kind == domain.CatchAllSegment:
+		return segmentsOverlap(a[1:], b) || (len(b) > 0 && segmentsOverlap(a, b[1:]))
+	case len(b) > 0 && b[0].Kind == domain.CatchAllSegment:
+		return segmentsOverlap(a, b[1:]) || (len(a) > 0 && segmentsOverlap(a[1:], b))
+	case len(a) == 0 || len(b) == 0:
+		return len(a) == len(b)
 	}
 
-	return true
+	var overlap bool
+	switch {
+	case a[0].Kind == domain.StaticSegment:
+		overlap = b[0].Matches(a[0].Value)
+	case b[0].Kind == domain.StaticSegment:
+		overlap = a[0].Matches(b[0].Value)
+	default:
+		overlap = true
+	}
+	return overlap && segmentsOverlap(a[1:], b[1:])
 }
 
 func (r *Registry) ValidateRoutes(serviceName, basePath string, routes []domain.Route) ([]domain.RouteCollision, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.validateRoutes(context.Background(), serviceName, basePath, routes)
+}
+
+func (r *Registry) validateRoutes(ctx context.Context, serviceName, basePath string, routes []domain.Route) ([]domain.RouteCollision, error) {
+	exact, err := r.repo.CheckRouteCollisions(ctx, basePath, routes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check route collisions: %w", err)
+	}
 
 	var collisions []domain.RouteCollision
+	collided := make(map[string]bool)
+	for _, collision := range exact {
+		if collision.RegisteredBy == serviceName {
+			continue
+		}
+		collisions = append(collisions, collision)
+		collided[collision.Method+":"+collision.Path] = true
+	}
+
+	// Without strict pattern matching only routes with conditions can
+	// collide beyond their key, with routes on the same path.
+	conditional := slices.ContainsFunc(routes, func(route domain.Route) bool { return route.HasConditions() })
+	if !r.config.StrictPatternMatching && !conditional {
+		return collisions, nil
+	}
+
+	entries, err := r.repo.GetAllRoutes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
 
 	for _, route := range routes {
 		fullPath := route.FullPath(basePath)
-
-		if collision := r.checkExactCollision(serviceName, route.Method, fullPath); collision != nil {
-			collisions = append(collisions, *collision)
+		if collided[route.Method+":"+fullPath] {
 			continue
 		}
-
 		if r.config.StrictPatternMatching {
-			patternCollisions := r.checkPatternCollision(serviceName, route.Method, fullPath)
-			collisions = append(collisions, patternCollisions...)
+			collisions = append(collisions, checkPatternCollision(serviceName, &route, fullPath, entries)...)
+		} else if route.HasConditions() {
+			collisions = append(collisions, checkConditionCollision(serviceName, &route, fullPath, entries)...)
 		}
 	}
 
@@ -7,29 +7,6 @@ import (
 	"github.com/apascualco/gotway/internal/domain"
 )
 
-func TestNormalizePath(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"/users/:id", "/users/*"},
-		{"/users/{id}", "/users/*"},
-		{"/users/:id/posts/:postId", "/users/*/posts/*"},
-		{"/users/{userId}/posts/{postId}", "/users/*/posts/*"},
-		{"/static/path", "/static/path"},
-		{"/mix/:id/fixed/{name}", "/mix/*/fixed/*"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := normalizePath(tt.input)
-			if result != tt.expected {
-				t.Errorf("normalizePath(%q) = %q, want %q", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestValidateRoutes_NoCollision(t *testing.T) {
 	registry := NewRegistry(RegistryConfig{})
 
@@ -49,7 +26,7 @@ func TestValidateRoutes_NoCollision(t *testing.T) {
 
 func TestValidateRoutes_ExactCollision(t *testing.T) {
 	registry := NewRegistry(RegistryConfig{})
-	registry.routes["GET:/api/v1/users"] = &domain.RouteEntry{
+	memoryRepo(registry).routes["GET:/api/v1/users"] = &domain.RouteEntry{
 		ServiceName:  "service-a",
 		BasePath:     "/api/v1",
 		Route:        domain.Route{Method: "GET", Path: "/users"},
@@ -79,7 +56,7 @@ func TestValidateRoutes_PatternCollision(t *testing.T) {
 	registry := NewRegistry(RegistryConfig{
 		StrictPatternMatching: true,
 	})
-	registry.routes["GET:/api/v1/users/:id"] = &domain.RouteEntry{
+	memoryRepo(registry).routes["GET:/api/v1/users/:id"] = &domain.RouteEntry{
 		ServiceName:  "service-a",
 		BasePath:     "/api/v1",
 		Route:        domain.Route{Method: "GET", Path: "/users/:id"},
@@ -104,7 +81,7 @@ func TestValidateRoutes_PatternCollision(t *testing.T) {
 
 func TestValidateRoutes_SameServiceAllowed(t *testing.T) {
 	registry := NewRegistry(RegistryConfig{})
-	registry.routes["GET:/api/v1/users"] = &domain.RouteEntry{
+	memoryRepo(registry).routes["GET:/api/v1/users"] = &domain.RouteEntry{
 		ServiceName:  "service-a",
 		BasePath:     "/api/v1",
 		Route:        domain.Route{Method: "GET", Path: "/users"},
@@ -137,6 +114,15 @@ func TestPathsOverlap(t *testing.T) {
 		{"/users/*/posts", "/users/*/comments", false},
 		{"/a/b/c", "/a/b/d", false},
 		{"/a/b", "/a/b/c", false},
+		{"/users/:id", "/users/{userId}", true},
+		{"/users/{id:[0-9]+}", "/users/42", true},
+		{"/users/{id:[0-9]+}", "/users/me", false},
+		{"/users/{id:[0-9]+}", "/users/{slug:[a-z]+}", true},
+		{"/files/*rest", "/files/a/b/c", true},
+		{"/files/*rest", "/static/a", false},
+		{"/files/*path/download", "/files/a/b/download", true},
+		{"/files/*path/download", "/files/a/b/upload", false},
+		{"/users/:id/", "/users/:id", true},
 	}
 
 	for _, tt := range tests {
@@ -149,3 +135,91 @@ func TestPathsOverlap(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRoutes_PatternCollisionHonoursConstraints(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{
+		StrictPatternMatching: true,
+	})
+	memoryRepo(registry).routes["GET:/api/v1/users/{id:[0-9]+}"] = &domain.RouteEntry{
+		ServiceName:  "service-a",
+		BasePath:     "/api/v1",
+		Route:        domain.Route{Method: "GET", Path: "/users/{id:[0-9]+}"},
+		RegisteredAt: time.Now(),
+	}
+
+	collisions, err := registry.ValidateRoutes("service-b", "/api/v1", []domain.Route{
+		{Method: "GET", Path: "/users/me"},
+		{Method: "GET", Path: "/users/{name}"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %d", len(collisions))
+	}
+	if collisions[0].Path != "/api/v1/users/{name}" {
+		t.Errorf("expected the unconstrained parameter to collide, got %s", collisions[0].Path)
+	}
+}
+
+func TestValidateRoutes_ConditionCollision(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{})
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "service-a",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/users", Hosts: []string{"api.example.com", "www.example.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		route domain.Route
+		want  int
+	}{
+		{"other host", domain.Route{Method: "GET", Path: "/users", Hosts: []string{"admin.example.com"}}, 0},
+		{"any host", domain.Route{Method: "GET", Path: "/users"}, 0},
+		{"more specific", domain.Route{Method: "GET", Path: "/users", Hosts: []string{"api.example.com"},
+			Headers: []domain.MatchPredicate{{Name: "X-Api-Version", Value: "2"}}}, 0},
+		{"shared host", domain.Route{Method: "GET", Path: "/users", Hosts: []string{"API.example.com"}}, 1},
+		{"same conditions", domain.Route{Method: "GET", Path: "/users", Hosts: []string{"www.example.com", "api.example.com"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collisions, err := registry.ValidateRoutes("service-b", "/api/v1", []domain.Route{tt.route})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(collisions) != tt.want {
+				t.Fatalf("expected %d collisions, got %v", tt.want, collisions)
+			}
+		})
+	}
+}
+
+func TestValidateRoutes_PatternCollisionHonoursConditions(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{
+		StrictPatternMatching: true,
+	})
+	memoryRepo(registry).routes["GET:/api/v1/users/:id host=api.example.com"] = &domain.RouteEntry{
+		ServiceName:  "service-a",
+		BasePath:     "/api/v1",
+		Route:        domain.Route{Method: "GET", Path: "/users/:id", Hosts: []string{"api.example.com"}},
+		RegisteredAt: time.Now(),
+	}
+
+	collisions, err := registry.ValidateRoutes("service-b", "/api/v1", []domain.Route{
+		{Method: "GET", Path: "/users/me", Hosts: []string{"admin.example.com"}},
+		{Method: "GET", Path: "/users/{name}", Hosts: []string{"*.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(collisions) != 1 || collisions[0].Path != "/api/v1/users/{name}" {
+		t.Fatalf("expected only the wildcard host route to collide, got %v", collisions)
+	}
+}
@@ -0,0 +1,207 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// HealthProber checks a single instance and returns an error when it is not healthy.
+type HealthProber interface {
+	Probe(ctx context.Context, instance *domain.ServiceInstance) error
+}
+
+type HealthCheckConfig struct {
+	Interval           time.Duration
+	Timeout            time.Duration
+	HealthyThreshold   int
+	UnhealthyThreshold int
+	// Leader is required when the registry is shared between replicas: only
+	// the replica holding it probes and writes instance status, so replicas
+	// with a different view of the network cannot flap a shared status.
+	Leader domain.LeaderLock
+}
+
+type probeState struct {
+	successes int
+	failures  int
+}
+
+// HealthChecker actively probes every registered instance and flips its status
+// once HealthyThreshold consecutive probes pass or UnhealthyThreshold fail.
+// An instance whose heartbeat has expired is never promoted back to healthy,
+// and instances taken out of rotation on purpose are not probed.
+type HealthChecker struct {
+	registry *Registry
+	prober   HealthProber
+	config   HealthCheckConfig
+	mu       sync.Mutex
+	states   map[string]*probeState
+	stopCh   chan struct{}
+}
+
+func NewHealthChecker(registry *Registry, prober HealthProber, cfg HealthCheckConfig) *HealthChecker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Timeout <= 0 || cfg.Timeout > cfg.Interval {
+		cfg.Timeout = cfg.Interval
+	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = 1
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = 1
+	}
+
+	return &HealthChecker{
+		registry: registry,
+		prober:   prober,
+		config:   cfg,
+		states:   make(map[string]*probeState),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (h *HealthChecker) Start() {
+	go h.loop()
+}
+
+func (h *HealthChecker) Stop() {
+	close(h.stopCh)
+}
+
+func (h *HealthChecker) loop() {
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.check()
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+func (h *HealthChecker) check() {
+	if !h.isLeader() {
+		h.mu.Lock()
+		clear(h.states)
+		h.mu.Unlock()
+		return
+	}
+
+	seen := make(map[string]struct{})
+
+	var wg sync.WaitGroup
+	for _, instances := range h.registry.GetAllServices() {
+		for _, instance := range instances {
+			if instance.OutOfRotation() {
+				continue
+			}
+			seen[instance.ID] = struct{}{}
+
+			wg.Add(1)
+			go func(instance *domain.ServiceInstance, status domain.ServiceStatus) {
+				defer wg.Done()
+				h.probe(instance, status)
+			}(instance, instance.Status)
+		}
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	for id := range h.states {
+		if _, ok := seen[id]; !ok {
+			delete(h.states, id)
+		}
+	}
+	h.mu.Unlock()
+}
+
+// isLeader renews the leader lock for a few intervals so it survives a slow
+// round of probes but moves to another replica soon after this one stops.
+func (h *HealthChecker) isLeader() bool {
+	if h.config.Leader == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	held, err := h.config.Leader.TryAcquire(ctx, 3*h.config.Interval)
+	if err != nil {
+		slog.Error("failed to acquire health check leadership", "error", err)
+		return false
+	}
+	return held
+}
+
+// probe checks the instance, which had status when the round started.
+func (h *HealthChecker) probe(instance *domain.ServiceInstance, status domain.ServiceStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	err := h.prober.Probe(ctx, instance)
+
+	h.mu.Lock()
+	state, exists := h.states[instance.ID]
+	if !exists {
+		state = &probeState{}
+		h.states[instance.ID] = state
+	}
+	if err != nil {
+		state.failures++
+		state.successes = 0
+	} else {
+		state.successes++
+		state.failures = 0
+	}
+	successes, failures := state.successes, state.failures
+	h.mu.Unlock()
+
+	switch {
+	case err != nil && status == domain.StatusHealthy && failures >= h.config.UnhealthyThreshold:
+		h.setStatus(instance, status, domain.StatusUnhealthy, "error", err)
+	case err == nil && status != domain.StatusHealthy && successes >= h.config.HealthyThreshold && h.heartbeatFresh(instance):
+		h.setStatus(instance, status, domain.StatusHealthy)
+	}
+}
+
+func (h *HealthChecker) heartbeatFresh(instance *domain.ServiceInstance) bool {
+	ttl := h.registry.config.HeartbeatTTL
+	return ttl <= 0 || time.Since(instance.LastHeartbeat) <= ttl
+}
+
+// setStatus moves the instance from the status it had when the round started,
+// so a drain or disable that lands while the probe runs is never overwritten.
+func (h *HealthChecker) setStatus(instance *domain.ServiceInstance, from, status domain.ServiceStatus, attrs ...any) {
+	updated, err := h.registry.SetInstanceStatusIf(instance.ID, from, status)
+	if err != nil {
+		slog.Error("failed to update instance status",
+			"instance_id", instance.ID,
+			"status", status,
+			"error", err,
+		)
+		return
+	}
+	if !updated {
+		slog.Debug("instance status changed during health check",
+			"instance_id", instance.ID,
+			"service", instance.ServiceName,
+		)
+		return
+	}
+
+	attrs = append([]any{
+		"instance_id", instance.ID,
+		"service", instance.ServiceName,
+		"status", status,
+	}, attrs...)
+	slog.Warn("health check changed instance status", attrs...)
+}
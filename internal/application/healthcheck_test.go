@@ -0,0 +1,207 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+type fakeProber struct {
+	mu      sync.Mutex
+	err     error
+	onProbe func()
+}
+
+func (f *fakeProber) Probe(ctx context.Context, instance *domain.ServiceInstance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.onProbe != nil {
+		f.onProbe()
+	}
+	return f.err
+}
+
+func (f *fakeProber) set(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func setupHealthCheck(t *testing.T) (*Registry, *HealthChecker, *fakeProber, string) {
+	t.Helper()
+
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/test"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	prober := &fakeProber{}
+	checker := NewHealthChecker(registry, prober, HealthCheckConfig{
+		Interval:           time.Second,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	})
+	return registry, checker, prober, resp.InstanceID
+}
+
+func TestHealthChecker_MarksUnhealthyAfterFallThreshold(t *testing.T) {
+	registry, checker, prober, id := setupHealthCheck(t)
+	prober.set(errors.New("status 500"))
+
+	checker.check()
+	checker.check()
+	if !registry.GetInstance(id).IsHealthy() {
+		t.Fatal("instance should stay healthy below the fall threshold")
+	}
+
+	checker.check()
+	if registry.GetInstance(id).Status != domain.StatusUnhealthy {
+		t.Errorf("expected unhealthy after 3 failures, got %s", registry.GetInstance(id).Status)
+	}
+	if len(registry.GetHealthyInstances("test-service")) != 0 {
+		t.Error("unhealthy instance should not be returned as healthy")
+	}
+}
+
+func TestHealthChecker_RecoversAfterRiseThreshold(t *testing.T) {
+	registry, checker, prober, id := setupHealthCheck(t)
+	_ = registry.SetInstanceStatus(id, domain.StatusUnhealthy)
+
+	checker.check()
+	if registry.GetInstance(id).IsHealthy() {
+		t.Fatal("instance should stay unhealthy below the rise threshold")
+	}
+
+	checker.check()
+	if !registry.GetInstance(id).IsHealthy() {
+		t.Errorf("expected healthy after 2 successes, got %s", registry.GetInstance(id).Status)
+	}
+
+	prober.set(errors.New("timeout"))
+	checker.check()
+	prober.set(nil)
+	checker.check()
+	if !registry.GetInstance(id).IsHealthy() {
+		t.Error("a single failure should reset without flipping status")
+	}
+}
+
+func TestHealthChecker_DoesNotReviveExpiredHeartbeat(t *testing.T) {
+	registry, checker, _, id := setupHealthCheck(t)
+	memoryRepo(registry).instances[id].LastHeartbeat = time.Now().Add(-time.Minute)
+	_ = registry.SetInstanceStatus(id, domain.StatusUnhealthy)
+
+	checker.check()
+	checker.check()
+
+	if registry.GetInstance(id).IsHealthy() {
+		t.Error("instance with expired heartbeat should not be marked healthy")
+	}
+}
+
+func TestHealthChecker_SkipsInstancesOutOfRotation(t *testing.T) {
+	registry, checker, _, id := setupHealthCheck(t)
+
+	for _, status := range []domain.ServiceStatus{domain.StatusDisabled, domain.StatusDraining} {
+		_ = registry.SetInstanceStatus(id, status)
+
+		checker.check()
+		checker.check()
+
+		if got := registry.GetInstance(id).Status; got != status {
+			t.Errorf("expected a passing probe to leave a %s instance alone, got %s", status, got)
+		}
+	}
+}
+
+func TestHealthChecker_KeepsDrainLandingDuringProbe(t *testing.T) {
+	registry, checker, prober, id := setupHealthCheck(t)
+	prober.set(errors.New("status 500"))
+
+	checker.check()
+	checker.check()
+	prober.onProbe = func() { _ = registry.DrainInstance(id) }
+	checker.check()
+
+	if got := registry.GetInstance(id).Status; got != domain.StatusDraining {
+		t.Fatalf("expected a failing probe started before the drain to keep it, got %s", got)
+	}
+
+	_ = registry.SetInstanceStatus(id, domain.StatusUnhealthy)
+	prober.onProbe = nil
+	prober.set(nil)
+	checker.check()
+	prober.onProbe = func() { _ = registry.DrainInstance(id) }
+	checker.check()
+
+	if got := registry.GetInstance(id).Status; got != domain.StatusDraining {
+		t.Errorf("expected a passing probe started before the drain to keep it, got %s", got)
+	}
+}
+
+func TestHealthChecker_ForgetsRemovedInstances(t *testing.T) {
+	registry, checker, _, id := setupHealthCheck(t)
+
+	checker.check()
+	if len(checker.states) != 1 {
+		t.Fatalf("expected 1 tracked instance, got %d", len(checker.states))
+	}
+
+	_ = registry.Deregister(id)
+	checker.check()
+	if len(checker.states) != 0 {
+		t.Errorf("expected no tracked instances, got %d", len(checker.states))
+	}
+}
+
+func TestHealthChecker_StartStop(t *testing.T) {
+	_, checker, _, _ := setupHealthCheck(t)
+
+	checker.Start()
+	time.Sleep(10 * time.Millisecond)
+	checker.Stop()
+}
+
+type fakeLeader struct {
+	held bool
+}
+
+func (f *fakeLeader) TryAcquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	return f.held, nil
+}
+
+func TestHealthChecker_OnlyLeaderWritesStatus(t *testing.T) {
+	registry, checker, prober, id := setupHealthCheck(t)
+	leader := &fakeLeader{}
+	checker.config.Leader = leader
+	prober.set(errors.New("status 500"))
+
+	for i := 0; i < 3; i++ {
+		checker.check()
+	}
+	if !registry.GetInstance(id).IsHealthy() {
+		t.Fatal("a replica without leadership should not change status")
+	}
+	if len(checker.states) != 0 {
+		t.Fatalf("a replica without leadership should not track probes, got %d", len(checker.states))
+	}
+
+	leader.held = true
+	for i := 0; i < 3; i++ {
+		checker.check()
+	}
+	if registry.GetInstance(id).Status != domain.StatusUnhealthy {
+		t.Errorf("expected leader to mark instance unhealthy, got %s", registry.GetInstance(id).Status)
+	}
+}
@@ -1,20 +1,21 @@
 package application
 
 import (
-	"time"
+	"context"
 
 	"github.com/apascualco/gotway/internal/domain"
 )
 
 func (r *Registry) Heartbeat(instanceID string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	return r.repo.UpdateHeartbeat(context.Background(), instanceID)
+}
 
-	instance, exists := r.instances[instanceID]
-	if !exists {
-		return domain.ErrInstanceNotFound
-	}
+func (r *Registry) SetInstanceStatus(instanceID string, status domain.ServiceStatus) error {
+	return r.repo.UpdateInstanceStatus(context.Background(), instanceID, status)
+}
 
-	instance.LastHeartbeat = time.Now()
-	return nil
+// SetInstanceStatusIf changes the instance status from from to to, and
+// reports false without changing it when the status is no longer from.
+func (r *Registry) SetInstanceStatusIf(instanceID string, from, to domain.ServiceStatus) (bool, error) {
+	return r.repo.UpdateInstanceStatusIf(context.Background(), instanceID, from, to)
 }
@@ -19,7 +19,7 @@ func TestHeartbeat_Success(t *testing.T) {
 	}
 	resp, _ := registry.Register(req)
 
-	oldHeartbeat := registry.instances[resp.InstanceID].LastHeartbeat
+	oldHeartbeat := memoryRepo(registry).instances[resp.InstanceID].LastHeartbeat
 	time.Sleep(10 * time.Millisecond)
 
 	err := registry.Heartbeat(resp.InstanceID)
@@ -27,7 +27,7 @@ func TestHeartbeat_Success(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	newHeartbeat := registry.instances[resp.InstanceID].LastHeartbeat
+	newHeartbeat := memoryRepo(registry).instances[resp.InstanceID].LastHeartbeat
 	if !newHeartbeat.After(oldHeartbeat) {
 		t.Error("LastHeartbeat was not updated")
 	}
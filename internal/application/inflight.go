@@ -0,0 +1,74 @@
+package application
+
+import (
+	"sync"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// InflightTracker counts outstanding proxied requests per instance, and
+// separately the long-lived upgraded connections, such as WebSockets, open
+// to each instance.
+type InflightTracker struct {
+	mu          sync.RWMutex
+	counts      map[string]int64
+	connections map[string]int64
+}
+
+func NewInflightTracker() *InflightTracker {
+	return &InflightTracker{
+		counts:      make(map[string]int64),
+		connections: make(map[string]int64),
+	}
+}
+
+func (t *InflightTracker) Begin(instance *domain.ServiceInstance) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[instance.ID]++
+}
+
+func (t *InflightTracker) End(instance *domain.ServiceInstance) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	decrement(t.counts, instance.ID)
+}
+
+func (t *InflightTracker) Count(instanceID string) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.counts[instanceID]
+}
+
+func (t *InflightTracker) Connect(instance *domain.ServiceInstance) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.connections[instance.ID]++
+}
+
+func (t *InflightTracker) Disconnect(instance *domain.ServiceInstance) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	decrement(t.connections, instance.ID)
+}
+
+func (t *InflightTracker) Connections(instanceID string) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.connections[instanceID]
+}
+
+// decrement lowers the count for id, forgetting it once it reaches zero.
+func decrement(counts map[string]int64, id string) {
+	if counts[id] <= 1 {
+		delete(counts, id)
+		return
+	}
+	counts[id]--
+}
@@ -1,6 +1,10 @@
 package application
 
 import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
 	"sync/atomic"
 
 	"github.com/apascualco/gotway/internal/domain"
@@ -10,6 +14,21 @@ type LoadBalancer interface {
 	Select(instances []*domain.ServiceInstance) *domain.ServiceInstance
 }
 
+// RequestInfo exposes the request attributes a request-aware balancer may key on.
+type RequestInfo struct {
+	Request           *http.Request
+	ClientIP          string
+	Subject           string
+	ClientCertSubject string
+}
+
+// RequestAwareBalancer is a LoadBalancer whose choice depends on the request,
+// such as consistent hashing on a user identifier.
+type RequestAwareBalancer interface {
+	LoadBalancer
+	SelectForRequest(info *RequestInfo, instances []*domain.ServiceInstance) *domain.ServiceInstance
+}
+
 type RoundRobinBalancer struct {
 	counter uint64
 }
@@ -27,3 +46,221 @@ func (r *RoundRobinBalancer) Select(instances []*domain.ServiceInstance) *domain
 	idx := (n - 1) % uint64(len(instances))
 	return instances[idx]
 }
+
+// RequestTracker is implemented by balancers that need to observe the lifetime
+// of every proxied request, such as least-requests strategies.
+type RequestTracker interface {
+	Begin(instance *domain.ServiceInstance)
+	End(instance *domain.ServiceInstance)
+}
+
+// ConnectionTracker is implemented by balancers that need to observe the
+// lifetime of upgraded connections, such as least-connections strategies.
+type ConnectionTracker interface {
+	Connect(instance *domain.ServiceInstance)
+	Disconnect(instance *domain.ServiceInstance)
+}
+
+type BalancerStrategy string
+
+const (
+	StrategyRoundRobin         BalancerStrategy = "round_robin"
+	StrategyWeightedRoundRobin BalancerStrategy = "weighted_round_robin"
+	StrategyLeastRequests      BalancerStrategy = "least_requests"
+	StrategyLeastConnections   BalancerStrategy = "least_connections"
+	StrategyPowerOfTwo         BalancerStrategy = "power_of_two"
+	StrategyRandom             BalancerStrategy = "random"
+	StrategyRingHash           BalancerStrategy = "ring_hash"
+)
+
+// BalancerOptions carries the dependencies a strategy may need.
+type BalancerOptions struct {
+	// Tracker provides outstanding request and connection counts for
+	// least-requests and least-connections strategies.
+	Tracker *InflightTracker
+	// HashKey selects the request attribute ring_hash hashes on.
+	HashKey HashKey
+}
+
+// NewBalancer creates a load balancer for the given strategy.
+func NewBalancer(strategy BalancerStrategy, opts BalancerOptions) (LoadBalancer, error) {
+	switch strategy {
+	case StrategyRoundRobin:
+		return NewRoundRobinBalancer(), nil
+	case StrategyWeightedRoundRobin:
+		return NewWeightedRoundRobinBalancer(), nil
+	case StrategyLeastRequests:
+		return NewLeastRequestsBalancer(opts.Tracker), nil
+	case StrategyLeastConnections:
+		return NewLeastConnectionsBalancer(opts.Tracker), nil
+	case StrategyPowerOfTwo:
+		return NewPowerOfTwoBalancer(opts.Tracker), nil
+	case StrategyRandom:
+		return NewRandomBalancer(), nil
+	case StrategyRingHash:
+		return NewRingHashBalancer(opts.HashKey), nil
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy %q", strategy)
+	}
+}
+
+func instanceWeight(instance *domain.ServiceInstance) int {
+	if instance.Weight <= 0 {
+		return 1
+	}
+	return instance.Weight
+}
+
+// WeightedRoundRobinBalancer implements smooth weighted round-robin: an instance
+// with weight 3 receives three requests for every one sent to an instance with
+// weight 1, interleaved rather than in bursts.
+type WeightedRoundRobinBalancer struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func NewWeightedRoundRobinBalancer() *WeightedRoundRobinBalancer {
+	return &WeightedRoundRobinBalancer{current: make(map[string]int)}
+}
+
+func (w *WeightedRoundRobinBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.current) > len(instances) {
+		w.prune(instances)
+	}
+
+	total := 0
+	var best *domain.ServiceInstance
+	for _, instance := range instances {
+		weight := instanceWeight(instance)
+		total += weight
+		w.current[instance.ID] += weight
+		if best == nil || w.current[instance.ID] > w.current[best.ID] {
+			best = instance
+		}
+	}
+	w.current[best.ID] -= total
+	return best
+}
+
+func (w *WeightedRoundRobinBalancer) prune(instances []*domain.ServiceInstance) {
+	live := make(map[string]struct{}, len(instances))
+	for _, instance := range instances {
+		live[instance.ID] = struct{}{}
+	}
+	for id := range w.current {
+		if _, ok := live[id]; !ok {
+			delete(w.current, id)
+		}
+	}
+}
+
+// LeastRequestsBalancer picks the instance with the fewest outstanding requests
+// relative to its weight. Ties are broken in round-robin order.
+type LeastRequestsBalancer struct {
+	tracker *InflightTracker
+	count   func(instanceID string) int64
+	counter uint64
+}
+
+func NewLeastRequestsBalancer(tracker *InflightTracker) *LeastRequestsBalancer {
+	return &LeastRequestsBalancer{tracker: tracker, count: tracker.Count}
+}
+
+func (l *LeastRequestsBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	offset := int((atomic.AddUint64(&l.counter, 1) - 1) % uint64(len(instances)))
+
+	var best *domain.ServiceInstance
+	for i := range instances {
+		instance := instances[(offset+i)%len(instances)]
+		if best == nil || l.less(instance, best) {
+			best = instance
+		}
+	}
+	return best
+}
+
+func (l *LeastRequestsBalancer) less(a, b *domain.ServiceInstance) bool {
+	return l.count(a.ID)*int64(instanceWeight(b)) < l.count(b.ID)*int64(instanceWeight(a))
+}
+
+func (l *LeastRequestsBalancer) Begin(instance *domain.ServiceInstance) {
+	l.tracker.Begin(instance)
+}
+
+func (l *LeastRequestsBalancer) End(instance *domain.ServiceInstance) {
+	l.tracker.End(instance)
+}
+
+// PowerOfTwoBalancer samples two random instances and keeps the one with fewer
+// outstanding requests, avoiding the herd effect of a global least-requests scan.
+type PowerOfTwoBalancer struct {
+	LeastRequestsBalancer
+}
+
+func NewPowerOfTwoBalancer(tracker *InflightTracker) *PowerOfTwoBalancer {
+	return &PowerOfTwoBalancer{LeastRequestsBalancer{tracker: tracker, count: tracker.Count}}
+}
+
+func (p *PowerOfTwoBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	switch len(instances) {
+	case 0:
+		return nil
+	case 1:
+		return instances[0]
+	}
+
+	i := rand.IntN(len(instances))
+	j := rand.IntN(len(instances) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := instances[i], instances[j]
+	if p.less(b, a) {
+		return b
+	}
+	return a
+}
+
+// LeastConnectionsBalancer picks the instance with the fewest open upgraded
+// connections relative to its weight, for services whose load is made of
+// long-lived WebSockets rather than requests.
+type LeastConnectionsBalancer struct {
+	LeastRequestsBalancer
+}
+
+func NewLeastConnectionsBalancer(tracker *InflightTracker) *LeastConnectionsBalancer {
+	return &LeastConnectionsBalancer{LeastRequestsBalancer{tracker: tracker, count: tracker.Connections}}
+}
+
+func (l *LeastConnectionsBalancer) Connect(instance *domain.ServiceInstance) {
+	l.tracker.Connect(instance)
+}
+
+func (l *LeastConnectionsBalancer) Disconnect(instance *domain.ServiceInstance) {
+	l.tracker.Disconnect(instance)
+}
+
+type RandomBalancer struct{}
+
+func NewRandomBalancer() *RandomBalancer {
+	return &RandomBalancer{}
+}
+
+func (r *RandomBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+	return instances[rand.IntN(len(instances))]
+}
@@ -106,3 +106,212 @@ func TestRoundRobin_Concurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestWeightedRoundRobin_Distribution(t *testing.T) {
+	lb := NewWeightedRoundRobinBalancer()
+
+	instances := []*domain.ServiceInstance{
+		{ID: "heavy", Weight: 5},
+		{ID: "medium", Weight: 1},
+		{ID: "light", Weight: 1},
+	}
+
+	var sequence []string
+	counts := make(map[string]int)
+	for i := 0; i < 7; i++ {
+		selected := lb.Select(instances)
+		counts[selected.ID]++
+		sequence = append(sequence, selected.ID)
+	}
+
+	if counts["heavy"] != 5 || counts["medium"] != 1 || counts["light"] != 1 {
+		t.Errorf("unexpected distribution: %v", counts)
+	}
+
+	expected := []string{"heavy", "heavy", "medium", "heavy", "light", "heavy", "heavy"}
+	for i := range expected {
+		if sequence[i] != expected[i] {
+			t.Errorf("smooth WRR sequence = %v, want %v", sequence, expected)
+			break
+		}
+	}
+}
+
+func TestWeightedRoundRobin_ZeroWeightTreatedAsOne(t *testing.T) {
+	lb := NewWeightedRoundRobinBalancer()
+
+	instances := []*domain.ServiceInstance{
+		{ID: "instance-1"},
+		{ID: "instance-2"},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		counts[lb.Select(instances).ID]++
+	}
+	if counts["instance-1"] != 5 || counts["instance-2"] != 5 {
+		t.Errorf("expected even distribution, got %v", counts)
+	}
+}
+
+func TestWeightedRoundRobin_PrunesRemovedInstances(t *testing.T) {
+	lb := NewWeightedRoundRobinBalancer()
+
+	lb.Select([]*domain.ServiceInstance{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	lb.Select([]*domain.ServiceInstance{{ID: "a"}})
+
+	if len(lb.current) != 1 {
+		t.Errorf("expected state for 1 instance, got %d", len(lb.current))
+	}
+}
+
+func TestLeastRequests_PicksLeastLoaded(t *testing.T) {
+	tracker := NewInflightTracker()
+	lb := NewLeastRequestsBalancer(tracker)
+
+	instances := []*domain.ServiceInstance{
+		{ID: "busy"},
+		{ID: "idle"},
+	}
+	tracker.Begin(instances[0])
+	tracker.Begin(instances[0])
+
+	for i := 0; i < 5; i++ {
+		if selected := lb.Select(instances); selected.ID != "idle" {
+			t.Fatalf("iteration %d: expected idle, got %s", i, selected.ID)
+		}
+	}
+
+	lb.Begin(instances[1])
+	lb.Begin(instances[1])
+	lb.Begin(instances[1])
+	if selected := lb.Select(instances); selected.ID != "busy" {
+		t.Errorf("expected busy after idle became busier, got %s", selected.ID)
+	}
+}
+
+func TestLeastRequests_RespectsWeight(t *testing.T) {
+	tracker := NewInflightTracker()
+	lb := NewLeastRequestsBalancer(tracker)
+
+	instances := []*domain.ServiceInstance{
+		{ID: "small", Weight: 1},
+		{ID: "large", Weight: 4},
+	}
+	tracker.Begin(instances[0])
+	for i := 0; i < 3; i++ {
+		tracker.Begin(instances[1])
+	}
+
+	if selected := lb.Select(instances); selected.ID != "large" {
+		t.Errorf("expected large (3/4 < 1/1), got %s", selected.ID)
+	}
+}
+
+func TestLeastRequests_TiesRotate(t *testing.T) {
+	lb := NewLeastRequestsBalancer(NewInflightTracker())
+
+	instances := []*domain.ServiceInstance{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 9; i++ {
+		counts[lb.Select(instances).ID]++
+	}
+	for id, count := range counts {
+		if count != 3 {
+			t.Errorf("instance %s: expected 3 selections, got %d", id, count)
+		}
+	}
+}
+
+func TestLeastConnections_IgnoresShortRequests(t *testing.T) {
+	tracker := NewInflightTracker()
+	lb := NewLeastConnectionsBalancer(tracker)
+
+	instances := []*domain.ServiceInstance{{ID: "a"}, {ID: "b"}}
+	lb.Connect(instances[0])
+	for i := 0; i < 5; i++ {
+		tracker.Begin(instances[1])
+	}
+
+	if selected := lb.Select(instances); selected.ID != "b" {
+		t.Errorf("expected b, which holds no open connection, got %s", selected.ID)
+	}
+
+	lb.Disconnect(instances[0])
+	if got := tracker.Connections("a"); got != 0 {
+		t.Errorf("expected 0 connections after disconnect, got %d", got)
+	}
+}
+
+func TestPowerOfTwo_AvoidsBusiestInstance(t *testing.T) {
+	tracker := NewInflightTracker()
+	lb := NewPowerOfTwoBalancer(tracker)
+
+	instances := []*domain.ServiceInstance{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	for i := 0; i < 10; i++ {
+		tracker.Begin(instances[2])
+	}
+
+	for i := 0; i < 100; i++ {
+		if selected := lb.Select(instances); selected.ID == "c" {
+			t.Fatal("busiest instance should never win a two-choice comparison")
+		}
+	}
+}
+
+func TestPowerOfTwo_EdgeCases(t *testing.T) {
+	lb := NewPowerOfTwoBalancer(NewInflightTracker())
+
+	if selected := lb.Select(nil); selected != nil {
+		t.Errorf("expected nil for empty list, got %v", selected)
+	}
+
+	only := []*domain.ServiceInstance{{ID: "only"}}
+	if selected := lb.Select(only); selected.ID != "only" {
+		t.Errorf("expected only, got %s", selected.ID)
+	}
+}
+
+func TestRandom_SelectsFromList(t *testing.T) {
+	lb := NewRandomBalancer()
+
+	if selected := lb.Select(nil); selected != nil {
+		t.Errorf("expected nil for empty list, got %v", selected)
+	}
+
+	instances := []*domain.ServiceInstance{{ID: "a"}, {ID: "b"}}
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seen[lb.Select(instances).ID] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("expected both instances to be selected, got %v", seen)
+	}
+}
+
+func TestNewBalancer_UnknownStrategy(t *testing.T) {
+	if _, err := NewBalancer("fastest", BalancerOptions{}); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestInflightTracker_BeginEnd(t *testing.T) {
+	tracker := NewInflightTracker()
+	instance := &domain.ServiceInstance{ID: "instance-1"}
+
+	tracker.Begin(instance)
+	tracker.Begin(instance)
+	if count := tracker.Count("instance-1"); count != 2 {
+		t.Errorf("expected 2 in-flight, got %d", count)
+	}
+
+	tracker.End(instance)
+	tracker.End(instance)
+	if count := tracker.Count("instance-1"); count != 0 {
+		t.Errorf("expected 0 in-flight, got %d", count)
+	}
+	if len(tracker.counts) != 0 {
+		t.Errorf("expected idle instances to be forgotten, got %d entries", len(tracker.counts))
+	}
+}
@@ -0,0 +1,311 @@
+package application
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// MemoryRepository is a process-local domain.Repository. State is lost on
+// restart and is not shared between gateway replicas.
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	instances map[string]*domain.ServiceInstance
+	services  map[string][]string
+	routes    map[string]*domain.RouteEntry
+	policies  map[string]*domain.TrafficPolicy
+	version   uint64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		instances: make(map[string]*domain.ServiceInstance),
+		services:  make(map[string][]string),
+		routes:    make(map[string]*domain.RouteEntry),
+		policies:  make(map[string]*domain.TrafficPolicy),
+	}
+}
+
+func (m *MemoryRepository) SaveInstance(ctx context.Context, instance *domain.ServiceInstance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.instances[instance.ID]; !exists {
+		m.services[instance.ServiceName] = append(m.services[instance.ServiceName], instance.ID)
+	}
+	m.instances[instance.ID] = instance
+	return nil
+}
+
+func (m *MemoryRepository) GetInstance(ctx context.Context, instanceID string) (*domain.ServiceInstance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return nil, domain.ErrInstanceNotFound
+	}
+	return instance, nil
+}
+
+func (m *MemoryRepository) GetInstancesByService(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instanceIDs := m.services[serviceName]
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	instances := make([]*domain.ServiceInstance, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		if instance := m.instances[id]; instance != nil {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func (m *MemoryRepository) DeleteInstance(ctx context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return domain.ErrInstanceNotFound
+	}
+
+	s := instance.ServiceName
+
+	instanceIDs := m.services[s]
+	for i, id := range instanceIDs {
+		if id == instanceID {
+			m.services[s] = append(instanceIDs[:i], instanceIDs[i+1:]...)
+			break
+		}
+	}
+	if len(m.services[s]) == 0 {
+		delete(m.services, s)
+		m.deleteRoutesLocked(s)
+	}
+
+	delete(m.instances, instanceID)
+	return nil
+}
+
+func (m *MemoryRepository) UpdateInstanceStatus(ctx context.Context, instanceID string, status domain.ServiceStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return domain.ErrInstanceNotFound
+	}
+	instance.Status = status
+	return nil
+}
+
+func (m *MemoryRepository) UpdateInstanceStatusIf(ctx context.Context, instanceID string, from, to domain.ServiceStatus) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return false, domain.ErrInstanceNotFound
+	}
+	if instance.Status != from {
+		return false, nil
+	}
+	instance.Status = to
+	return true, nil
+}
+
+func (m *MemoryRepository) UpdateInstanceWeight(ctx context.Context, instanceID string, weight int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return domain.ErrInstanceNotFound
+	}
+	instance.Weight = weight
+	return nil
+}
+
+func (m *MemoryRepository) UpdateHeartbeat(ctx context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instance, exists := m.instances[instanceID]
+	if !exists {
+		return domain.ErrInstanceNotFound
+	}
+	instance.LastHeartbeat = time.Now()
+	return nil
+}
+
+func (m *MemoryRepository) SaveRoutes(ctx context.Context, serviceName string, basePath string, routes []domain.Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var collisions []domain.RouteCollision
+	for _, route := range routes {
+		if entry, exists := m.routes[route.Key(basePath)]; exists && entry.ServiceName != serviceName {
+			collisions = append(collisions, exactCollision(entry))
+		}
+	}
+	if len(collisions) > 0 {
+		return &domain.CollisionError{Collisions: collisions}
+	}
+
+	now := time.Now()
+	m.version++
+	for _, route := range routes {
+		m.routes[route.Key(basePath)] = &domain.RouteEntry{
+			ServiceName:  serviceName,
+			BasePath:     basePath,
+			Route:        route,
+			RegisteredAt: now,
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) GetRoute(ctx context.Context, method, path string) (*domain.RouteEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, exists := m.routes[method+":"+path]
+	if !exists {
+		return nil, domain.ErrRouteNotFound
+	}
+	return entry, nil
+}
+
+func (m *MemoryRepository) GetAllRoutes(ctx context.Context) ([]domain.RouteEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]domain.RouteEntry, 0, len(m.routes))
+	for _, entry := range m.routes {
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+func (m *MemoryRepository) DeleteRoutesByService(ctx context.Context, serviceName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteRoutesLocked(serviceName)
+	return nil
+}
+
+func (m *MemoryRepository) deleteRoutesLocked(serviceName string) {
+	for key, entry := range m.routes {
+		if entry.ServiceName == serviceName {
+			delete(m.routes, key)
+			m.version++
+		}
+	}
+}
+
+// RoutesVersion changes every time a route is saved or deleted.
+func (m *MemoryRepository) RoutesVersion() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.version
+}
+
+func (m *MemoryRepository) CheckRouteCollisions(ctx context.Context, basePath string, routes []domain.Route) ([]domain.RouteCollision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var collisions []domain.RouteCollision
+	for _, route := range routes {
+		entry, exists := m.routes[route.Key(basePath)]
+		if !exists {
+			continue
+		}
+		collisions = append(collisions, exactCollision(entry))
+	}
+	return collisions, nil
+}
+
+func (m *MemoryRepository) GetAllServices(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.services))
+	for name := range m.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *MemoryRepository) GetHealthyInstances(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var healthy []*domain.ServiceInstance
+	for _, id := range m.services[serviceName] {
+		if instance := m.instances[id]; instance != nil && instance.IsHealthy() {
+			healthy = append(healthy, instance)
+		}
+	}
+	return healthy, nil
+}
+
+func (m *MemoryRepository) SaveTrafficPolicy(ctx context.Context, serviceName string, policy *domain.TrafficPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.policies[serviceName] = policy
+	return nil
+}
+
+func (m *MemoryRepository) GetTrafficPolicy(ctx context.Context, serviceName string) (*domain.TrafficPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	policy, exists := m.policies[serviceName]
+	if !exists {
+		return nil, domain.ErrTrafficPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (m *MemoryRepository) GetAllTrafficPolicies(ctx context.Context) (map[string]*domain.TrafficPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.Clone(m.policies), nil
+}
+
+func (m *MemoryRepository) DeleteTrafficPolicy(ctx context.Context, serviceName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.policies[serviceName]; !exists {
+		return domain.ErrTrafficPolicyNotFound
+	}
+	delete(m.policies, serviceName)
+	return nil
+}
+
+// exactCollision describes an existing entry that a new route clashes with.
+func exactCollision(entry *domain.RouteEntry) domain.RouteCollision {
+	return domain.RouteCollision{
+		Method:        entry.Route.Method,
+		Path:          entry.Route.FullPath(entry.BasePath),
+		CollisionType: domain.ExactCollision,
+		RegisteredBy:  entry.ServiceName,
+		RegisteredAt:  entry.RegisteredAt,
+	}
+}
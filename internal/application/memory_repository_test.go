@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func memoryRepo(r *Registry) *MemoryRepository {
+	return r.repo.(*MemoryRepository)
+}
+
+func TestMemoryRepository_InstanceLifecycle(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	instance := &domain.ServiceInstance{ID: "instance-1", ServiceName: "svc", Status: domain.StatusHealthy}
+	if err := repo.SaveInstance(ctx, instance); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.GetInstance(ctx, "instance-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ServiceName != "svc" {
+		t.Errorf("expected service svc, got %s", got.ServiceName)
+	}
+
+	if err := repo.UpdateInstanceStatus(ctx, "instance-1", domain.StatusUnhealthy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	healthy, _ := repo.GetHealthyInstances(ctx, "svc")
+	if len(healthy) != 0 {
+		t.Errorf("expected 0 healthy instances, got %d", len(healthy))
+	}
+
+	if err := repo.DeleteInstance(ctx, "instance-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.GetInstance(ctx, "instance-1"); err != domain.ErrInstanceNotFound {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+	services, _ := repo.GetAllServices(ctx)
+	if len(services) != 0 {
+		t.Errorf("expected no services, got %v", services)
+	}
+}
+
+func TestMemoryRepository_NotFound(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	if err := repo.DeleteInstance(ctx, "missing"); err != domain.ErrInstanceNotFound {
+		t.Errorf("DeleteInstance: expected ErrInstanceNotFound, got %v", err)
+	}
+	if err := repo.UpdateHeartbeat(ctx, "missing"); err != domain.ErrInstanceNotFound {
+		t.Errorf("UpdateHeartbeat: expected ErrInstanceNotFound, got %v", err)
+	}
+	if _, err := repo.GetRoute(ctx, "GET", "/missing"); err != domain.ErrRouteNotFound {
+		t.Errorf("GetRoute: expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepository_Routes(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	routes := []domain.Route{{Method: "GET", Path: "/users"}}
+	if err := repo.SaveRoutes(ctx, "svc", "/api/v1", routes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	collisions, _ := repo.CheckRouteCollisions(ctx, "/api/v1", routes)
+	if len(collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %d", len(collisions))
+	}
+	if collisions[0].RegisteredBy != "svc" || collisions[0].Path != "/api/v1/users" {
+		t.Errorf("unexpected collision: %+v", collisions[0])
+	}
+
+	if err := repo.DeleteRoutesByService(ctx, "svc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	all, _ := repo.GetAllRoutes(ctx)
+	if len(all) != 0 {
+		t.Errorf("expected 0 routes, got %d", len(all))
+	}
+}
+
+func TestMemoryRepository_SaveRoutesRejectsOtherOwner(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	routes := []domain.Route{{Method: "GET", Path: "/users"}}
+	_ = repo.SaveRoutes(ctx, "svc-a", "/api/v1", routes)
+
+	err := repo.SaveRoutes(ctx, "svc-b", "/api/v1", append(routes, domain.Route{Method: "POST", Path: "/users"}))
+	collisionErr, ok := err.(*domain.CollisionError)
+	if !ok {
+		t.Fatalf("expected *CollisionError, got %v", err)
+	}
+	if len(collisionErr.Collisions) != 1 || collisionErr.Collisions[0].RegisteredBy != "svc-a" {
+		t.Errorf("unexpected collisions: %+v", collisionErr.Collisions)
+	}
+	if _, err := repo.GetRoute(ctx, "POST", "/api/v1/users"); err != domain.ErrRouteNotFound {
+		t.Error("no route should be saved when one of them collides")
+	}
+
+	if err := repo.SaveRoutes(ctx, "svc-a", "/api/v1", routes); err != nil {
+		t.Errorf("owner should be able to save its routes again, got %v", err)
+	}
+}
+
+func TestMemoryRepository_DeleteLastInstanceRemovesRoutes(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_ = repo.SaveInstance(ctx, &domain.ServiceInstance{ID: "instance-1", ServiceName: "svc"})
+	_ = repo.SaveInstance(ctx, &domain.ServiceInstance{ID: "instance-2", ServiceName: "svc"})
+	_ = repo.SaveRoutes(ctx, "svc", "/api/v1", []domain.Route{{Method: "GET", Path: "/users"}})
+
+	_ = repo.DeleteInstance(ctx, "instance-1")
+	if _, err := repo.GetRoute(ctx, "GET", "/api/v1/users"); err != nil {
+		t.Fatalf("routes should stay while an instance remains, got %v", err)
+	}
+
+	_ = repo.DeleteInstance(ctx, "instance-2")
+	if _, err := repo.GetRoute(ctx, "GET", "/api/v1/users"); err != domain.ErrRouteNotFound {
+		t.Errorf("routes should be removed with the last instance, got %v", err)
+	}
+}
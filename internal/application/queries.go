@@ -1,69 +1,72 @@
 package application
 
-import "github.com/apascualco/gotway/internal/domain"
+import (
+	"context"
+	"errors"
+	"log/slog"
 
-func (r *Registry) GetInstance(instanceID string) *domain.ServiceInstance {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	"github.com/apascualco/gotway/internal/domain"
+)
 
-	return r.instances[instanceID]
+func (r *Registry) GetInstance(instanceID string) *domain.ServiceInstance {
+	instance, err := r.repo.GetInstance(context.Background(), instanceID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrInstanceNotFound) {
+			slog.Error("failed to get instance", "instance_id", instanceID, "error", err)
+		}
+		return nil
+	}
+	return instance
 }
 
 func (r *Registry) GetInstances(serviceName string) []*domain.ServiceInstance {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	instanceIDs := r.services[serviceName]
-	if len(instanceIDs) == 0 {
+	instances, err := r.repo.GetInstancesByService(context.Background(), serviceName)
+	if err != nil {
+		slog.Error("failed to get instances", "service", serviceName, "error", err)
 		return nil
 	}
-
-	instances := make([]*domain.ServiceInstance, 0, len(instanceIDs))
-	for _, id := range instanceIDs {
-		if instance := r.instances[id]; instance != nil {
-			instances = append(instances, instance)
-		}
+	if len(instances) == 0 {
+		return nil
 	}
 	return instances
 }
 
 func (r *Registry) GetHealthyInstances(serviceName string) []*domain.ServiceInstance {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	instanceIDs := r.services[serviceName]
-	if len(instanceIDs) == 0 {
+	healthy, err := r.repo.GetHealthyInstances(context.Background(), serviceName)
+	if err != nil {
+		slog.Error("failed to get healthy instances", "service", serviceName, "error", err)
 		return nil
 	}
-
-	var healthy []*domain.ServiceInstance
-	for _, id := range instanceIDs {
-		if instance := r.instances[id]; instance != nil && instance.Status == domain.StatusHealthy {
-			healthy = append(healthy, instance)
-		}
-	}
 	return healthy
 }
 
 func (r *Registry) GetRoute(method, path string) *domain.RouteEntry {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	key := method + ":" + path
-	return r.routes[key]
+	entry, err := r.repo.GetRoute(context.Background(), method, path)
+	if err != nil {
+		if !errors.Is(err, domain.ErrRouteNotFound) {
+			slog.Error("failed to get route", "method", method, "path", path, "error", err)
+		}
+		return nil
+	}
+	return entry
 }
 
 func (r *Registry) GetAllServices() map[string][]*domain.ServiceInstance {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	ctx := context.Background()
 
 	result := make(map[string][]*domain.ServiceInstance)
-	for serviceName, instanceIDs := range r.services {
-		instances := make([]*domain.ServiceInstance, 0, len(instanceIDs))
-		for _, id := range instanceIDs {
-			if instance := r.instances[id]; instance != nil {
-				instances = append(instances, instance)
-			}
+
+	services, err := r.repo.GetAllServices(ctx)
+	if err != nil {
+		slog.Error("failed to get services", "error", err)
+		return result
+	}
+
+	for _, serviceName := range services {
+		instances, err := r.repo.GetInstancesByService(ctx, serviceName)
+		if err != nil {
+			slog.Error("failed to get instances", "service", serviceName, "error", err)
+			continue
 		}
 		if len(instances) > 0 {
 			result[serviceName] = instances
@@ -73,12 +76,16 @@ func (r *Registry) GetAllServices() map[string][]*domain.ServiceInstance {
 }
 
 func (r *Registry) GetAllRoutes() map[string]*domain.RouteEntry {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	entries, err := r.repo.GetAllRoutes(context.Background())
+	if err != nil {
+		slog.Error("failed to get routes", "error", err)
+		return map[string]*domain.RouteEntry{}
+	}
 
-	result := make(map[string]*domain.RouteEntry, len(r.routes))
-	for k, v := range r.routes {
-		result[k] = v
+	result := make(map[string]*domain.RouteEntry, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		result[entry.Route.Key(entry.BasePath)] = entry
 	}
 	return result
 }
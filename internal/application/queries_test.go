@@ -85,7 +85,7 @@ func TestGetHealthyInstances(t *testing.T) {
 		t.Errorf("expected 1 healthy instance, got %d", len(healthy))
 	}
 
-	registry.instances[resp.InstanceID].Status = domain.StatusUnhealthy
+	memoryRepo(registry).instances[resp.InstanceID].Status = domain.StatusUnhealthy
 
 	healthy = registry.GetHealthyInstances("test-service")
 	if len(healthy) != 0 {
@@ -1,7 +1,10 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/apascualco/gotway/internal/domain"
@@ -12,8 +15,24 @@ func (r *Registry) Register(req *domain.RegisterRequest) (*domain.RegisterRespon
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
+	if err := validateBalancerMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	collisions, err := r.ValidateRoutes(req.ServiceName, req.BasePath, req.Routes)
+	existing, err := r.repo.GetInstancesByService(ctx, req.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load service instances: %w", err)
+	}
+	if err := checkBalancerMetadataConsistency(req.Metadata, existing); err != nil {
+		return nil, err
+	}
+
+	collisions, err := r.validateRoutes(ctx, req.ServiceName, req.BasePath, req.Routes)
 	if err != nil {
 		return nil, err
 	}
@@ -29,33 +48,36 @@ func (r *Registry) Register(req *domain.RegisterRequest) (*domain.RegisterRespon
 		ServiceName:   req.ServiceName,
 		Host:          req.Host,
 		Port:          req.Port,
+		Scheme:        req.Scheme,
+		TLSServerName: req.TLSServerName,
+		Protocol:      req.Protocol,
 		HealthURL:     req.HealthURL,
 		Version:       req.Version,
 		Status:        domain.StatusHealthy,
-		Weight:        1,
+		Weight:        req.Weight,
 		Metadata:      req.Metadata,
 		RegisteredAt:  now,
 		LastHeartbeat: now,
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.instances[instanceID] = instance
-	r.services[req.ServiceName] = append(r.services[req.ServiceName], instanceID)
+	if err := r.repo.SaveInstance(ctx, instance); err != nil {
+		return nil, fmt.Errorf("failed to save instance: %w", err)
+	}
+	if err := r.repo.SaveRoutes(ctx, req.ServiceName, req.BasePath, req.Routes); err != nil {
+		// Another replica may have claimed a route since validateRoutes ran.
+		if deleteErr := r.repo.DeleteInstance(ctx, instanceID); deleteErr != nil {
+			slog.Error("failed to roll back instance", "instance_id", instanceID, "error", deleteErr)
+		}
+		var collisionErr *domain.CollisionError
+		if errors.As(err, &collisionErr) {
+			return nil, collisionErr
+		}
+		return nil, fmt.Errorf("failed to save routes: %w", err)
+	}
 
 	var registeredRoutes []string
 	for _, route := range req.Routes {
-		fullPath := route.FullPath(req.BasePath)
-		key := route.Method + ":" + fullPath
-
-		r.routes[key] = &domain.RouteEntry{
-			ServiceName:  req.ServiceName,
-			BasePath:     req.BasePath,
-			Route:        route,
-			RegisteredAt: now,
-		}
-		registeredRoutes = append(registeredRoutes, key)
+		registeredRoutes = append(registeredRoutes, route.Key(req.BasePath))
 	}
 
 	return &domain.RegisterResponse{
@@ -70,31 +92,5 @@ func (r *Registry) Deregister(instanceID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	instance, exists := r.instances[instanceID]
-	if !exists {
-		return domain.ErrInstanceNotFound
-	}
-
-	serviceName := instance.ServiceName
-
-	instanceIDs := r.services[serviceName]
-	for i, id := range instanceIDs {
-		if id == instanceID {
-			r.services[serviceName] = append(instanceIDs[:i], instanceIDs[i+1:]...)
-			break
-		}
-	}
-
-	if len(r.services[serviceName]) == 0 {
-		for key, entry := range r.routes {
-			if entry.ServiceName == serviceName {
-				delete(r.routes, key)
-			}
-		}
-		delete(r.services, serviceName)
-	}
-
-	delete(r.instances, instanceID)
-
-	return nil
+	return r.repo.DeleteInstance(context.Background(), instanceID)
 }
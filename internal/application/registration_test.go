@@ -38,14 +38,14 @@ func TestRegister_Success(t *testing.T) {
 		t.Errorf("expected 2 registered routes, got %d", len(resp.RegisteredRoutes))
 	}
 
-	if _, exists := registry.instances[resp.InstanceID]; !exists {
+	if _, exists := memoryRepo(registry).instances[resp.InstanceID]; !exists {
 		t.Error("instance not stored in registry")
 	}
-	if len(registry.services["user-service"]) != 1 {
+	if len(memoryRepo(registry).services["user-service"]) != 1 {
 		t.Error("service not stored in registry")
 	}
-	if len(registry.routes) != 2 {
-		t.Errorf("expected 2 routes, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 2 {
+		t.Errorf("expected 2 routes, got %d", len(memoryRepo(registry).routes))
 	}
 }
 
@@ -161,11 +161,11 @@ func TestDeregister_Success(t *testing.T) {
 	}
 	resp, _ := registry.Register(req)
 
-	if len(registry.instances) != 1 {
-		t.Fatalf("expected 1 instance, got %d", len(registry.instances))
+	if len(memoryRepo(registry).instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(memoryRepo(registry).instances))
 	}
-	if len(registry.routes) != 2 {
-		t.Fatalf("expected 2 routes, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(memoryRepo(registry).routes))
 	}
 
 	err := registry.Deregister(resp.InstanceID)
@@ -173,14 +173,14 @@ func TestDeregister_Success(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(registry.instances) != 0 {
-		t.Errorf("expected 0 instances, got %d", len(registry.instances))
+	if len(memoryRepo(registry).instances) != 0 {
+		t.Errorf("expected 0 instances, got %d", len(memoryRepo(registry).instances))
 	}
-	if len(registry.routes) != 0 {
-		t.Errorf("expected 0 routes, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 0 {
+		t.Errorf("expected 0 routes, got %d", len(memoryRepo(registry).routes))
 	}
-	if len(registry.services["test-service"]) != 0 {
-		t.Errorf("expected 0 service entries, got %d", len(registry.services["test-service"]))
+	if len(memoryRepo(registry).services["test-service"]) != 0 {
+		t.Errorf("expected 0 service entries, got %d", len(memoryRepo(registry).services["test-service"]))
 	}
 }
 
@@ -214,16 +214,93 @@ func TestDeregister_RoutesRemoved(t *testing.T) {
 	}
 	_, _ = registry.Register(req2)
 
-	if len(registry.routes) != 2 {
-		t.Fatalf("expected 2 routes, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(memoryRepo(registry).routes))
 	}
 
 	_ = registry.Deregister(resp1.InstanceID)
 
-	if len(registry.routes) != 1 {
-		t.Errorf("expected 1 route after deregister, got %d", len(registry.routes))
+	if len(memoryRepo(registry).routes) != 1 {
+		t.Errorf("expected 1 route after deregister, got %d", len(memoryRepo(registry).routes))
 	}
-	if _, exists := registry.routes["GET:/api/v2/b"]; !exists {
+	if _, exists := memoryRepo(registry).routes["GET:/api/v2/b"]; !exists {
 		t.Error("service-b route should still exist")
 	}
 }
+
+func TestRegister_StoresWeight(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{})
+
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		Weight:      3,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/test"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if weight := registry.GetInstance(resp.InstanceID).Weight; weight != 3 {
+		t.Errorf("expected weight 3, got %d", weight)
+	}
+}
+
+func TestRegister_RejectsInvalidBalancerMetadata(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+
+	tests := []map[string]string{
+		{domain.MetadataLoadBalancer: "fastest"},
+		{domain.MetadataLoadBalancer: "ring_hash", domain.MetadataHashKey: "header"},
+		{domain.MetadataHashKey: "query=id"},
+	}
+
+	for _, metadata := range tests {
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "user-service",
+			Host:        "localhost",
+			Port:        8081,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{{Method: "GET", Path: "/users"}},
+			Metadata:    metadata,
+		})
+		if err == nil {
+			t.Errorf("expected error for metadata %v", metadata)
+		}
+	}
+}
+
+func TestRegister_RejectsConflictingBalancerMetadata(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+
+	register := func(port int, metadata map[string]string) error {
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "user-service",
+			Host:        "localhost",
+			Port:        port,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{{Method: "GET", Path: "/users"}},
+			Metadata:    metadata,
+		})
+		return err
+	}
+
+	sticky := map[string]string{
+		domain.MetadataLoadBalancer: "ring_hash",
+		domain.MetadataHashKey:      "header=X-User-ID",
+	}
+	if err := register(8081, sticky); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := register(8082, sticky); err != nil {
+		t.Fatalf("instance with the same metadata should register, got %v", err)
+	}
+	if err := register(8083, map[string]string{domain.MetadataLoadBalancer: "random"}); err == nil {
+		t.Error("expected error for a different strategy")
+	}
+	if err := register(8084, nil); err == nil {
+		t.Error("expected error for missing balancing metadata")
+	}
+}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apascualco/gotway/internal/domain"
@@ -15,20 +16,25 @@ type RegistryConfig struct {
 }
 
 type Registry struct {
-	config    RegistryConfig
-	mu        sync.RWMutex
-	instances map[string]*domain.ServiceInstance
-	services  map[string][]string
-	routes    map[string]*domain.RouteEntry
-	stopCh    chan struct{}
+	config   RegistryConfig
+	mu       sync.Mutex
+	repo     domain.Repository
+	stopCh   chan struct{}
+	routes   atomic.Pointer[routeTable]
+	routesMu sync.Mutex
 }
 
+// NewRegistry creates a registry backed by a process-local MemoryRepository.
 func NewRegistry(cfg RegistryConfig) *Registry {
+	return NewRegistryWithRepository(cfg, NewMemoryRepository())
+}
+
+// NewRegistryWithRepository creates a registry that delegates persistence to repo,
+// allowing several gateway replicas to share the same registry state.
+func NewRegistryWithRepository(cfg RegistryConfig, repo domain.Repository) *Registry {
 	return &Registry{
-		config:    cfg,
-		instances: make(map[string]*domain.ServiceInstance),
-		services:  make(map[string][]string),
-		routes:    make(map[string]*domain.RouteEntry),
-		stopCh:    make(chan struct{}),
+		config: cfg,
+		repo:   repo,
+		stopCh: make(chan struct{}),
 	}
 }
@@ -0,0 +1,103 @@
+package application
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudgetBuckets is how many slices the budget window is divided into.
+const retryBudgetBuckets = 10
+
+type RetryBudgetConfig struct {
+	// Ratio is the share of a service's requests in Window that may be
+	// retried, e.g. 0.2 lets retries add at most 20% extra load.
+	Ratio float64
+	// MinRetriesPerSecond keeps retries possible for services with little
+	// traffic, where Ratio alone would allow almost none.
+	MinRetriesPerSecond int
+	Window              time.Duration
+}
+
+// RetryBudget bounds retries per service, so retrying cannot multiply the
+// load on a service that is already failing.
+type RetryBudget struct {
+	config   RetryBudgetConfig
+	mu       sync.Mutex
+	services map[string]*[retryBudgetBuckets]budgetBucket
+	now      func() time.Time
+}
+
+type budgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+func NewRetryBudget(cfg RetryBudgetConfig) *RetryBudget {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Ratio < 0 {
+		cfg.Ratio = 0
+	}
+	if cfg.MinRetriesPerSecond < 0 {
+		cfg.MinRetriesPerSecond = 0
+	}
+
+	return &RetryBudget{
+		config:   cfg,
+		services: make(map[string]*[retryBudgetBuckets]budgetBucket),
+		now:      time.Now,
+	}
+}
+
+// Request counts a client request to serviceName, earning it retry budget.
+func (b *RetryBudget) Request(serviceName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bucket(serviceName, b.now()).requests++
+}
+
+// TryRetry spends one retry of serviceName's budget, reporting false when
+// none is left.
+func (b *RetryBudget) TryRetry(serviceName string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	requests, retries := 0, 0
+	if buckets := b.services[serviceName]; buckets != nil {
+		for _, bucket := range buckets {
+			if now.Sub(bucket.start) < b.config.Window {
+				requests += bucket.requests
+				retries += bucket.retries
+			}
+		}
+	}
+
+	allowed := max(b.config.Ratio*float64(requests), float64(b.config.MinRetriesPerSecond)*b.config.Window.Seconds())
+	if float64(retries) >= allowed {
+		return false
+	}
+	b.bucket(serviceName, now).retries++
+	return true
+}
+
+// bucket returns the window slice now falls in for serviceName, resetting it
+// if it last held an older slice. Callers must hold mu.
+func (b *RetryBudget) bucket(serviceName string, now time.Time) *budgetBucket {
+	buckets := b.services[serviceName]
+	if buckets == nil {
+		buckets = &[retryBudgetBuckets]budgetBucket{}
+		b.services[serviceName] = buckets
+	}
+
+	width := max(b.config.Window/retryBudgetBuckets, time.Millisecond)
+	start := now.Truncate(width)
+	bucket := &buckets[(start.UnixNano()/int64(width))%retryBudgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	return bucket
+}
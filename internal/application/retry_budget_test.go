@@ -0,0 +1,50 @@
+package application
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_LimitsRetriesToRatioOfRequests(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	budget := NewRetryBudget(RetryBudgetConfig{Ratio: 0.2, Window: 10 * time.Second})
+	budget.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		budget.Request("orders")
+	}
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if budget.TryRetry("orders") {
+			allowed++
+		}
+	}
+	if allowed != 4 {
+		t.Errorf("expected 4 retries for 20 requests at ratio 0.2, got %d", allowed)
+	}
+	if budget.TryRetry("payments") {
+		t.Error("a service without requests should have no budget")
+	}
+
+	now = now.Add(11 * time.Second)
+	if budget.TryRetry("orders") {
+		t.Error("requests outside the window should not earn budget")
+	}
+}
+
+func TestRetryBudget_MinRetriesPerSecond(t *testing.T) {
+	budget := NewRetryBudget(RetryBudgetConfig{MinRetriesPerSecond: 1, Window: 2 * time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	budget.now = func() time.Time { return now }
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if budget.TryRetry("orders") {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("expected 2 retries over a 2s window at 1 per second, got %d", allowed)
+	}
+}
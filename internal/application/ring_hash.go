@@ -0,0 +1,228 @@
+package application
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+type HashKeySource string
+
+const (
+	HashKeyHeader   HashKeySource = "header"
+	HashKeyCookie   HashKeySource = "cookie"
+	HashKeyClientIP HashKeySource = "ip"
+	HashKeySubject  HashKeySource = "sub"
+	// HashKeyClientCert keys on the subject of the verified client certificate.
+	HashKeyClientCert HashKeySource = "client_cert"
+)
+
+// HashKey identifies the request attribute used for consistent hashing.
+type HashKey struct {
+	Source HashKeySource
+	Name   string
+}
+
+// ParseHashKey parses "header=<name>", "cookie=<name>", "ip", "sub" or
+// "client_cert".
+func ParseHashKey(s string) (HashKey, error) {
+	source, name, _ := strings.Cut(s, "=")
+	key := HashKey{Source: HashKeySource(source), Name: name}
+
+	switch key.Source {
+	case HashKeyHeader, HashKeyCookie:
+		if key.Name == "" {
+			return HashKey{}, fmt.Errorf("hash key %q requires a name", s)
+		}
+	case HashKeyClientIP, HashKeySubject, HashKeyClientCert:
+		if key.Name != "" {
+			return HashKey{}, fmt.Errorf("hash key %q does not take a name", s)
+		}
+	default:
+		return HashKey{}, fmt.Errorf("unknown hash key source %q", source)
+	}
+	return key, nil
+}
+
+func (k HashKey) String() string {
+	if k.Name == "" {
+		return string(k.Source)
+	}
+	return string(k.Source) + "=" + k.Name
+}
+
+// Extract returns the value to hash, or "" when the request does not carry it.
+func (k HashKey) Extract(info *RequestInfo) string {
+	if info == nil {
+		return ""
+	}
+
+	switch k.Source {
+	case HashKeyHeader:
+		if info.Request != nil {
+			return info.Request.Header.Get(k.Name)
+		}
+	case HashKeyCookie:
+		if info.Request != nil {
+			if cookie, err := info.Request.Cookie(k.Name); err == nil {
+				return cookie.Value
+			}
+		}
+	case HashKeyClientIP:
+		return info.ClientIP
+	case HashKeySubject:
+		return info.Subject
+	case HashKeyClientCert:
+		return info.ClientCertSubject
+	}
+	return ""
+}
+
+// ringReplicas is the number of virtual nodes placed on the ring per unit of
+// weight. Rings that would exceed maxRingPoints are scaled down, keeping the
+// weight ratios but never dropping an instance below one point.
+const (
+	ringReplicas  = 160
+	maxRingPoints = 1 << 16
+)
+
+type ringPoint struct {
+	hash uint64
+	id   string
+}
+
+type hashRing struct {
+	signature string
+	points    []ringPoint
+}
+
+// RingHashBalancer maps each request key to an instance on a consistent hash
+// ring, so the same key keeps landing on the same instance and only the keys
+// owned by an instance move when it joins or leaves. Requests without a key
+// fall back to round-robin.
+type RingHashBalancer struct {
+	key      HashKey
+	fallback *RoundRobinBalancer
+	mu       sync.Mutex
+	ring     atomic.Pointer[hashRing]
+}
+
+func NewRingHashBalancer(key HashKey) *RingHashBalancer {
+	if key.Source == "" {
+		key.Source = HashKeyClientIP
+	}
+	return &RingHashBalancer{
+		key:      key,
+		fallback: NewRoundRobinBalancer(),
+	}
+}
+
+func (r *RingHashBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	return r.fallback.Select(instances)
+}
+
+func (r *RingHashBalancer) SelectForRequest(info *RequestInfo, instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	key := r.key.Extract(info)
+	if key == "" {
+		return r.fallback.Select(instances)
+	}
+
+	ring := r.ringFor(instances)
+	h := hashString(key)
+	idx := sort.Search(len(ring.points), func(i int) bool {
+		return ring.points[i].hash >= h
+	})
+	if idx == len(ring.points) {
+		idx = 0
+	}
+
+	id := ring.points[idx].id
+	for _, instance := range instances {
+		if instance.ID == id {
+			return instance
+		}
+	}
+	return r.fallback.Select(instances)
+}
+
+func (r *RingHashBalancer) ringFor(instances []*domain.ServiceInstance) *hashRing {
+	signature := ringSignature(instances)
+	if ring := r.ring.Load(); ring != nil && ring.signature == signature {
+		return ring
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ring := r.ring.Load(); ring != nil && ring.signature == signature {
+		return ring
+	}
+
+	ring := buildRing(signature, instances)
+	r.ring.Store(ring)
+	return ring
+}
+
+func buildRing(signature string, instances []*domain.ServiceInstance) *hashRing {
+	totalWeight := 0
+	for _, instance := range instances {
+		totalWeight += instanceWeight(instance)
+	}
+	scale := 1.0
+	if total := totalWeight * ringReplicas; total > maxRingPoints {
+		scale = float64(maxRingPoints) / float64(total)
+	}
+
+	var points []ringPoint
+	for _, instance := range instances {
+		replicas := max(1, int(float64(ringReplicas*instanceWeight(instance))*scale))
+		for i := 0; i < replicas; i++ {
+			points = append(points, ringPoint{
+				hash: hashString(instance.ID + "#" + strconv.Itoa(i)),
+				id:   instance.ID,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash == points[j].hash {
+			return points[i].id < points[j].id
+		}
+		return points[i].hash < points[j].hash
+	})
+	return &hashRing{signature: signature, points: points}
+}
+
+func ringSignature(instances []*domain.ServiceInstance) string {
+	var b strings.Builder
+	for _, instance := range instances {
+		b.WriteString(instance.ID)
+		b.WriteByte('/')
+		b.WriteString(strconv.Itoa(instanceWeight(instance)))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// hashString is FNV-1a followed by a 64-bit finalizer so that similar keys
+// spread evenly and every gateway replica computes the same ring.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,185 @@
+package application
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func ringInstances(n int) []*domain.ServiceInstance {
+	instances := make([]*domain.ServiceInstance, n)
+	for i := range instances {
+		instances[i] = &domain.ServiceInstance{ID: fmt.Sprintf("instance-%d", i), ServiceName: "svc"}
+	}
+	return instances
+}
+
+func headerRequest(value string) *RequestInfo {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User-ID", value)
+	return &RequestInfo{Request: req}
+}
+
+func TestRingHash_SameKeySameInstance(t *testing.T) {
+	lb := NewRingHashBalancer(HashKey{Source: HashKeyHeader, Name: "X-User-ID"})
+	instances := ringInstances(5)
+
+	first := lb.SelectForRequest(headerRequest("user-42"), instances)
+	for i := 0; i < 20; i++ {
+		if selected := lb.SelectForRequest(headerRequest("user-42"), instances); selected.ID != first.ID {
+			t.Fatalf("iteration %d: expected %s, got %s", i, first.ID, selected.ID)
+		}
+	}
+}
+
+func TestRingHash_MinimalRemapOnRemoval(t *testing.T) {
+	lb := NewRingHashBalancer(HashKey{Source: HashKeyHeader, Name: "X-User-ID"})
+	instances := ringInstances(5)
+
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before[key] = lb.SelectForRequest(headerRequest(key), instances).ID
+	}
+
+	removed := instances[2].ID
+	remaining := append(append([]*domain.ServiceInstance{}, instances[:2]...), instances[3:]...)
+
+	for key, previous := range before {
+		current := lb.SelectForRequest(headerRequest(key), remaining).ID
+		if previous != removed && current != previous {
+			t.Fatalf("key %s moved from %s to %s although its instance stayed", key, previous, current)
+		}
+	}
+}
+
+func TestRingHash_Distribution(t *testing.T) {
+	lb := NewRingHashBalancer(HashKey{Source: HashKeyHeader, Name: "X-User-ID"})
+	instances := ringInstances(4)
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[lb.SelectForRequest(headerRequest(fmt.Sprintf("user-%d", i)), instances).ID]++
+	}
+
+	for id, count := range counts {
+		if count < 600 || count > 1400 {
+			t.Errorf("instance %s got %d of 4000 keys, expected roughly 1000", id, count)
+		}
+	}
+}
+
+func TestRingHash_FallbackWithoutKey(t *testing.T) {
+	lb := NewRingHashBalancer(HashKey{Source: HashKeyHeader, Name: "X-User-ID"})
+	instances := ringInstances(2)
+
+	info := &RequestInfo{Request: httptest.NewRequest("GET", "/", nil)}
+	first := lb.SelectForRequest(info, instances)
+	second := lb.SelectForRequest(info, instances)
+	if first.ID == second.ID {
+		t.Error("requests without a key should round-robin")
+	}
+
+	if selected := lb.SelectForRequest(headerRequest("user-1"), nil); selected != nil {
+		t.Errorf("expected nil for empty list, got %v", selected)
+	}
+}
+
+func TestParseHashKey(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    HashKey
+		wantErr bool
+	}{
+		{"header=X-User-ID", HashKey{Source: HashKeyHeader, Name: "X-User-ID"}, false},
+		{"cookie=session", HashKey{Source: HashKeyCookie, Name: "session"}, false},
+		{"ip", HashKey{Source: HashKeyClientIP}, false},
+		{"sub", HashKey{Source: HashKeySubject}, false},
+		{"header", HashKey{}, true},
+		{"ip=foo", HashKey{}, true},
+		{"query=id", HashKey{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHashKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHashKey(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHashKey(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashKey_Extract(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	info := &RequestInfo{Request: req, ClientIP: "10.0.0.1", Subject: "user-7"}
+
+	tests := []struct {
+		key  HashKey
+		want string
+	}{
+		{HashKey{Source: HashKeyHeader, Name: "X-Tenant"}, "acme"},
+		{HashKey{Source: HashKeyCookie, Name: "session"}, "abc"},
+		{HashKey{Source: HashKeyCookie, Name: "missing"}, ""},
+		{HashKey{Source: HashKeyClientIP}, "10.0.0.1"},
+		{HashKey{Source: HashKeySubject}, "user-7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key.String(), func(t *testing.T) {
+			if got := tt.key.Extract(info); got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceBalancer_RingHashFromMetadata(t *testing.T) {
+	sb, _ := NewServiceBalancer(ServiceBalancerConfig{})
+
+	instances := ringInstances(3)
+	for _, instance := range instances {
+		instance.Metadata = map[string]string{
+			domain.MetadataLoadBalancer: string(StrategyRingHash),
+			domain.MetadataHashKey:      "sub",
+		}
+	}
+
+	info := &RequestInfo{Subject: "user-99"}
+	first := sb.SelectForRequest(info, instances)
+	for i := 0; i < 10; i++ {
+		if selected := sb.SelectForRequest(info, instances); selected.ID != first.ID {
+			t.Fatalf("expected sticky selection %s, got %s", first.ID, selected.ID)
+		}
+	}
+}
+
+func TestRingHash_BoundsRingSize(t *testing.T) {
+	instances := ringInstances(3)
+	instances[0].Weight = domain.MaxWeight
+	instances[1].Weight = domain.MaxWeight
+
+	ring := buildRing(ringSignature(instances), instances)
+	if len(ring.points) > maxRingPoints+len(instances) {
+		t.Fatalf("ring has %d points, expected at most about %d", len(ring.points), maxRingPoints)
+	}
+
+	counts := make(map[string]int)
+	for _, point := range ring.points {
+		counts[point.id]++
+	}
+	if counts["instance-2"] == 0 {
+		t.Error("light instance should keep at least one point")
+	}
+	if counts["instance-0"] <= counts["instance-2"] {
+		t.Errorf("heavy instance should own more points, got %d vs %d", counts["instance-0"], counts["instance-2"])
+	}
+}
@@ -0,0 +1,331 @@
+package application
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// routeVersioner is implemented by repositories that can tell cheaply whether
+// their routes changed, so the compiled route table is only rebuilt when they
+// do. Other repositories have it rebuilt on every lookup.
+type routeVersioner interface {
+	RoutesVersion() uint64
+}
+
+// routeTable is an immutable compilation of the registered routes into one
+// segment trie per method. At every segment a static child is tried first,
+// then parameters with a constraint, then those without, then a catch-all,
+// backtracking when a branch does not lead to a route, so overlapping
+// patterns resolve the same way on every request. Among the routes on the
+// matched path whose host, header and query conditions hold, the most
+// specific one wins.
+type routeTable struct {
+	version   uint64
+	versioned bool
+	trees     map[string]*routeNode
+}
+
+type routeNode struct {
+	static   map[string]*routeNode
+	params   []*paramChild
+	catchAll *routeNode
+	leaves   []*routeLeaf
+}
+
+// paramChild is the branch taken by parameter segments sharing the same
+// constraint, or no constraint.
+type paramChild struct {
+	constraint *regexp.Regexp
+	node       *routeNode
+}
+
+// routeLeaf is the route a pattern ends at, with the names of its parameters
+// in the order their segments appear.
+type routeLeaf struct {
+	entry  *domain.RouteEntry
+	params []string
+}
+
+func newRouteTable(entries []domain.RouteEntry) *routeTable {
+	// Sorted so that, should two routes compile to the same pattern, the
+	// same one wins on every rebuild.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Route.Key(entries[i].BasePath) < entries[j].Route.Key(entries[j].BasePath)
+	})
+
+	table := &routeTable{trees: make(map[string]*routeNode)}
+	for i := range entries {
+		entry := &entries[i]
+		segments, err := domain.ParsePathPattern(entry.Route.FullPath(entry.BasePath))
+		if err != nil {
+			slog.Warn("skipping route with an invalid pattern",
+				"service", entry.ServiceName,
+				"route", entry.Route.Key(entry.BasePath),
+				"error", err,
+			)
+			continue
+		}
+		root := table.trees[entry.Route.Method]
+		if root == nil {
+			root = &routeNode{}
+			table.trees[entry.Route.Method] = root
+		}
+		root.insert(entry, segments)
+	}
+	return table
+}
+
+func (n *routeNode) insert(entry *domain.RouteEntry, segments []domain.PatternSegment) {
+	var params []string
+	for _, segment := range segments {
+		switch segment.Kind {
+		case domain.StaticSegment:
+			if n.static == nil {
+				n.static = make(map[string]*routeNode)
+			}
+			child := n.static[segment.Value]
+			if child == nil {
+				child = &routeNode{}
+				n.static[segment.Value] = child
+			}
+			n = child
+		case domain.ParamSegment:
+			params = append(params, segment.Value)
+			n = n.paramChild(segment.Constraint)
+		case domain.CatchAllSegment:
+			params = append(params, segment.Value)
+			if n.catchAll == nil {
+				n.catchAll = &routeNode{}
+			}
+			n = n.catchAll
+		}
+	}
+	n.leaves = append(n.leaves, &routeLeaf{entry: entry, params: params})
+}
+
+// paramChild returns the branch for parameters with constraint, keeping the
+// constrained branches ahead of the unconstrained one.
+func (n *routeNode) paramChild(constraint *regexp.Regexp) *routeNode {
+	for _, child := range n.params {
+		if sameConstraint(child.constraint, constraint) {
+			return child.node
+		}
+	}
+	child := &paramChild{constraint: constraint, node: &routeNode{}}
+	if constraint == nil {
+		n.params = append(n.params, child)
+		return child.node
+	}
+	i := 0
+	for i < len(n.params) && n.params[i].constraint != nil {
+		i++
+	}
+	n.params = slices.Insert(n.params, i, child)
+	return child.node
+}
+
+func sameConstraint(a, b *regexp.Regexp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// match returns the route that serves req with its parameter values, or nil
+// when none does.
+func (t *routeTable) match(req *domain.RouteRequest) (*domain.RouteEntry, map[string]string) {
+	root := t.trees[req.Method]
+	if root == nil {
+		return nil, nil
+	}
+	segments := domain.SplitPath(req.Path)
+	leaf, values := root.match(req, segments, 0, make([]string, 0, len(segments)))
+	if leaf == nil {
+		return nil, nil
+	}
+	if len(leaf.params) == 0 {
+		return leaf.entry, nil
+	}
+	params := make(map[string]string, len(leaf.params))
+	for i, name := range leaf.params {
+		params[name] = values[i]
+	}
+	return leaf.entry, params
+}
+
+// bestLeaf returns the most specific of leaves whose conditions req
+// satisfies. Leaves are in key order, which breaks ties.
+func bestLeaf(leaves []*routeLeaf, req *domain.RouteRequest) *routeLeaf {
+	var (
+		best      *routeLeaf
+		bestScore domain.RouteScore
+	)
+	for _, leaf := range leaves {
+		score, ok := leaf.entry.Route.MatchRequest(req)
+		if ok && (best == nil || score.Compare(bestScore) > 0) {
+			best, bestScore = leaf, score
+		}
+	}
+	return best
+}
+
+func (n *routeNode) match(req *domain.RouteRequest, segments []string, i int, values []string) (*routeLeaf, []string) {
+	if i == len(segments) {
+		if leaf := bestLeaf(n.leaves, req); leaf != nil {
+			return leaf, values
+		}
+		if n.catchAll != nil {
+			if leaf := bestLeaf(n.catchAll.leaves, req); leaf != nil {
+				return leaf, append(values, "")
+			}
+		}
+		return nil, nil
+	}
+
+	if child := n.static[segments[i]]; child != nil {
+		if leaf, matched := child.match(req, segments, i+1, values); leaf != nil {
+			return leaf, matched
+		}
+	}
+	for _, child := range n.params {
+		if child.constraint != nil && !child.constraint.MatchString(segments[i]) {
+			continue
+		}
+		if leaf, matched := child.node.match(req, segments, i+1, append(values, segments[i])); leaf != nil {
+			return leaf, matched
+		}
+	}
+	if n.catchAll != nil {
+		// Longest first, so a catch-all followed by more segments takes
+		// as much of the path as it can.
+		for end := len(segments); end > i; end-- {
+			rest := strings.Join(segments[i:end], "/")
+			if leaf, matched := n.catchAll.match(req, segments, end, append(values, rest)); leaf != nil {
+				return leaf, matched
+			}
+		}
+	}
+	return nil, nil
+}
+
+// MatchRoute returns the route that serves req, with the values of its path
+// parameters, or nil when no route does. Lookups read the compiled route
+// table without locking; it is rebuilt when the repository reports that the
+// routes changed.
+func (r *Registry) MatchRoute(req *domain.RouteRequest) (*domain.RouteEntry, map[string]string) {
+	table := r.routeTable()
+	if table == nil {
+		return nil, nil
+	}
+	return table.match(req)
+}
+
+func (r *Registry) routeTable() *routeTable {
+	versioner, versioned := r.repo.(routeVersioner)
+	if versioned {
+		if table := r.routes.Load(); table != nil && table.versioned && table.version == versioner.RoutesVersion() {
+			return table
+		}
+	}
+
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+
+	// The version is read before the routes so a change made in between
+	// triggers another rebuild instead of being missed.
+	var version uint64
+	if versioned {
+		version = versioner.RoutesVersion()
+		if table := r.routes.Load(); table != nil && table.versioned && table.version == version {
+			return table
+		}
+	}
+
+	entries, err := r.repo.GetAllRoutes(context.Background())
+	if err != nil {
+		slog.Error("failed to load routes", "error", err)
+		return r.routes.Load()
+	}
+
+	table := newRouteTable(entries)
+	table.version = version
+	table.versioned = versioned
+	r.routes.Store(table)
+	return table
+}
+
+// RouteTableNode is one segment of the compiled route table, with the routes
+// ending there and the segments that may follow it in the order they are
+// tried.
+type RouteTableNode struct {
+	Segment  string            `json:"segment"`
+	Routes   []RouteTableRoute `json:"routes,omitempty"`
+	Children []*RouteTableNode `json:"children,omitempty"`
+}
+
+// RouteTableRoute is a route as the table stores it: with its key and the
+// names given to the parameters captured on the way, in order.
+type RouteTableRoute struct {
+	Service string   `json:"service"`
+	Key     string   `json:"key"`
+	Params  []string `json:"params,omitempty"`
+}
+
+// RouteTableDump is the route table requests are matched against, one tree
+// per method.
+type RouteTableDump struct {
+	Version uint64                     `json:"version"`
+	Methods map[string]*RouteTableNode `json:"methods"`
+}
+
+// DumpRouteTable describes the compiled route table, rebuilding it first if
+// the routes changed.
+func (r *Registry) DumpRouteTable() RouteTableDump {
+	dump := RouteTableDump{Methods: make(map[string]*RouteTableNode)}
+	table := r.routeTable()
+	if table == nil {
+		return dump
+	}
+	dump.Version = table.version
+	for method, root := range table.trees {
+		dump.Methods[method] = root.dump("/")
+	}
+	return dump
+}
+
+func (n *routeNode) dump(segment string) *RouteTableNode {
+	node := &RouteTableNode{Segment: segment}
+	for _, leaf := range n.leaves {
+		node.Routes = append(node.Routes, RouteTableRoute{
+			Service: leaf.entry.ServiceName,
+			Key:     leaf.entry.Route.Key(leaf.entry.BasePath),
+			Params:  leaf.params,
+		})
+	}
+
+	statics := make([]string, 0, len(n.static))
+	for value := range n.static {
+		statics = append(statics, value)
+	}
+	sort.Strings(statics)
+	for _, value := range statics {
+		node.Children = append(node.Children, n.static[value].dump(value))
+	}
+	for _, child := range n.params {
+		label := "{param}"
+		if child.constraint != nil {
+			label = "{param:" + child.constraint.String() + "}"
+		}
+		node.Children = append(node.Children, child.node.dump(label))
+	}
+	if n.catchAll != nil {
+		node.Children = append(node.Children, n.catchAll.dump("*"))
+	}
+	return node
+}
@@ -0,0 +1,216 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func routeRequest(method, path string) *domain.RouteRequest {
+	return &domain.RouteRequest{Method: method, Path: path}
+}
+
+func TestRouteTable_Precedence(t *testing.T) {
+	table := newRouteTable([]domain.RouteEntry{
+		{ServiceName: "wildcard", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/*"}},
+		{ServiceName: "param", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id"}},
+		{ServiceName: "static", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/me"}},
+		{ServiceName: "posts", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id/posts"}},
+		{ServiceName: "settings", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/me/settings"}},
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		wantService string
+		wantParams  map[string]string
+	}{
+		{"static over param", "/users/me", "static", nil},
+		{"param over wildcard", "/users/42", "param", map[string]string{"id": "42"}},
+		{"wildcard for deeper paths", "/users/42/comments", "wildcard", map[string]string{"*": "42/comments"}},
+		{"backtracks from static to param", "/users/me/posts", "posts", map[string]string{"id": "me"}},
+		{"static subtree", "/users/me/settings", "settings", nil},
+		{"trailing slash", "/users/me/", "static", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				entry, params := table.match(routeRequest("GET", tt.path))
+				if entry == nil {
+					t.Fatalf("expected a match for %s", tt.path)
+				}
+				if entry.ServiceName != tt.wantService {
+					t.Fatalf("expected %s, got %s", tt.wantService, entry.ServiceName)
+				}
+				if len(params) != len(tt.wantParams) {
+					t.Fatalf("expected params %v, got %v", tt.wantParams, params)
+				}
+				for key, want := range tt.wantParams {
+					if params[key] != want {
+						t.Errorf("param %s: expected %s, got %s", key, want, params[key])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRouteTable_NoMatch(t *testing.T) {
+	table := newRouteTable([]domain.RouteEntry{
+		{ServiceName: "svc", BasePath: "/api", Route: domain.Route{Method: "GET", Path: "/users/:id"}},
+	})
+
+	if entry, _ := table.match(routeRequest("POST", "/api/users/1")); entry != nil {
+		t.Error("a route should only match its own method")
+	}
+	if entry, _ := table.match(routeRequest("GET", "/api/users/1/posts")); entry != nil {
+		t.Error("a parameter should match a single segment")
+	}
+	if entry, _ := table.match(routeRequest("GET", "/api/users")); entry != nil {
+		t.Error("a parameter should not match a missing segment")
+	}
+}
+
+func TestRouteTable_PatternGrammar(t *testing.T) {
+	table := newRouteTable([]domain.RouteEntry{
+		{ServiceName: "orders", BasePath: "/orders", Route: domain.Route{Method: "GET", Path: "/{id}"}},
+		{ServiceName: "numeric", BasePath: "/items", Route: domain.Route{Method: "GET", Path: "/{id:[0-9]+}"}},
+		{ServiceName: "slug", BasePath: "/items", Route: domain.Route{Method: "GET", Path: "/{slug}"}},
+		{ServiceName: "files", BasePath: "/files", Route: domain.Route{Method: "GET", Path: "/*rest"}},
+		{ServiceName: "raw", BasePath: "/blobs", Route: domain.Route{Method: "GET", Path: "/*path/raw/"}},
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		wantService string
+		wantParams  map[string]string
+	}{
+		{"brace parameter", "/orders/7", "orders", map[string]string{"id": "7"}},
+		{"constrained parameter first", "/items/42", "numeric", map[string]string{"id": "42"}},
+		{"falls back to unconstrained parameter", "/items/blue-shirt", "slug", map[string]string{"slug": "blue-shirt"}},
+		{"named catch-all", "/files/a/b.txt", "files", map[string]string{"rest": "a/b.txt"}},
+		{"empty catch-all", "/files", "files", map[string]string{"rest": ""}},
+		{"mid-path catch-all", "/blobs/a/b/raw", "raw", map[string]string{"path": "a/b"}},
+		{"trailing slash on request", "/orders/7/", "orders", map[string]string{"id": "7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, params := table.match(routeRequest("GET", tt.path))
+			if entry == nil {
+				t.Fatalf("expected a match for %s", tt.path)
+			}
+			if entry.ServiceName != tt.wantService {
+				t.Fatalf("expected %s, got %s", tt.wantService, entry.ServiceName)
+			}
+			for key, want := range tt.wantParams {
+				if got, ok := params[key]; !ok || got != want {
+					t.Errorf("param %s: expected %q, got %q", key, want, got)
+				}
+			}
+		})
+	}
+
+	if entry, _ := table.match(routeRequest("GET", "/blobs/raw")); entry != nil {
+		t.Error("a mid-path catch-all should take at least one segment")
+	}
+}
+
+func TestRouteTable_MostSpecificConditionsWin(t *testing.T) {
+	table := newRouteTable([]domain.RouteEntry{
+		{ServiceName: "any", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id"}},
+		{ServiceName: "wildcard", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id", Hosts: []string{"*.example.com"}}},
+		{ServiceName: "admin", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id", Hosts: []string{"admin.example.com"}}},
+		{ServiceName: "v2", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/:id", Hosts: []string{"*.example.com"},
+			Headers: []domain.MatchPredicate{{Name: "X-Api-Version", Value: "2"}}}},
+		{ServiceName: "me", BasePath: "/users", Route: domain.Route{Method: "GET", Path: "/me", Hosts: []string{"api.example.com"}}},
+	})
+
+	tests := []struct {
+		name        string
+		host        string
+		header      http.Header
+		path        string
+		wantService string
+	}{
+		{"no host condition", "other.org", nil, "/users/1", "any"},
+		{"wildcard host", "api.example.com", nil, "/users/1", "wildcard"},
+		{"exact host over wildcard", "admin.example.com", http.Header{"X-Api-Version": {"2"}}, "/users/1", "admin"},
+		{"predicates break host ties", "api.example.com", http.Header{"X-Api-Version": {"2"}}, "/users/1", "v2"},
+		{"static path with its host", "api.example.com", nil, "/users/me", "me"},
+		{"backtracks when static conditions fail", "admin.example.com", nil, "/users/me", "admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, _ := table.match(&domain.RouteRequest{Method: "GET", Host: tt.host, Header: tt.header, Path: tt.path})
+			if entry == nil {
+				t.Fatalf("expected a match for %s%s", tt.host, tt.path)
+			}
+			if entry.ServiceName != tt.wantService {
+				t.Errorf("expected %s, got %s", tt.wantService, entry.ServiceName)
+			}
+		})
+	}
+}
+
+func TestRegistry_MatchRouteFollowsRegistrations(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/api/v1/users/1")); entry != nil {
+		t.Fatal("expected no match on an empty registry")
+	}
+
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "user-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/users/:id"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	entry, params := registry.MatchRoute(routeRequest("GET", "/api/v1/users/1"))
+	if entry == nil || entry.ServiceName != "user-service" || params["id"] != "1" {
+		t.Fatalf("expected user-service with id=1, got %v %v", entry, params)
+	}
+
+	if err := registry.Deregister(resp.InstanceID); err != nil {
+		t.Fatalf("deregister failed: %v", err)
+	}
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/api/v1/users/1")); entry != nil {
+		t.Error("routes of a deregistered service should no longer match")
+	}
+}
+
+func TestRegistry_MatchRouteFollowsCacheRefresh(t *testing.T) {
+	shared := NewMemoryRepository()
+	cache := NewCachedRepository(shared, time.Minute)
+	registry := NewRegistryWithRepository(RegistryConfig{HeartbeatTTL: 30 * time.Second}, cache)
+	ctx := context.Background()
+
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/api/v1/users")); entry != nil {
+		t.Fatal("expected no match before any registration")
+	}
+
+	// Written by another replica straight to the shared store.
+	_ = shared.SaveInstance(ctx, &domain.ServiceInstance{ID: "instance-1", ServiceName: "svc", Status: domain.StatusHealthy})
+	_ = shared.SaveRoutes(ctx, "svc", "/api/v1", []domain.Route{{Method: "GET", Path: "/users"}})
+
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/api/v1/users")); entry != nil {
+		t.Fatal("route from another replica should only match after a refresh")
+	}
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if entry, _ := registry.MatchRoute(routeRequest("GET", "/api/v1/users")); entry == nil {
+		t.Error("expected the route to match after a refresh")
+	}
+}
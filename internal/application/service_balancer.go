@@ -0,0 +1,201 @@
+package application
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+type ServiceBalancerConfig struct {
+	DefaultStrategy   BalancerStrategy
+	ServiceStrategies map[string]BalancerStrategy
+	DefaultHashKey    HashKey
+	ServiceHashKeys   map[string]HashKey
+}
+
+type balancerKey struct {
+	strategy BalancerStrategy
+	hashKey  HashKey
+}
+
+type serviceBalancerEntry struct {
+	requested balancerKey
+	balancer  LoadBalancer
+}
+
+// ServiceBalancer keeps one load balancer per service. The strategy comes from
+// ServiceStrategies, then from the domain.MetadataLoadBalancer registration
+// metadata, and finally from DefaultStrategy; the ring_hash key is resolved the
+// same way from ServiceHashKeys, domain.MetadataHashKey and DefaultHashKey.
+type ServiceBalancer struct {
+	config    ServiceBalancerConfig
+	tracker   *InflightTracker
+	mu        sync.Mutex
+	balancers map[string]*serviceBalancerEntry
+}
+
+func NewServiceBalancer(cfg ServiceBalancerConfig) (*ServiceBalancer, error) {
+	if cfg.DefaultStrategy == "" {
+		cfg.DefaultStrategy = StrategyRoundRobin
+	}
+	if cfg.DefaultHashKey.Source == "" {
+		cfg.DefaultHashKey = HashKey{Source: HashKeyClientIP}
+	}
+
+	opts := BalancerOptions{Tracker: NewInflightTracker(), HashKey: cfg.DefaultHashKey}
+	if _, err := NewBalancer(cfg.DefaultStrategy, opts); err != nil {
+		return nil, err
+	}
+	for _, strategy := range cfg.ServiceStrategies {
+		if _, err := NewBalancer(strategy, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ServiceBalancer{
+		config:    cfg,
+		tracker:   opts.Tracker,
+		balancers: make(map[string]*serviceBalancerEntry),
+	}, nil
+}
+
+func (s *ServiceBalancer) Select(instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+	return s.balancerFor(instances).Select(instances)
+}
+
+func (s *ServiceBalancer) SelectForRequest(info *RequestInfo, instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	balancer := s.balancerFor(instances)
+	if aware, ok := balancer.(RequestAwareBalancer); ok {
+		return aware.SelectForRequest(info, instances)
+	}
+	return balancer.Select(instances)
+}
+
+func (s *ServiceBalancer) Begin(instance *domain.ServiceInstance) {
+	s.tracker.Begin(instance)
+}
+
+func (s *ServiceBalancer) End(instance *domain.ServiceInstance) {
+	s.tracker.End(instance)
+}
+
+func (s *ServiceBalancer) Connect(instance *domain.ServiceInstance) {
+	s.tracker.Connect(instance)
+}
+
+func (s *ServiceBalancer) Disconnect(instance *domain.ServiceInstance) {
+	s.tracker.Disconnect(instance)
+}
+
+func (s *ServiceBalancer) balancerFor(instances []*domain.ServiceInstance) LoadBalancer {
+	serviceName := instances[0].ServiceName
+	requested := s.resolve(serviceName, instances)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.balancers[serviceName]; ok && entry.requested == requested {
+		return entry.balancer
+	}
+
+	balancer, err := NewBalancer(requested.strategy, BalancerOptions{
+		Tracker: s.tracker,
+		HashKey: requested.hashKey,
+	})
+	if err != nil {
+		slog.Warn("unknown load balancing strategy, using default",
+			"service", serviceName,
+			"strategy", requested.strategy,
+			"default", s.config.DefaultStrategy,
+		)
+		balancer, _ = NewBalancer(s.config.DefaultStrategy, BalancerOptions{
+			Tracker: s.tracker,
+			HashKey: s.config.DefaultHashKey,
+		})
+	}
+
+	s.balancers[serviceName] = &serviceBalancerEntry{
+		requested: requested,
+		balancer:  balancer,
+	}
+	return balancer
+}
+
+func (s *ServiceBalancer) resolve(serviceName string, instances []*domain.ServiceInstance) balancerKey {
+	key := balancerKey{
+		strategy: s.config.DefaultStrategy,
+		hashKey:  s.config.DefaultHashKey,
+	}
+
+	if strategy := metadataValue(instances, domain.MetadataLoadBalancer); strategy != "" {
+		key.strategy = BalancerStrategy(strategy)
+	}
+	if strategy, ok := s.config.ServiceStrategies[serviceName]; ok {
+		key.strategy = strategy
+	}
+
+	if raw := metadataValue(instances, domain.MetadataHashKey); raw != "" {
+		if hashKey, err := ParseHashKey(raw); err == nil {
+			key.hashKey = hashKey
+		}
+	}
+	if hashKey, ok := s.config.ServiceHashKeys[serviceName]; ok {
+		key.hashKey = hashKey
+	}
+
+	return key
+}
+
+// balancerMetadata lists the registration metadata keys that select how a
+// service is balanced. Every instance of a service must agree on them.
+var balancerMetadata = []string{domain.MetadataLoadBalancer, domain.MetadataHashKey}
+
+// validateBalancerMetadata rejects an unknown strategy or an unparsable hash
+// key at registration instead of silently falling back at request time.
+func validateBalancerMetadata(metadata map[string]string) error {
+	if strategy := metadata[domain.MetadataLoadBalancer]; strategy != "" {
+		if _, err := NewBalancer(BalancerStrategy(strategy), BalancerOptions{}); err != nil {
+			return fmt.Errorf("invalid metadata %s: %w", domain.MetadataLoadBalancer, err)
+		}
+	}
+	if raw := metadata[domain.MetadataHashKey]; raw != "" {
+		if _, err := ParseHashKey(raw); err != nil {
+			return fmt.Errorf("invalid metadata %s: %w", domain.MetadataHashKey, err)
+		}
+	}
+	return nil
+}
+
+// checkBalancerMetadataConsistency rejects an instance whose balancing
+// metadata differs from the instances already registered for the service, so
+// the resolved strategy does not depend on which instances are healthy.
+func checkBalancerMetadataConsistency(metadata map[string]string, existing []*domain.ServiceInstance) error {
+	if len(existing) == 0 {
+		return nil
+	}
+	for _, name := range balancerMetadata {
+		if want, got := existing[0].Metadata[name], metadata[name]; want != got {
+			return fmt.Errorf("metadata %s %q conflicts with %q used by the other instances of %s",
+				name, got, want, existing[0].ServiceName)
+		}
+	}
+	return nil
+}
+
+func metadataValue(instances []*domain.ServiceInstance, name string) string {
+	for _, instance := range instances {
+		if value := instance.Metadata[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func TestServiceBalancer_DefaultStrategy(t *testing.T) {
+	sb, err := NewServiceBalancer(ServiceBalancerConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	instances := []*domain.ServiceInstance{
+		{ID: "instance-1", ServiceName: "svc"},
+		{ID: "instance-2", ServiceName: "svc"},
+	}
+	sb.Select(instances)
+
+	if _, ok := sb.balancers["svc"].balancer.(*RoundRobinBalancer); !ok {
+		t.Errorf("expected round robin by default, got %T", sb.balancers["svc"].balancer)
+	}
+}
+
+func TestServiceBalancer_StrategyFromMetadata(t *testing.T) {
+	sb, _ := NewServiceBalancer(ServiceBalancerConfig{})
+
+	instances := []*domain.ServiceInstance{
+		{ID: "instance-1", ServiceName: "svc", Metadata: map[string]string{domain.MetadataLoadBalancer: "least_requests"}},
+	}
+	sb.Select(instances)
+
+	if _, ok := sb.balancers["svc"].balancer.(*LeastRequestsBalancer); !ok {
+		t.Errorf("expected least requests from metadata, got %T", sb.balancers["svc"].balancer)
+	}
+}
+
+func TestServiceBalancer_ConfigOverridesMetadata(t *testing.T) {
+	sb, _ := NewServiceBalancer(ServiceBalancerConfig{
+		ServiceStrategies: map[string]BalancerStrategy{"svc": StrategyRandom},
+	})
+
+	instances := []*domain.ServiceInstance{
+		{ID: "instance-1", ServiceName: "svc", Metadata: map[string]string{domain.MetadataLoadBalancer: "least_requests"}},
+	}
+	sb.Select(instances)
+
+	if _, ok := sb.balancers["svc"].balancer.(*RandomBalancer); !ok {
+		t.Errorf("expected random from config, got %T", sb.balancers["svc"].balancer)
+	}
+}
+
+func TestServiceBalancer_UnknownMetadataFallsBack(t *testing.T) {
+	sb, _ := NewServiceBalancer(ServiceBalancerConfig{DefaultStrategy: StrategyWeightedRoundRobin})
+
+	instances := []*domain.ServiceInstance{
+		{ID: "instance-1", ServiceName: "svc", Metadata: map[string]string{domain.MetadataLoadBalancer: "fastest"}},
+	}
+	if selected := sb.Select(instances); selected == nil {
+		t.Fatal("expected an instance")
+	}
+
+	if _, ok := sb.balancers["svc"].balancer.(*WeightedRoundRobinBalancer); !ok {
+		t.Errorf("expected fallback to default, got %T", sb.balancers["svc"].balancer)
+	}
+}
+
+func TestServiceBalancer_IndependentPerService(t *testing.T) {
+	sb, _ := NewServiceBalancer(ServiceBalancerConfig{})
+
+	svcA := []*domain.ServiceInstance{{ID: "a1", ServiceName: "a"}, {ID: "a2", ServiceName: "a"}}
+	svcB := []*domain.ServiceInstance{{ID: "b1", ServiceName: "b"}, {ID: "b2", ServiceName: "b"}}
+
+	if selected := sb.Select(svcA); selected.ID != "a1" {
+		t.Errorf("expected a1, got %s", selected.ID)
+	}
+	if selected := sb.Select(svcB); selected.ID != "b1" {
+		t.Errorf("expected b1 (independent counter), got %s", selected.ID)
+	}
+}
+
+func TestNewServiceBalancer_InvalidConfig(t *testing.T) {
+	if _, err := NewServiceBalancer(ServiceBalancerConfig{DefaultStrategy: "fastest"}); err == nil {
+		t.Error("expected error for unknown default strategy")
+	}
+	if _, err := NewServiceBalancer(ServiceBalancerConfig{
+		ServiceStrategies: map[string]BalancerStrategy{"svc": "fastest"},
+	}); err == nil {
+		t.Error("expected error for unknown service strategy")
+	}
+}
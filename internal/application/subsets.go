@@ -0,0 +1,47 @@
+package application
+
+import "github.com/apascualco/gotway/internal/domain"
+
+// FilterBySelector returns the instances whose metadata matches selector, all
+// of them when selector is empty.
+func FilterBySelector(instances []*domain.ServiceInstance, selector map[string]string) []*domain.ServiceInstance {
+	if len(selector) == 0 {
+		return instances
+	}
+	var subset []*domain.ServiceInstance
+	for _, instance := range instances {
+		if instance.MatchesSelector(selector) {
+			subset = append(subset, instance)
+		}
+	}
+	return subset
+}
+
+// ZoneAffinity keeps requests in the gateway's zone while it has enough
+// instances to serve them.
+type ZoneAffinity struct {
+	// Zone is the zone the gateway runs in.
+	Zone string
+	// MinInstances is the number of available instances the zone needs to
+	// keep the traffic; with fewer, requests spill over to every zone. It
+	// defaults to 1.
+	MinInstances int
+}
+
+// Prefer returns the instances in the zone, or all of them when the zone
+// has fewer than MinInstances available.
+func (z ZoneAffinity) Prefer(instances []*domain.ServiceInstance) []*domain.ServiceInstance {
+	if z.Zone == "" {
+		return instances
+	}
+	var local []*domain.ServiceInstance
+	for _, instance := range instances {
+		if instance.Zone() == z.Zone {
+			local = append(local, instance)
+		}
+	}
+	if len(local) == 0 || len(local) < max(z.MinInstances, 1) {
+		return instances
+	}
+	return local
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func zonedInstances(zones ...string) []*domain.ServiceInstance {
+	instances := make([]*domain.ServiceInstance, len(zones))
+	for i, zone := range zones {
+		instances[i] = &domain.ServiceInstance{
+			ID:       zone + "-" + string(rune('a'+i)),
+			Metadata: map[string]string{domain.MetadataZone: zone},
+		}
+	}
+	return instances
+}
+
+func TestZoneAffinity_Prefer(t *testing.T) {
+	tests := []struct {
+		name     string
+		affinity ZoneAffinity
+		zones    []string
+		want     int
+	}{
+		{"prefers the local zone", ZoneAffinity{Zone: "a"}, []string{"a", "a", "b", "b"}, 2},
+		{"spills over below the minimum", ZoneAffinity{Zone: "a", MinInstances: 2}, []string{"a", "b", "b"}, 3},
+		{"keeps the zone at the minimum", ZoneAffinity{Zone: "a", MinInstances: 2}, []string{"a", "a", "b"}, 2},
+		{"spills over without local instances", ZoneAffinity{Zone: "c"}, []string{"a", "b"}, 2},
+		{"disabled without a zone", ZoneAffinity{}, []string{"a", "b"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.affinity.Prefer(zonedInstances(tt.zones...))
+			if len(got) != tt.want {
+				t.Fatalf("expected %d instances, got %d", tt.want, len(got))
+			}
+			if tt.want < len(tt.zones) {
+				for _, instance := range got {
+					if instance.Zone() != tt.affinity.Zone {
+						t.Errorf("expected only zone %s, got %s", tt.affinity.Zone, instance.Zone())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFilterBySelector(t *testing.T) {
+	instances := []*domain.ServiceInstance{
+		{ID: "web-1", Metadata: map[string]string{"pool": "web"}},
+		{ID: "batch-1", Metadata: map[string]string{"pool": "batch"}},
+		{ID: "bare"},
+	}
+
+	if got := FilterBySelector(instances, nil); len(got) != 3 {
+		t.Errorf("expected every instance without a selector, got %d", len(got))
+	}
+	got := FilterBySelector(instances, map[string]string{"pool": "batch"})
+	if len(got) != 1 || got[0].ID != "batch-1" {
+		t.Errorf("expected only batch-1, got %v", got)
+	}
+	if got := FilterBySelector(instances, map[string]string{"pool": "gpu"}); len(got) != 0 {
+		t.Errorf("expected no instance for an unmatched selector, got %d", len(got))
+	}
+}
@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// TrafficPolicy returns the policy splitting the traffic of serviceName
+// between its versions, or nil when it has none.
+func (r *Registry) TrafficPolicy(serviceName string) *domain.TrafficPolicy {
+	policy, err := r.repo.GetTrafficPolicy(context.Background(), serviceName)
+	if err != nil {
+		if !errors.Is(err, domain.ErrTrafficPolicyNotFound) {
+			slog.Error("failed to get traffic policy", "service", serviceName, "error", err)
+		}
+		return nil
+	}
+	return policy
+}
+
+// SetTrafficPolicy replaces the traffic policy of a registered service.
+func (r *Registry) SetTrafficPolicy(serviceName string, policy *domain.TrafficPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrInvalidRequest, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx := context.Background()
+	instances, err := r.repo.GetInstancesByService(ctx, serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to load service instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return domain.ErrServiceNotFound
+	}
+	return r.repo.SaveTrafficPolicy(ctx, serviceName, policy)
+}
+
+// ShiftTraffic sets the weight of version in the traffic policy of
+// serviceName, rebalancing the other versions, and returns the new policy.
+func (r *Registry) ShiftTraffic(serviceName, version string, weight int) (*domain.TrafficPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx := context.Background()
+	current, err := r.repo.GetTrafficPolicy(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &domain.TrafficPolicy{
+		Rules:   current.Rules,
+		Weights: slices.Clone(current.Weights),
+	}
+	if err := policy.Shift(version, weight); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidRequest, err)
+	}
+	if err := r.repo.SaveTrafficPolicy(ctx, serviceName, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteTrafficPolicy stops splitting the traffic of serviceName by version.
+func (r *Registry) DeleteTrafficPolicy(serviceName string) error {
+	return r.repo.DeleteTrafficPolicy(context.Background(), serviceName)
+}
+
+// SplitTraffic narrows instances to those of the versions policy sends req
+// to. roll, in [0, 1), picks the version of requests split by weight. When
+// no instance of the picked versions is available all instances are
+// returned, so a policy never turns a request away.
+func SplitTraffic(policy *domain.TrafficPolicy, req *http.Request, instances []*domain.ServiceInstance, roll float64) []*domain.ServiceInstance {
+	if policy == nil || len(instances) == 0 {
+		return instances
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if !rule.Matches(req) {
+			continue
+		}
+		if subset := filterVersions(instances, rule.Versions); len(subset) > 0 {
+			return subset
+		}
+		break
+	}
+
+	// Versions without an available instance give their share to the
+	// others.
+	var (
+		candidates []domain.VersionWeight
+		total      int
+	)
+	for _, weight := range policy.Weights {
+		if weight.Weight > 0 && slices.ContainsFunc(instances, func(instance *domain.ServiceInstance) bool {
+			return instance.Version == weight.Version
+		}) {
+			candidates = append(candidates, weight)
+			total += weight.Weight
+		}
+	}
+	if total == 0 {
+		return instances
+	}
+
+	target := int(roll * float64(total))
+	for _, candidate := range candidates {
+		if target < candidate.Weight {
+			return filterVersions(instances, []string{candidate.Version})
+		}
+		target -= candidate.Weight
+	}
+	return filterVersions(instances, []string{candidates[len(candidates)-1].Version})
+}
+
+func filterVersions(instances []*domain.ServiceInstance, versions []string) []*domain.ServiceInstance {
+	var subset []*domain.ServiceInstance
+	for _, instance := range instances {
+		if slices.Contains(versions, instance.Version) {
+			subset = append(subset, instance)
+		}
+	}
+	return subset
+}
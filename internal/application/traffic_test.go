@@ -0,0 +1,189 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func versionedInstances(versions ...string) []*domain.ServiceInstance {
+	instances := make([]*domain.ServiceInstance, len(versions))
+	for i, version := range versions {
+		instances[i] = &domain.ServiceInstance{ID: version + "-" + string(rune('a'+i)), Version: version}
+	}
+	return instances
+}
+
+func onlyVersion(t *testing.T, instances []*domain.ServiceInstance) string {
+	t.Helper()
+	if len(instances) == 0 {
+		t.Fatal("expected at least one instance")
+	}
+	for _, instance := range instances[1:] {
+		if instance.Version != instances[0].Version {
+			t.Fatalf("expected a single version, got %s and %s", instances[0].Version, instance.Version)
+		}
+	}
+	return instances[0].Version
+}
+
+func TestSplitTraffic_Weights(t *testing.T) {
+	instances := versionedInstances("v1", "v1", "v2")
+	policy := &domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 90}, {Version: "v2", Weight: 10}}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tests := []struct {
+		roll float64
+		want string
+	}{
+		{0, "v1"},
+		{0.89, "v1"},
+		{0.9, "v2"},
+		{0.999, "v2"},
+	}
+	for _, tt := range tests {
+		subset := SplitTraffic(policy, req, instances, tt.roll)
+		if got := onlyVersion(t, subset); got != tt.want {
+			t.Errorf("roll %v: expected %s, got %s", tt.roll, tt.want, got)
+		}
+	}
+	if subset := SplitTraffic(policy, req, instances, 0); len(subset) != 2 {
+		t.Errorf("expected both v1 instances, got %d", len(subset))
+	}
+}
+
+func TestSplitTraffic_Rules(t *testing.T) {
+	instances := versionedInstances("v1", "v2", "v3")
+	policy := &domain.TrafficPolicy{
+		Rules: []domain.VersionRule{
+			{Header: &domain.MatchPredicate{Name: "X-Canary", Value: "true"}, Versions: []string{"v2"}},
+			{Cookie: &domain.MatchPredicate{Name: "beta"}, Versions: []string{"v2", "v3"}},
+		},
+		Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}},
+	}
+
+	canary := httptest.NewRequest(http.MethodGet, "/", nil)
+	canary.Header.Set("X-Canary", "true")
+	if got := onlyVersion(t, SplitTraffic(policy, canary, instances, 0.5)); got != "v2" {
+		t.Errorf("expected the canary header to select v2, got %s", got)
+	}
+
+	beta := httptest.NewRequest(http.MethodGet, "/", nil)
+	beta.AddCookie(&http.Cookie{Name: "beta", Value: "yes"})
+	if subset := SplitTraffic(policy, beta, instances, 0.5); len(subset) != 2 {
+		t.Errorf("expected the beta cookie to select v2 and v3, got %d instances", len(subset))
+	}
+
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := onlyVersion(t, SplitTraffic(policy, plain, instances, 0.5)); got != "v1" {
+		t.Errorf("expected requests matching no rule to follow the weights, got %s", got)
+	}
+}
+
+func TestSplitTraffic_FallsBackWhenVersionsUnavailable(t *testing.T) {
+	policy := &domain.TrafficPolicy{
+		Rules:   []domain.VersionRule{{Header: &domain.MatchPredicate{Name: "X-Canary"}, Versions: []string{"v3"}}},
+		Weights: []domain.VersionWeight{{Version: "v1", Weight: 50}, {Version: "v2", Weight: 50}},
+	}
+
+	canary := httptest.NewRequest(http.MethodGet, "/", nil)
+	canary.Header.Set("X-Canary", "1")
+	if got := onlyVersion(t, SplitTraffic(policy, canary, versionedInstances("v1", "v2"), 0.99)); got != "v2" {
+		t.Errorf("expected a rule without instances to fall back to the weights, got %s", got)
+	}
+
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := onlyVersion(t, SplitTraffic(policy, plain, versionedInstances("v1", "v1"), 0.99)); got != "v1" {
+		t.Errorf("expected a weighted version without instances to give its share away, got %s", got)
+	}
+	if subset := SplitTraffic(policy, plain, versionedInstances("v3", "v4"), 0.5); len(subset) != 2 {
+		t.Errorf("expected every instance when no weighted version is available, got %d", len(subset))
+	}
+}
+
+func TestRegistry_TrafficPolicy(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	policy := &domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}}}
+
+	if err := registry.SetTrafficPolicy("orders", policy); !errors.Is(err, domain.ErrServiceNotFound) {
+		t.Fatalf("expected ErrServiceNotFound for an unknown service, got %v", err)
+	}
+
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "orders",
+		Host:        "localhost",
+		Port:        8080,
+		Version:     "v1",
+		BasePath:    "/orders",
+		Routes:      []domain.Route{{Method: "GET", Path: "/"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	invalid := &domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 50}}}
+	if err := registry.SetTrafficPolicy("orders", invalid); !errors.Is(err, domain.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for an invalid policy, got %v", err)
+	}
+	if err := registry.SetTrafficPolicy("orders", policy); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	shifted, err := registry.ShiftTraffic("orders", "v2", 20)
+	if err != nil {
+		t.Fatalf("shift failed: %v", err)
+	}
+	if len(shifted.Weights) != 2 || shifted.Weights[0].Weight != 80 || shifted.Weights[1].Weight != 20 {
+		t.Errorf("expected v1=80 v2=20, got %v", shifted.Weights)
+	}
+	if policy.Weights[0].Weight != 100 {
+		t.Error("shifting should not modify the stored policy in place")
+	}
+	if got := registry.TrafficPolicy("orders"); got == nil || len(got.Weights) != 2 {
+		t.Errorf("expected the shifted policy to be stored, got %v", got)
+	}
+
+	if err := registry.DeleteTrafficPolicy("orders"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if registry.TrafficPolicy("orders") != nil {
+		t.Error("expected no policy after delete")
+	}
+	if _, err := registry.ShiftTraffic("orders", "v2", 50); !errors.Is(err, domain.ErrTrafficPolicyNotFound) {
+		t.Errorf("expected ErrTrafficPolicyNotFound when shifting without a policy, got %v", err)
+	}
+}
+
+func TestCachedRepository_ServesTrafficPolicies(t *testing.T) {
+	shared := NewMemoryRepository()
+	cache := NewCachedRepository(shared, time.Minute)
+	ctx := context.Background()
+	policy := &domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}}}
+
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	// Written by another replica straight to the shared store.
+	_ = shared.SaveTrafficPolicy(ctx, "orders", policy)
+	if _, err := cache.GetTrafficPolicy(ctx, "orders"); !errors.Is(err, domain.ErrTrafficPolicyNotFound) {
+		t.Fatalf("expected the policy to be served only after a refresh, got %v", err)
+	}
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if got, err := cache.GetTrafficPolicy(ctx, "orders"); err != nil || got.Weights[0].Version != "v1" {
+		t.Fatalf("expected the policy after a refresh, got %v %v", got, err)
+	}
+
+	if err := cache.DeleteTrafficPolicy(ctx, "orders"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := cache.GetTrafficPolicy(ctx, "orders"); !errors.Is(err, domain.ErrTrafficPolicyNotFound) {
+		t.Errorf("expected a write through the cache to be visible at once, got %v", err)
+	}
+}
@@ -11,6 +11,9 @@ type CollisionType string
 const (
 	ExactCollision   CollisionType = "exact"
 	PatternCollision CollisionType = "pattern"
+	// ConditionCollision is a route on the same path as another whose host,
+	// header and query conditions can match the same requests equally well.
+	ConditionCollision CollisionType = "condition"
 )
 
 type RouteCollision struct {
@@ -39,4 +42,6 @@ var (
 	ErrInstanceNotFound = fmt.Errorf("instance not found")
 	ErrRouteNotFound    = fmt.Errorf("route not found")
 	ErrInvalidRequest   = fmt.Errorf("invalid request")
+
+	ErrTrafficPolicyNotFound = fmt.Errorf("traffic policy not found")
 )
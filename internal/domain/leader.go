@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LeaderLock lets a single gateway replica at a time run a task whose results
+// are shared by the whole fleet (output port).
+type LeaderLock interface {
+	// TryAcquire takes the lock, or renews it when this replica already holds
+	// it, for ttl. It reports whether this replica is the holder.
+	TryAcquire(ctx context.Context, ttl time.Duration) (bool, error)
+}
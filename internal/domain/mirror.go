@@ -0,0 +1,33 @@
+package domain
+
+import "errors"
+
+// MirrorPolicy copies a share of a route's requests to a shadow service or
+// version, whose responses are discarded, so a release can be tried on live
+// traffic before it serves any.
+type MirrorPolicy struct {
+	// Service receives the copies; empty mirrors to the route's own service.
+	Service string `json:"service,omitempty"`
+	// Version restricts the copies to the instances of that version.
+	Version string `json:"version,omitempty"`
+	// Percent is the share of requests copied, from 1 to 100.
+	Percent int `json:"percent"`
+}
+
+func (m *MirrorPolicy) Validate() error {
+	if m.Service == "" && m.Version == "" {
+		return errors.New("mirror requires a service or a version")
+	}
+	if m.Percent < 1 || m.Percent > 100 {
+		return errors.New("mirror percent must be between 1 and 100")
+	}
+	return nil
+}
+
+// Target returns the service the copies of requests to serviceName go to.
+func (m *MirrorPolicy) Target(serviceName string) string {
+	if m.Service != "" {
+		return m.Service
+	}
+	return serviceName
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "testing"
+
+func TestMirrorPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  MirrorPolicy
+		wantErr bool
+	}{
+		{"service", MirrorPolicy{Service: "orders-shadow", Percent: 10}, false},
+		{"version", MirrorPolicy{Version: "v2", Percent: 100}, false},
+		{"no target", MirrorPolicy{Percent: 10}, true},
+		{"zero percent", MirrorPolicy{Version: "v2"}, true},
+		{"over 100 percent", MirrorPolicy{Version: "v2", Percent: 101}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMirrorPolicy_Target(t *testing.T) {
+	if got := (&MirrorPolicy{Version: "v2"}).Target("orders"); got != "orders" {
+		t.Errorf("expected the route's own service, got %s", got)
+	}
+	if got := (&MirrorPolicy{Service: "orders-shadow"}).Target("orders"); got != "orders-shadow" {
+		t.Errorf("expected the shadow service, got %s", got)
+	}
+}
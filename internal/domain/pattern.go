@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SegmentKind tells how a segment of a route pattern matches request paths.
+type SegmentKind int
+
+const (
+	// StaticSegment matches its literal text.
+	StaticSegment SegmentKind = iota
+	// ParamSegment matches any single segment, or only those its constraint
+	// accepts, and captures it under its name.
+	ParamSegment
+	// CatchAllSegment matches the rest of the path when it ends the pattern,
+	// possibly nothing, and one or more segments anywhere else.
+	CatchAllSegment
+)
+
+// CatchAllName is the parameter an unnamed catch-all ("*") is captured under.
+const CatchAllName = "*"
+
+// PatternSegment is one "/"-separated segment of a parsed route pattern.
+type PatternSegment struct {
+	Kind SegmentKind
+	// Value is the literal text of a static segment and the parameter name
+	// of the others.
+	Value string
+	// Constraint, when set, restricts the segments a parameter matches.
+	Constraint *regexp.Regexp
+}
+
+// Matches reports whether s, a single path segment, can fill the segment.
+func (p PatternSegment) Matches(s string) bool {
+	switch p.Kind {
+	case StaticSegment:
+		return p.Value == s
+	case ParamSegment:
+		return p.Constraint == nil || p.Constraint.MatchString(s)
+	default:
+		return true
+	}
+}
+
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParsePathPattern parses a route pattern. Each segment is one of:
+//
+//	users          static text
+//	:id  {id}      a parameter matching any single segment
+//	{id:[0-9]+}    a parameter whose whole segment must match the expression,
+//	               which cannot contain "/"
+//	*  *rest       a catch-all, at most one per pattern
+//
+// Parameters span whole segments. A trailing slash is optional: patterns and
+// paths match with or without one.
+func ParsePathPattern(pattern string) ([]PatternSegment, error) {
+	var (
+		segments []PatternSegment
+		catchAll bool
+	)
+	names := make(map[string]bool)
+
+	for _, s := range SplitPath(pattern) {
+		segment, err := parseSegment(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment %q: %w", s, err)
+		}
+		if segment.Kind == CatchAllSegment {
+			if catchAll {
+				return nil, fmt.Errorf("pattern %q has more than one catch-all", pattern)
+			}
+			catchAll = true
+		}
+		if segment.Kind != StaticSegment {
+			if names[segment.Value] {
+				return nil, fmt.Errorf("pattern %q repeats parameter %q", pattern, segment.Value)
+			}
+			names[segment.Value] = true
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func parseSegment(s string) (PatternSegment, error) {
+	switch {
+	case strings.HasPrefix(s, ":"):
+		return paramSegment(s[1:], "")
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return PatternSegment{}, fmt.Errorf("unclosed {")
+		}
+		name, expr, _ := strings.Cut(s[1:len(s)-1], ":")
+		return paramSegment(name, expr)
+	case strings.HasPrefix(s, "*"):
+		name := s[1:]
+		if name == "" {
+			name = CatchAllName
+		} else if !paramName.MatchString(name) {
+			return PatternSegment{}, fmt.Errorf("invalid catch-all name %q", name)
+		}
+		return PatternSegment{Kind: CatchAllSegment, Value: name}, nil
+	case strings.ContainsAny(s, "{}"):
+		return PatternSegment{}, fmt.Errorf("parameters must span a whole segment")
+	default:
+		return PatternSegment{Kind: StaticSegment, Value: s}, nil
+	}
+}
+
+func paramSegment(name, expr string) (PatternSegment, error) {
+	if !paramName.MatchString(name) {
+		return PatternSegment{}, fmt.Errorf("invalid parameter name %q", name)
+	}
+	segment := PatternSegment{Kind: ParamSegment, Value: name}
+	if expr == "" {
+		return segment, nil
+	}
+	constraint, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return PatternSegment{}, fmt.Errorf("invalid constraint for %q: %w", name, err)
+	}
+	segment.Constraint = constraint
+	return segment, nil
+}
+
+// SplitPath splits a pattern or request path into its segments, ignoring
+// leading and trailing slashes.
+func SplitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
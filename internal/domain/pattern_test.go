@@ -0,0 +1,81 @@
+package domain
+
+import "testing"
+
+func TestParsePathPattern(t *testing.T) {
+	type segment struct {
+		kind       SegmentKind
+		value      string
+		constraint string
+	}
+	tests := []struct {
+		pattern string
+		want    []segment
+	}{
+		{"/users", []segment{{StaticSegment, "users", ""}}},
+		{"/users/:id", []segment{{StaticSegment, "users", ""}, {ParamSegment, "id", ""}}},
+		{"/users/{id}/", []segment{{StaticSegment, "users", ""}, {ParamSegment, "id", ""}}},
+		{"/users/{id:[0-9]{1,6}}", []segment{{StaticSegment, "users", ""}, {ParamSegment, "id", "^(?:[0-9]{1,6})$"}}},
+		{"/static/*", []segment{{StaticSegment, "static", ""}, {CatchAllSegment, CatchAllName, ""}}},
+		{"/files/*path/raw", []segment{{StaticSegment, "files", ""}, {CatchAllSegment, "path", ""}, {StaticSegment, "raw", ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			segments, err := ParsePathPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(segments) != len(tt.want) {
+				t.Fatalf("expected %d segments, got %d", len(tt.want), len(segments))
+			}
+			for i, want := range tt.want {
+				got := segments[i]
+				var constraint string
+				if got.Constraint != nil {
+					constraint = got.Constraint.String()
+				}
+				if got.Kind != want.kind || got.Value != want.value || constraint != want.constraint {
+					t.Errorf("segment %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePathPattern_Errors(t *testing.T) {
+	for _, pattern := range []string{
+		"/users/:",
+		"/users/{}",
+		"/users/{id",
+		"/users/{1d}",
+		"/users/{id:(}",
+		"/users/user-{id}",
+		"/users/:id/posts/{id}",
+		"/files/*a/*b",
+		"/files/*re-st",
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			if _, err := ParsePathPattern(pattern); err == nil {
+				t.Errorf("expected %q to be rejected", pattern)
+			}
+		})
+	}
+}
+
+func TestPatternSegment_Matches(t *testing.T) {
+	segments, err := ParsePathPattern("/users/{id:[0-9]+}/:name")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !segments[0].Matches("users") || segments[0].Matches("posts") {
+		t.Error("a static segment should match only its text")
+	}
+	if !segments[1].Matches("42") || segments[1].Matches("42a") {
+		t.Error("a constrained parameter should match whole segments its expression accepts")
+	}
+	if !segments[2].Matches("anything") {
+		t.Error("an unconstrained parameter should match any segment")
+	}
+}
@@ -0,0 +1,268 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// MatchPredicate requires a header or query parameter on the requests a
+// route serves.
+type MatchPredicate struct {
+	Name string `json:"name"`
+	// Value is the value required; empty only requires the header or
+	// parameter to be present.
+	Value string `json:"value,omitempty"`
+}
+
+// RouteRequest is the part of a request routes are matched against.
+type RouteRequest struct {
+	Method   string
+	Host     string
+	Path     string
+	Header   http.Header
+	RawQuery string
+
+	query url.Values
+}
+
+func NewRouteRequest(r *http.Request) *RouteRequest {
+	return &RouteRequest{
+		Method:   r.Method,
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		Header:   r.Header,
+		RawQuery: r.URL.RawQuery,
+	}
+}
+
+// Query parses the query string on first use, as most routes never look at
+// it.
+func (r *RouteRequest) Query() url.Values {
+	if r.query == nil {
+		r.query, _ = url.ParseQuery(r.RawQuery)
+	}
+	return r.query
+}
+
+// RouteScore ranks the routes that match a request on the same path: a route
+// naming the exact host beats one matching it with a wildcard, the longer
+// wildcard winning, which beats one for any host; then the route with more
+// header and query predicates wins.
+type RouteScore struct {
+	Host       int
+	Predicates int
+}
+
+const exactHostScore = 1 << 16
+
+func (s RouteScore) Compare(other RouteScore) int {
+	if s.Host != other.Host {
+		return s.Host - other.Host
+	}
+	return s.Predicates - other.Predicates
+}
+
+// HasConditions reports whether the route restricts the hosts, headers or
+// query parameters it serves.
+func (r *Route) HasConditions() bool {
+	return len(r.Hosts) > 0 || len(r.Headers) > 0 || len(r.Query) > 0
+}
+
+// MatchRequest reports whether req satisfies the route's host, header and
+// query conditions, and how specifically. The path is matched separately.
+func (r *Route) MatchRequest(req *RouteRequest) (RouteScore, bool) {
+	score := RouteScore{Predicates: len(r.Headers) + len(r.Query)}
+
+	if len(r.Hosts) > 0 {
+		host := normalizeHost(req.Host)
+		matched := false
+		for _, pattern := range r.Hosts {
+			if s, ok := matchHost(pattern, host); ok && (!matched || s > score.Host) {
+				score.Host = s
+				matched = true
+			}
+		}
+		if !matched {
+			return RouteScore{}, false
+		}
+	}
+
+	for _, predicate := range r.Headers {
+		values := req.Header.Values(predicate.Name)
+		if len(values) == 0 || (predicate.Value != "" && !slices.Contains(values, predicate.Value)) {
+			return RouteScore{}, false
+		}
+	}
+	if len(r.Query) > 0 {
+		query := req.Query()
+		for _, predicate := range r.Query {
+			values, present := query[predicate.Name]
+			if !present || (predicate.Value != "" && !slices.Contains(values, predicate.Value)) {
+				return RouteScore{}, false
+			}
+		}
+	}
+	return score, true
+}
+
+// matchHost matches a lower-case host without port against pattern, either a
+// host name or "*." followed by the domain whose subdomains it matches.
+func matchHost(pattern, host string) (int, bool) {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return len(suffix), true
+		}
+		return 0, false
+	}
+	if host == pattern {
+		return exactHostScore, true
+	}
+	return 0, false
+}
+
+func hostPatternsOverlap(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	suffixA, wildcardA := strings.CutPrefix(a, "*")
+	suffixB, wildcardB := strings.CutPrefix(b, "*")
+	switch {
+	case wildcardA && wildcardB:
+		return strings.HasSuffix(suffixA, suffixB) || strings.HasSuffix(suffixB, suffixA)
+	case wildcardA:
+		_, ok := matchHost(a, b)
+		return ok
+	case wildcardB:
+		_, ok := matchHost(b, a)
+		return ok
+	default:
+		return a == b
+	}
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// ConditionsCompatible reports whether some request can satisfy the
+// conditions of both routes.
+func (r *Route) ConditionsCompatible(other *Route) bool {
+	if len(r.Hosts) > 0 && len(other.Hosts) > 0 && !shareHost(r.Hosts, other.Hosts, hostPatternsOverlap) {
+		return false
+	}
+	return r.predicatesCompatible(other)
+}
+
+// ConditionsAmbiguous reports whether some request can satisfy the
+// conditions of both routes with the same score, leaving the gateway no way
+// to choose between them.
+func (r *Route) ConditionsAmbiguous(other *Route) bool {
+	if len(r.Headers)+len(r.Query) != len(other.Headers)+len(other.Query) {
+		return false
+	}
+	if (len(r.Hosts) > 0 || len(other.Hosts) > 0) && !shareHost(r.Hosts, other.Hosts, strings.EqualFold) {
+		return false
+	}
+	return r.predicatesCompatible(other)
+}
+
+func shareHost(a, b []string, same func(string, string) bool) bool {
+	return slices.ContainsFunc(a, func(host string) bool {
+		return slices.ContainsFunc(b, func(other string) bool { return same(host, other) })
+	})
+}
+
+// predicatesCompatible reports whether one request can satisfy the header
+// and query predicates of both routes, which it cannot when they require
+// different values of the same name.
+func (r *Route) predicatesCompatible(other *Route) bool {
+	return predicatesCompatible(r.Headers, other.Headers, textproto.CanonicalMIMEHeaderKey) &&
+		predicatesCompatible(r.Query, other.Query, queryName)
+}
+
+func predicatesCompatible(a, b []MatchPredicate, canonical func(string) string) bool {
+	for _, pa := range a {
+		for _, pb := range b {
+			if canonical(pa.Name) == canonical(pb.Name) && pa.Value != "" && pb.Value != "" && pa.Value != pb.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// queryName is the canonical form of a query parameter name: itself, as
+// query parameters are case-sensitive.
+func queryName(name string) string {
+	return name
+}
+
+// conditionsKey describes the route's conditions in a canonical form, empty
+// when it has none.
+func (r *Route) conditionsKey() string {
+	if !r.HasConditions() {
+		return ""
+	}
+
+	var parts []string
+	if len(r.Hosts) > 0 {
+		hosts := make([]string, len(r.Hosts))
+		for i, host := range r.Hosts {
+			hosts[i] = strings.ToLower(host)
+		}
+		slices.Sort(hosts)
+		parts = append(parts, "host="+strings.Join(hosts, ","))
+	}
+	parts = append(parts, predicateKeys("header:", r.Headers, textproto.CanonicalMIMEHeaderKey)...)
+	parts = append(parts, predicateKeys("query:", r.Query, queryName)...)
+	return strings.Join(parts, " ")
+}
+
+func predicateKeys(prefix string, predicates []MatchPredicate, canonical func(string) string) []string {
+	keys := make([]string, 0, len(predicates))
+	for _, predicate := range predicates {
+		key := prefix + canonical(predicate.Name)
+		if predicate.Value != "" {
+			key += "=" + predicate.Value
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (r *Route) validateConditions() error {
+	for _, host := range r.Hosts {
+		name := strings.TrimPrefix(host, "*.")
+		if name == "" || strings.ContainsAny(name, "*/:") {
+			return fmt.Errorf("invalid host pattern %q", host)
+		}
+	}
+	if err := validatePredicates("header", r.Headers, textproto.CanonicalMIMEHeaderKey); err != nil {
+		return err
+	}
+	return validatePredicates("query", r.Query, queryName)
+}
+
+func validatePredicates(kind string, predicates []MatchPredicate, canonical func(string) string) error {
+	seen := make(map[string]bool, len(predicates))
+	for _, predicate := range predicates {
+		if predicate.Name == "" {
+			return errors.New(kind + " predicate requires a name")
+		}
+		name := canonical(predicate.Name)
+		if seen[name] {
+			return fmt.Errorf("%s predicate %q is repeated", kind, predicate.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRoute_MatchRequest(t *testing.T) {
+	route := &Route{
+		Hosts:   []string{"api.example.com", "*.example.com"},
+		Headers: []MatchPredicate{{Name: "x-api-version", Value: "2"}},
+		Query:   []MatchPredicate{{Name: "beta"}},
+	}
+
+	tests := []struct {
+		name      string
+		host      string
+		header    http.Header
+		query     string
+		wantMatch bool
+		wantHost  int
+	}{
+		{"exact host", "api.example.com", http.Header{"X-Api-Version": {"2"}}, "beta=1", true, exactHostScore},
+		{"exact host with port and case", "API.example.com:8443", http.Header{"X-Api-Version": {"2"}}, "beta", true, exactHostScore},
+		{"wildcard host", "eu.admin.example.com", http.Header{"X-Api-Version": {"2"}}, "beta=", true, len(".example.com")},
+		{"apex is not a subdomain", "example.com", http.Header{"X-Api-Version": {"2"}}, "beta", false, 0},
+		{"other host", "api.example.org", http.Header{"X-Api-Version": {"2"}}, "beta", false, 0},
+		{"wrong header value", "api.example.com", http.Header{"X-Api-Version": {"1"}}, "beta", false, 0},
+		{"missing query parameter", "api.example.com", http.Header{"X-Api-Version": {"2"}}, "alpha=1", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := route.MatchRequest(&RouteRequest{Host: tt.host, Header: tt.header, RawQuery: tt.query})
+			if ok != tt.wantMatch {
+				t.Fatalf("expected match %v, got %v", tt.wantMatch, ok)
+			}
+			if ok && (score.Host != tt.wantHost || score.Predicates != 2) {
+				t.Errorf("expected score {%d 2}, got %+v", tt.wantHost, score)
+			}
+		})
+	}
+}
+
+func TestRoute_KeyIncludesConditions(t *testing.T) {
+	a := &Route{Method: "GET", Path: "/users", Hosts: []string{"B.example.com", "a.example.com"},
+		Headers: []MatchPredicate{{Name: "x-api-version", Value: "2"}}}
+	b := &Route{Method: "GET", Path: "/users", Hosts: []string{"a.example.com", "b.example.com"},
+		Headers: []MatchPredicate{{Name: "X-Api-Version", Value: "2"}}}
+
+	want := "GET:/api/users host=a.example.com,b.example.com header:X-Api-Version=2"
+	if got := a.Key("/api"); got != want {
+		t.Errorf("expected key %q, got %q", want, got)
+	}
+	if a.Key("/api") != b.Key("/api") {
+		t.Error("equivalent conditions should give the same key")
+	}
+	if got := (&Route{Method: "GET", Path: "/users"}).Key("/api"); got != "GET:/api/users" {
+		t.Errorf("routes without conditions should keep their key, got %q", got)
+	}
+}
+
+func TestRoute_ConditionsOverlap(t *testing.T) {
+	tests := []struct {
+		name           string
+		a, b           Route
+		wantCompatible bool
+		wantAmbiguous  bool
+	}{
+		{"no conditions", Route{}, Route{}, true, true},
+		{"different hosts", Route{Hosts: []string{"a.example.com"}}, Route{Hosts: []string{"b.example.com"}}, false, false},
+		{"shared host", Route{Hosts: []string{"a.example.com", "b.example.com"}}, Route{Hosts: []string{"B.example.com"}}, true, true},
+		{"wildcard and exact host", Route{Hosts: []string{"*.example.com"}}, Route{Hosts: []string{"a.example.com"}}, true, false},
+		{"host and any host", Route{Hosts: []string{"a.example.com"}}, Route{}, true, false},
+		{"different header values", Route{Headers: []MatchPredicate{{Name: "X-V", Value: "1"}}}, Route{Headers: []MatchPredicate{{Name: "x-v", Value: "2"}}}, false, false},
+		{"different headers", Route{Headers: []MatchPredicate{{Name: "X-A", Value: "1"}}}, Route{Headers: []MatchPredicate{{Name: "X-B", Value: "1"}}}, true, true},
+		{"more predicates", Route{Query: []MatchPredicate{{Name: "v"}, {Name: "w"}}}, Route{Query: []MatchPredicate{{Name: "v"}}}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.ConditionsCompatible(&tt.b); got != tt.wantCompatible {
+				t.Errorf("ConditionsCompatible() = %v, want %v", got, tt.wantCompatible)
+			}
+			if got := tt.a.ConditionsAmbiguous(&tt.b); got != tt.wantAmbiguous {
+				t.Errorf("ConditionsAmbiguous() = %v, want %v", got, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+func TestRegisterRequest_Validate_Conditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr bool
+	}{
+		{"hosts and predicates", Route{Hosts: []string{"api.example.com", "*.example.com"}, Headers: []MatchPredicate{{Name: "X-Api-Version", Value: "2"}}, Query: []MatchPredicate{{Name: "beta"}}}, false},
+		{"host with port", Route{Hosts: []string{"api.example.com:443"}}, true},
+		{"inner wildcard", Route{Hosts: []string{"api.*.com"}}, true},
+		{"bare wildcard", Route{Hosts: []string{"*."}}, true},
+		{"unnamed header", Route{Headers: []MatchPredicate{{Value: "2"}}}, true},
+		{"repeated header", Route{Headers: []MatchPredicate{{Name: "X-V"}, {Name: "x-v"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.route.Method = "GET"
+			tt.route.Path = "/users"
+			req := &RegisterRequest{
+				ServiceName: "test-service",
+				Host:        "localhost",
+				Port:        8080,
+				BasePath:    "/api/v1",
+				Routes:      []Route{tt.route},
+			}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,16 +1,30 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
+// MaxWeight bounds RegisterRequest.Weight so weighted balancers stay cheap.
+const MaxWeight = 1000
+
+// RegisterRequest describes an instance joining the gateway. Scheme is "http"
+// (the default) or "https"; TLSServerName overrides the name expected in the
+// instance's certificate, which otherwise is Host. Protocol is "http" (the
+// default) or "grpc", whose routes are POST /<package>.<Service>/<Method>.
 type RegisterRequest struct {
-	ServiceName string            `json:"service_name" binding:"required"`
-	Host        string            `json:"host" binding:"required"`
-	Port        int               `json:"port" binding:"required"`
-	HealthURL   string            `json:"health_url"`
-	Version     string            `json:"version"`
-	BasePath    string            `json:"base_path" binding:"required"`
-	Routes      []Route           `json:"routes" binding:"required"`
-	Metadata    map[string]string `json:"metadata"`
+	ServiceName   string            `json:"service_name" binding:"required"`
+	Host          string            `json:"host" binding:"required"`
+	Port          int               `json:"port" binding:"required"`
+	Scheme        string            `json:"scheme"`
+	TLSServerName string            `json:"tls_server_name"`
+	Protocol      string            `json:"protocol"`
+	HealthURL     string            `json:"health_url"`
+	Version       string            `json:"version"`
+	Weight        int               `json:"weight"`
+	BasePath      string            `json:"base_path" binding:"required"`
+	Routes        []Route           `json:"routes" binding:"required"`
+	Metadata      map[string]string `json:"metadata"`
 }
 
 func (r *RegisterRequest) Validate() error {
@@ -29,6 +43,81 @@ func (r *RegisterRequest) Validate() error {
 	if len(r.Routes) == 0 {
 		return errors.New("at least one route is required")
 	}
+	for _, route := range r.Routes {
+		pattern, err := ParsePathPattern(route.FullPath(r.BasePath))
+		if err != nil {
+			return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+		if route.Rewrite != nil {
+			if err := route.Rewrite.Validate(pattern); err != nil {
+				return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+			}
+		}
+		if err := route.validateConditions(); err != nil {
+			return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+		if _, empty := route.InstanceSelector[""]; empty {
+			return fmt.Errorf("route %s %s: instance_selector labels require a name", route.Method, route.Path)
+		}
+		if route.Mirror != nil {
+			if err := route.Mirror.Validate(); err != nil {
+				return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+			}
+		}
+		if route.TimeoutMs < 0 {
+			return fmt.Errorf("route %s %s: timeout_ms must not be negative", route.Method, route.Path)
+		}
+		if route.Retry == nil {
+			continue
+		}
+		if err := route.Retry.Validate(); err != nil {
+			return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	switch r.Scheme {
+	case "", SchemeHTTP:
+		if r.TLSServerName != "" {
+			return errors.New("tls_server_name requires scheme https")
+		}
+	case SchemeHTTPS:
+	default:
+		return fmt.Errorf("scheme must be %s or %s", SchemeHTTP, SchemeHTTPS)
+	}
+	switch r.Protocol {
+	case "", ProtocolHTTP:
+	case ProtocolGRPC:
+		if r.Metadata[MetadataUpstreamProtocol] == UpstreamProtocolHTTP1 {
+			return fmt.Errorf("protocol %s requires HTTP/2, not metadata %s %q", ProtocolGRPC, MetadataUpstreamProtocol, UpstreamProtocolHTTP1)
+		}
+		for _, route := range r.Routes {
+			if route.Method != "POST" {
+				return fmt.Errorf("route %s %s: gRPC routes must use POST", route.Method, route.Path)
+			}
+		}
+	default:
+		return fmt.Errorf("protocol must be %s or %s", ProtocolHTTP, ProtocolGRPC)
+	}
+	switch protocol := r.Metadata[MetadataUpstreamProtocol]; protocol {
+	case "", UpstreamProtocolHTTP1:
+	case UpstreamProtocolH2C:
+		if r.Scheme == SchemeHTTPS {
+			return fmt.Errorf("metadata %s %q requires scheme %s", MetadataUpstreamProtocol, protocol, SchemeHTTP)
+		}
+	default:
+		return fmt.Errorf("invalid metadata %s %q", MetadataUpstreamProtocol, protocol)
+	}
+	if r.Weight < 0 || r.Weight > MaxWeight {
+		return fmt.Errorf("weight must be between 0 and %d", MaxWeight)
+	}
+	if r.Weight == 0 {
+		r.Weight = 1
+	}
+	if r.Scheme == "" {
+		r.Scheme = SchemeHTTP
+	}
+	if r.Protocol == "" {
+		r.Protocol = ProtocolHTTP
+	}
 	if r.HealthURL == "" {
 		r.HealthURL = "/health"
 	}
@@ -53,3 +142,7 @@ type HeartbeatResponse struct {
 type DeregisterRequest struct {
 	InstanceID string `json:"instance_id" binding:"required"`
 }
+
+type DrainRequest struct {
+	InstanceID string `json:"instance_id" binding:"required"`
+}
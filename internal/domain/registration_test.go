@@ -118,3 +118,214 @@ func TestRegisterRequest_Validate_PreservesHealthURL(t *testing.T) {
 		t.Errorf("Validate() should preserve custom HealthURL, got %q", req.HealthURL)
 	}
 }
+
+func TestRegisterRequest_Validate_Weight(t *testing.T) {
+	req := &RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []Route{{Method: "GET", Path: "/users"}},
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate() returned error for valid request: %v", err)
+	}
+	if req.Weight != 1 {
+		t.Errorf("Validate() should default weight to 1, got %d", req.Weight)
+	}
+
+	req.Weight = -1
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() should return error for negative weight")
+	}
+}
+
+func TestRegisterRequest_Validate_WeightUpperBound(t *testing.T) {
+	req := &RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []Route{{Method: "GET", Path: "/users"}},
+		Weight:      MaxWeight,
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate() returned error for weight %d: %v", MaxWeight, err)
+	}
+
+	req.Weight = MaxWeight + 1
+	if err := req.Validate(); err == nil {
+		t.Errorf("Validate() should return error for weight above %d", MaxWeight)
+	}
+}
+
+func TestRegisterRequest_Validate_InvalidRetryPolicy(t *testing.T) {
+	req := &RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes: []Route{
+			{Method: "GET", Path: "/users", Retry: &RetryPolicy{MaxAttempts: 3}},
+			{Method: "GET", Path: "/orders", Retry: &RetryPolicy{MaxAttempts: 10}},
+		},
+	}
+
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() should reject a retry policy with too many attempts")
+	}
+}
+
+func TestRegisterRequest_Validate_NegativeRouteTimeout(t *testing.T) {
+	req := &RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []Route{{Method: "GET", Path: "/users", TimeoutMs: -1}},
+	}
+
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() should reject a negative route timeout")
+	}
+}
+
+func TestRegisterRequest_Validate_InstanceSelector(t *testing.T) {
+	req := &RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8080,
+		BasePath:    "/api/v1",
+		Routes:      []Route{{Method: "GET", Path: "/reports", InstanceSelector: map[string]string{"pool": "batch"}}},
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	req.Routes[0].InstanceSelector = map[string]string{"": "batch"}
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() should reject a selector label without a name")
+	}
+}
+
+func TestRegisterRequest_Validate_UpstreamProtocol(t *testing.T) {
+	for protocol, wantErr := range map[string]bool{"": false, "http1": false, "h2c": false, "spdy": true} {
+		req := &RegisterRequest{
+			ServiceName: "test-service",
+			Host:        "localhost",
+			Port:        8080,
+			BasePath:    "/api/v1",
+			Routes:      []Route{{Method: "GET", Path: "/users"}},
+			Metadata:    map[string]string{MetadataUpstreamProtocol: protocol},
+		}
+		if err := req.Validate(); (err != nil) != wantErr {
+			t.Errorf("protocol %q: Validate() error = %v, wantErr %v", protocol, err, wantErr)
+		}
+	}
+}
+
+func TestRegisterRequest_Validate_Scheme(t *testing.T) {
+	tests := []struct {
+		name          string
+		scheme        string
+		tlsServerName string
+		protocol      string
+		wantErr       bool
+	}{
+		{name: "default", scheme: ""},
+		{name: "http", scheme: "http"},
+		{name: "https", scheme: "https", tlsServerName: "orders.internal"},
+		{name: "unknown scheme", scheme: "ftp", wantErr: true},
+		{name: "server name without tls", scheme: "http", tlsServerName: "orders.internal", wantErr: true},
+		{name: "h2c over tls", scheme: "https", protocol: UpstreamProtocolH2C, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &RegisterRequest{
+				ServiceName:   "test-service",
+				Host:          "localhost",
+				Port:          8080,
+				Scheme:        tt.scheme,
+				TLSServerName: tt.tlsServerName,
+				BasePath:      "/api/v1",
+				Routes:        []Route{{Method: "GET", Path: "/users"}},
+				Metadata:      map[string]string{MetadataUpstreamProtocol: tt.protocol},
+			}
+			err := req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.scheme == "" && req.Scheme != SchemeHTTP {
+				t.Errorf("expected scheme to default to http, got %q", req.Scheme)
+			}
+		})
+	}
+}
+
+func TestRegisterRequest_Validate_Protocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		method   string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{name: "default", protocol: "", method: "GET"},
+		{name: "grpc", protocol: ProtocolGRPC, method: "POST"},
+		{name: "grpc over h2c", protocol: ProtocolGRPC, method: "POST", metadata: map[string]string{MetadataUpstreamProtocol: UpstreamProtocolH2C}},
+		{name: "grpc over http1", protocol: ProtocolGRPC, method: "POST", metadata: map[string]string{MetadataUpstreamProtocol: UpstreamProtocolHTTP1}, wantErr: true},
+		{name: "grpc with GET route", protocol: ProtocolGRPC, method: "GET", wantErr: true},
+		{name: "unknown", protocol: "soap", method: "POST", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &RegisterRequest{
+				ServiceName: "test-service",
+				Host:        "localhost",
+				Port:        8080,
+				Protocol:    tt.protocol,
+				BasePath:    "/orders.v1.Orders",
+				Routes:      []Route{{Method: tt.method, Path: "/GetOrder"}},
+				Metadata:    tt.metadata,
+			}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterRequest_Validate_RoutePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "colon parameter", path: "/orders/:id"},
+		{name: "brace parameter", path: "/orders/{id}"},
+		{name: "constrained parameter", path: "/orders/{id:[0-9]+}"},
+		{name: "named catch-all", path: "/files/*rest"},
+		{name: "unclosed brace", path: "/orders/{id", wantErr: true},
+		{name: "invalid constraint", path: "/orders/{id:[0-9}", wantErr: true},
+		{name: "partial segment", path: "/orders/v{version}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &RegisterRequest{
+				ServiceName: "test-service",
+				Host:        "localhost",
+				Port:        8080,
+				BasePath:    "/api/v1",
+				Routes:      []Route{{Method: "GET", Path: tt.path}},
+			}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,24 +2,49 @@ package domain
 
 import "context"
 
-// Repository defines the interface for service registry persistence (output port)
+// Repository defines the interface for service registry persistence (output port).
+// Lookups of unknown instances return ErrInstanceNotFound, lookups of unknown
+// routes return ErrRouteNotFound and lookups of unknown traffic policies
+// return ErrTrafficPolicyNotFound.
 type Repository interface {
 	// Instance operations
 	SaveInstance(ctx context.Context, instance *ServiceInstance) error
 	GetInstance(ctx context.Context, instanceID string) (*ServiceInstance, error)
 	GetInstancesByService(ctx context.Context, serviceName string) ([]*ServiceInstance, error)
+	// DeleteInstance removes the instance and, in the same atomic step, the
+	// routes of its service when it was the service's last instance.
 	DeleteInstance(ctx context.Context, instanceID string) error
 	UpdateInstanceStatus(ctx context.Context, instanceID string, status ServiceStatus) error
+	// UpdateInstanceStatusIf sets the status to to only while it is still
+	// from, and reports whether it did. Automatic checks use it so they never
+	// undo a status an operator or the service set after they looked.
+	UpdateInstanceStatusIf(ctx context.Context, instanceID string, from, to ServiceStatus) (bool, error)
+	// UpdateInstanceWeight changes only the weight, leaving a status or
+	// heartbeat written meanwhile by another replica untouched.
+	UpdateInstanceWeight(ctx context.Context, instanceID string, weight int) error
 	UpdateHeartbeat(ctx context.Context, instanceID string) error
 
 	// Route operations
+	// SaveRoutes stores the routes for serviceName. If another service already
+	// owns any of the route keys nothing is saved and a *CollisionError is
+	// returned, so concurrent registrations cannot take over each other's routes.
 	SaveRoutes(ctx context.Context, serviceName string, basePath string, routes []Route) error
 	GetRoute(ctx context.Context, method, path string) (*RouteEntry, error)
 	GetAllRoutes(ctx context.Context) ([]RouteEntry, error)
 	DeleteRoutesByService(ctx context.Context, serviceName string) error
+	// CheckRouteCollisions returns an exact collision for every route already
+	// registered under the same method and full path, regardless of owner.
 	CheckRouteCollisions(ctx context.Context, basePath string, routes []Route) ([]RouteCollision, error)
 
 	// Service operations
 	GetAllServices(ctx context.Context) ([]string, error)
 	GetHealthyInstances(ctx context.Context, serviceName string) ([]*ServiceInstance, error)
+
+	// Traffic policy operations
+	// Policies outlive the instances of their service, so a service keeps its
+	// policy across a restart of all its instances.
+	SaveTrafficPolicy(ctx context.Context, serviceName string, policy *TrafficPolicy) error
+	GetTrafficPolicy(ctx context.Context, serviceName string) (*TrafficPolicy, error)
+	GetAllTrafficPolicies(ctx context.Context) (map[string]*TrafficPolicy, error)
+	DeleteTrafficPolicy(ctx context.Context, serviceName string) error
 }
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// MaxRetryAttempts bounds RetryPolicy.MaxAttempts so a single client request
+// cannot fan out into unbounded upstream load.
+const MaxRetryAttempts = 5
+
+// Retry conditions a RetryPolicy may list in RetryOn, besides individual
+// status codes such as "429".
+const (
+	RetryOnConnectFailure = "connect-failure"
+	RetryOnTimeout        = "timeout"
+	RetryOnGatewayError   = "gateway-error"
+	RetryOnServerError    = "5xx"
+)
+
+// RetryPolicy tells the gateway to retry a failed upstream request on another
+// instance of the service.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, so 3 allows two retries.
+	MaxAttempts int `json:"max_attempts"`
+	// PerTryTimeoutMs bounds how long each attempt may wait for the response
+	// headers; zero means no per-attempt limit.
+	PerTryTimeoutMs int `json:"per_try_timeout_ms,omitempty"`
+	// RetryOn lists the conditions that trigger a retry. It defaults to
+	// connect-failure and gateway-error.
+	RetryOn []string `json:"retry_on,omitempty"`
+	// BackoffBaseMs and BackoffMaxMs shape the jittered exponential delay
+	// between attempts.
+	BackoffBaseMs int `json:"backoff_base_ms,omitempty"`
+	BackoffMaxMs  int `json:"backoff_max_ms,omitempty"`
+	// RetryNonIdempotent allows retrying methods such as POST, which may then
+	// reach the service more than once.
+	RetryNonIdempotent bool `json:"retry_non_idempotent,omitempty"`
+}
+
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("retry max_attempts must be between 1 and %d", MaxRetryAttempts)
+	}
+	if p.PerTryTimeoutMs < 0 || p.BackoffBaseMs < 0 || p.BackoffMaxMs < 0 {
+		return fmt.Errorf("retry timeouts and backoff must not be negative")
+	}
+	for _, condition := range p.RetryOn {
+		switch condition {
+		case RetryOnConnectFailure, RetryOnTimeout, RetryOnGatewayError, RetryOnServerError:
+		default:
+			code, err := strconv.Atoi(condition)
+			if err != nil || code < 100 || code > 599 {
+				return fmt.Errorf("unknown retry_on condition %q", condition)
+			}
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether requests with method may be retried.
+func (p *RetryPolicy) AppliesTo(method string) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	if p.RetryNonIdempotent {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetriesOn reports whether condition, one of the RetryOn* constants, is
+// retried by the policy.
+func (p *RetryPolicy) RetriesOn(condition string) bool {
+	for _, candidate := range p.conditions() {
+		if candidate == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesStatus reports whether an upstream response with status is retried.
+func (p *RetryPolicy) RetriesStatus(status int) bool {
+	for _, condition := range p.conditions() {
+		switch condition {
+		case RetryOnServerError:
+			if status >= 500 {
+				return true
+			}
+		case RetryOnGatewayError:
+			if status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout {
+				return true
+			}
+		default:
+			if condition == strconv.Itoa(status) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) conditions() []string {
+	if len(p.RetryOn) == 0 {
+		return []string{RetryOnConnectFailure, RetryOnGatewayError}
+	}
+	return p.RetryOn
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "testing"
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		wantErr bool
+	}{
+		{"defaults", RetryPolicy{MaxAttempts: 3}, false},
+		{"named conditions and codes", RetryPolicy{MaxAttempts: 2, RetryOn: []string{"5xx", "timeout", "429"}}, false},
+		{"zero attempts", RetryPolicy{MaxAttempts: 0}, true},
+		{"too many attempts", RetryPolicy{MaxAttempts: MaxRetryAttempts + 1}, true},
+		{"negative timeout", RetryPolicy{MaxAttempts: 2, PerTryTimeoutMs: -1}, true},
+		{"unknown condition", RetryPolicy{MaxAttempts: 2, RetryOn: []string{"sometimes"}}, true},
+		{"invalid code", RetryPolicy{MaxAttempts: 2, RetryOn: []string{"700"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_AppliesTo(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+	for _, method := range []string{"GET", "HEAD", "PUT", "DELETE"} {
+		if !policy.AppliesTo(method) {
+			t.Errorf("%s should be retried", method)
+		}
+	}
+	for _, method := range []string{"POST", "PATCH"} {
+		if policy.AppliesTo(method) {
+			t.Errorf("%s should not be retried by default", method)
+		}
+	}
+
+	policy.RetryNonIdempotent = true
+	if !policy.AppliesTo("POST") {
+		t.Error("POST should be retried when RetryNonIdempotent is set")
+	}
+
+	if (&RetryPolicy{MaxAttempts: 1}).AppliesTo("GET") {
+		t.Error("a single attempt leaves nothing to retry")
+	}
+}
+
+func TestRetryPolicy_RetriesStatus(t *testing.T) {
+	defaults := &RetryPolicy{MaxAttempts: 2}
+	if !defaults.RetriesStatus(503) || defaults.RetriesStatus(500) || defaults.RetriesStatus(429) {
+		t.Error("default policy should retry gateway errors only")
+	}
+	if !defaults.RetriesOn(RetryOnConnectFailure) || defaults.RetriesOn(RetryOnTimeout) {
+		t.Error("default policy should retry connect failures only")
+	}
+
+	custom := &RetryPolicy{MaxAttempts: 2, RetryOn: []string{"5xx", "429"}}
+	if !custom.RetriesStatus(500) || !custom.RetriesStatus(429) || custom.RetriesStatus(404) {
+		t.Error("custom policy should retry 5xx and 429")
+	}
+}
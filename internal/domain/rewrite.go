@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// templatePlaceholder matches the {name} placeholders of RewriteRule.Template.
+var templatePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// RewriteRule changes the path a route forwards to its service, so a service
+// can be mounted under any external prefix. Either Template, or any of
+// StripBasePath and Regex, builds the new path, applied in that order; Prefix
+// is then prepended to it. The query string is forwarded untouched.
+type RewriteRule struct {
+	// StripBasePath removes the segments of the service's base path.
+	StripBasePath bool `json:"strip_base_path,omitempty"`
+	// Regex and Replacement rewrite the path as regexp.ReplaceAllString does,
+	// so Replacement may refer to groups as $1 or ${name}.
+	Regex       string `json:"regex,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+	// Template replaces the whole path, filling {name} with the path
+	// parameter of that name, e.g. "/v2/orders/{id}".
+	Template string `json:"template,omitempty"`
+	// Prefix is prepended to the path, e.g. "/internal".
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Validate checks the rule against the parameters the route pattern
+// captures.
+func (r *RewriteRule) Validate(pattern []PatternSegment) error {
+	if r.Template != "" && (r.StripBasePath || r.Regex != "") {
+		return errors.New("rewrite template cannot be combined with strip_base_path or regex")
+	}
+	if r.Replacement != "" && r.Regex == "" {
+		return errors.New("rewrite replacement requires a regex")
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("invalid rewrite regex: %w", err)
+		}
+	}
+
+	captured := make(map[string]bool)
+	for _, segment := range pattern {
+		if segment.Kind != StaticSegment {
+			captured[segment.Value] = true
+		}
+	}
+	for _, placeholder := range templatePlaceholder.FindAllStringSubmatch(r.Template, -1) {
+		if !captured[placeholder[1]] {
+			return fmt.Errorf("rewrite template refers to unknown parameter %q", placeholder[1])
+		}
+	}
+	return nil
+}
+
+// ExpandTemplate fills the placeholders of the rule's Template with params.
+func (r *RewriteRule) ExpandTemplate(params map[string]string) string {
+	return templatePlaceholder.ReplaceAllStringFunc(r.Template, func(placeholder string) string {
+		return params[placeholder[1:len(placeholder)-1]]
+	})
+}
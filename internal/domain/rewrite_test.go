@@ -0,0 +1,40 @@
+package domain
+
+import "testing"
+
+func TestRewriteRule_Validate(t *testing.T) {
+	pattern, err := ParsePathPattern("/orders/{id}/files/*rest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		rule    RewriteRule
+		wantErr bool
+	}{
+		{"strip and prefix", RewriteRule{StripBasePath: true, Prefix: "/internal"}, false},
+		{"regex", RewriteRule{Regex: `^/orders/(\d+)`, Replacement: "/o/$1"}, false},
+		{"template", RewriteRule{Template: "/v2/{id}/{rest}", Prefix: "/internal"}, false},
+		{"unknown parameter", RewriteRule{Template: "/v2/{name}"}, true},
+		{"template with strip", RewriteRule{Template: "/v2/{id}", StripBasePath: true}, true},
+		{"invalid regex", RewriteRule{Regex: "("}, true},
+		{"replacement without regex", RewriteRule{Replacement: "/x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(pattern); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRewriteRule_ExpandTemplate(t *testing.T) {
+	rule := &RewriteRule{Template: "/v2/{tenant}/orders/{id}"}
+	got := rule.ExpandTemplate(map[string]string{"tenant": "acme", "id": "42"})
+	if got != "/v2/acme/orders/42" {
+		t.Errorf("expected /v2/acme/orders/42, got %s", got)
+	}
+}
@@ -11,6 +11,32 @@ type Route struct {
 	Public    bool     `json:"public"`
 	RateLimit int      `json:"rate_limit"`
 	Scopes    []string `json:"scopes"`
+	// TimeoutMs bounds the whole upstream exchange, retries included; zero
+	// uses the gateway default.
+	TimeoutMs int          `json:"timeout_ms,omitempty"`
+	Retry     *RetryPolicy `json:"retry,omitempty"`
+	// Streaming lets requests asking for a protocol upgrade, such as
+	// WebSocket handshakes, switch protocols and be tunnelled to the instance.
+	Streaming bool `json:"streaming,omitempty"`
+	// Rewrite changes the path forwarded to the service; nil forwards the
+	// request path as is.
+	Rewrite *RewriteRule `json:"rewrite,omitempty"`
+	// ForwardParams sends each path parameter the route captures to the
+	// service as an X-Path-Param-<name> header.
+	ForwardParams bool `json:"forward_params,omitempty"`
+	// Hosts restricts the route to requests for these hosts, where
+	// "*.example.com" matches every subdomain of example.com. Headers and
+	// Query restrict it to requests carrying the given values. When several
+	// routes match a path, the most specific one serves the request.
+	Hosts   []string         `json:"hosts,omitempty"`
+	Headers []MatchPredicate `json:"headers,omitempty"`
+	Query   []MatchPredicate `json:"query,omitempty"`
+	// InstanceSelector restricts the instances serving the route to those
+	// whose metadata has all of these labels, e.g. {"pool": "batch"}.
+	InstanceSelector map[string]string `json:"instance_selector,omitempty"`
+	// Mirror copies some of the route's requests to a shadow service or
+	// version; nil mirrors none.
+	Mirror *MirrorPolicy `json:"mirror,omitempty"`
 }
 
 func (r *Route) FullPath(basePath string) string {
@@ -23,8 +49,14 @@ func (r *Route) FullPath(basePath string) string {
 	return fmt.Sprintf("%s%s", basePath, r.Path)
 }
 
+// Key identifies the route among those registered. Routes on the same path
+// with different host, header or query conditions have different keys.
 func (r *Route) Key(basePath string) string {
-	return fmt.Sprintf("%s:%s", r.Method, r.FullPath(basePath))
+	key := fmt.Sprintf("%s:%s", r.Method, r.FullPath(basePath))
+	if conditions := r.conditionsKey(); conditions != "" {
+		key += " " + conditions
+	}
+	return key
 }
 
 type RouteEntry struct {
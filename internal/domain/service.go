@@ -11,6 +11,47 @@ const (
 	StatusHealthy   ServiceStatus = "healthy"
 	StatusUnhealthy ServiceStatus = "unhealthy"
 	StatusUnknown   ServiceStatus = "unknown"
+	// StatusDraining and StatusDisabled take an instance out of rotation on
+	// purpose: it keeps its routes and heartbeat but receives no new requests,
+	// and health checks leave its status alone. Draining instances are about
+	// to leave; disabled ones stay until put back in rotation.
+	StatusDraining ServiceStatus = "draining"
+	StatusDisabled ServiceStatus = "disabled"
+)
+
+// MetadataLoadBalancer is the registration metadata key a service uses to pick
+// its load balancing strategy.
+const MetadataLoadBalancer = "lb_strategy"
+
+// MetadataHashKey is the registration metadata key a service uses to choose the
+// request attribute the ring_hash strategy keys on, e.g. "header=X-User-ID".
+const MetadataHashKey = "lb_hash_key"
+
+// MetadataUpstreamProtocol is the registration metadata key an instance uses to
+// choose how the gateway talks to it: "http1" (the default, negotiating
+// HTTP/2 only over TLS) or "h2c" for HTTP/2 over cleartext.
+const MetadataUpstreamProtocol = "upstream_protocol"
+
+// MetadataZone is the registration metadata key an instance uses to tell the
+// zone it runs in, so gateways in the same zone can prefer it.
+const MetadataZone = "zone"
+
+const (
+	UpstreamProtocolHTTP1 = "http1"
+	UpstreamProtocolH2C   = "h2c"
+)
+
+// Protocols a service may speak. gRPC services are always reached over
+// HTTP/2: h2c for scheme http and negotiated h2 for https.
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// Schemes an instance may be reached on.
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
 )
 
 type ServiceInstance struct {
@@ -18,6 +59,9 @@ type ServiceInstance struct {
 	ServiceName   string            `json:"service_name"`
 	Host          string            `json:"host"`
 	Port          int               `json:"port"`
+	Scheme        string            `json:"scheme,omitempty"`
+	TLSServerName string            `json:"tls_server_name,omitempty"`
+	Protocol      string            `json:"protocol,omitempty"`
 	HealthURL     string            `json:"health_url"`
 	Version       string            `json:"version"`
 	Status        ServiceStatus     `json:"status"`
@@ -31,6 +75,50 @@ func (i *ServiceInstance) Address() string {
 	return fmt.Sprintf("%s:%d", i.Host, i.Port)
 }
 
+// URLScheme returns the scheme the gateway uses to reach the instance.
+// Instances registered before schemes existed are reached over HTTP.
+func (i *ServiceInstance) URLScheme() string {
+	if i.Scheme == "" {
+		return SchemeHTTP
+	}
+	return i.Scheme
+}
+
+// UpstreamProtocol returns how the gateway talks to the instance: its
+// upstream_protocol metadata when set, h2c for cleartext gRPC and http1
+// otherwise.
+func (i *ServiceInstance) UpstreamProtocol() string {
+	if protocol := i.Metadata[MetadataUpstreamProtocol]; protocol != "" {
+		return protocol
+	}
+	if i.Protocol == ProtocolGRPC && i.URLScheme() == SchemeHTTP {
+		return UpstreamProtocolH2C
+	}
+	return UpstreamProtocolHTTP1
+}
+
+// Zone returns the zone the instance registered in, empty when unknown.
+func (i *ServiceInstance) Zone() string {
+	return i.Metadata[MetadataZone]
+}
+
+// MatchesSelector reports whether the instance's metadata has every label of
+// selector with the same value.
+func (i *ServiceInstance) MatchesSelector(selector map[string]string) bool {
+	for key, value := range selector {
+		if got, ok := i.Metadata[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
 func (i *ServiceInstance) IsHealthy() bool {
 	return i.Status == StatusHealthy
 }
+
+// OutOfRotation reports whether the instance was taken out of rotation on
+// purpose rather than by failing health checks.
+func (i *ServiceInstance) OutOfRotation() bool {
+	return i.Status == StatusDraining || i.Status == StatusDisabled
+}
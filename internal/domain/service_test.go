@@ -36,6 +36,8 @@ func TestServiceInstance_IsHealthy(t *testing.T) {
 		{"healthy", StatusHealthy, true},
 		{"unhealthy", StatusUnhealthy, false},
 		{"unknown", StatusUnknown, false},
+		{"draining", StatusDraining, false},
+		{"disabled", StatusDisabled, false},
 	}
 
 	for _, tt := range tests {
@@ -47,3 +49,46 @@ func TestServiceInstance_IsHealthy(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceInstance_OutOfRotation(t *testing.T) {
+	tests := []struct {
+		status   ServiceStatus
+		expected bool
+	}{
+		{StatusHealthy, false},
+		{StatusUnhealthy, false},
+		{StatusDraining, true},
+		{StatusDisabled, true},
+	}
+
+	for _, tt := range tests {
+		instance := &ServiceInstance{Status: tt.status}
+		if got := instance.OutOfRotation(); got != tt.expected {
+			t.Errorf("OutOfRotation() for %s = %v, want %v", tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestServiceInstance_MatchesSelector(t *testing.T) {
+	instance := &ServiceInstance{Metadata: map[string]string{"pool": "batch", MetadataZone: "eu-west-1a"}}
+
+	tests := []struct {
+		name     string
+		selector map[string]string
+		expected bool
+	}{
+		{"empty selector", nil, true},
+		{"matching label", map[string]string{"pool": "batch"}, true},
+		{"all labels", map[string]string{"pool": "batch", MetadataZone: "eu-west-1a"}, true},
+		{"different value", map[string]string{"pool": "web"}, false},
+		{"missing label", map[string]string{"gpu": "true"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instance.MatchesSelector(tt.selector); got != tt.expected {
+				t.Errorf("MatchesSelector() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
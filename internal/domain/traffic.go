@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// TrafficPolicy splits the traffic of a service between the versions its
+// instances registered with. Requests matching a rule go to the versions the
+// first such rule names; the others are spread over the versions in Weights.
+// A policy never leaves a request without an instance: when the versions it
+// picks have none available, the request falls back to the weights and then
+// to every instance of the service.
+type TrafficPolicy struct {
+	Rules []VersionRule `json:"rules,omitempty"`
+	// Weights, when set, add up to 100. Versions they leave out or give no
+	// weight receive only the requests sent to them by a rule.
+	Weights []VersionWeight `json:"weights,omitempty"`
+}
+
+// VersionRule sends the requests carrying a header or a cookie to a subset of
+// the versions, e.g. those with X-Canary: true to the canary.
+type VersionRule struct {
+	Header   *MatchPredicate `json:"header,omitempty"`
+	Cookie   *MatchPredicate `json:"cookie,omitempty"`
+	Versions []string        `json:"versions"`
+}
+
+// VersionWeight is the percentage of requests a version receives.
+type VersionWeight struct {
+	Version string `json:"version"`
+	Weight  int    `json:"weight"`
+}
+
+func (p *TrafficPolicy) Validate() error {
+	for i, rule := range p.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	if len(p.Weights) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(p.Weights))
+	total := 0
+	for _, weight := range p.Weights {
+		if weight.Version == "" {
+			return errors.New("weight requires a version")
+		}
+		if seen[weight.Version] {
+			return fmt.Errorf("version %q is weighted more than once", weight.Version)
+		}
+		seen[weight.Version] = true
+		if weight.Weight < 0 || weight.Weight > 100 {
+			return fmt.Errorf("weight of version %q must be between 0 and 100", weight.Version)
+		}
+		total += weight.Weight
+	}
+	if total != 100 {
+		return fmt.Errorf("weights must add up to 100, got %d", total)
+	}
+	return nil
+}
+
+func (r *VersionRule) validate() error {
+	if (r.Header == nil) == (r.Cookie == nil) {
+		return errors.New("exactly one of header or cookie is required")
+	}
+	if predicate := r.predicate(); predicate.Name == "" {
+		return errors.New("header or cookie requires a name")
+	}
+	if len(r.Versions) == 0 || slices.Contains(r.Versions, "") {
+		return errors.New("at least one version is required")
+	}
+	return nil
+}
+
+func (r *VersionRule) predicate() *MatchPredicate {
+	if r.Header != nil {
+		return r.Header
+	}
+	return r.Cookie
+}
+
+// Matches reports whether req carries the rule's header or cookie, with its
+// value when the rule requires one.
+func (r *VersionRule) Matches(req *http.Request) bool {
+	if r.Header != nil {
+		values := req.Header.Values(r.Header.Name)
+		return len(values) > 0 && (r.Header.Value == "" || slices.Contains(values, r.Header.Value))
+	}
+	for _, cookie := range req.CookiesNamed(r.Cookie.Name) {
+		if r.Cookie.Value == "" || cookie.Value == r.Cookie.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Shift sets the weight of version, adding it when the policy does not weigh
+// it yet, and shares the rest of the traffic between the other weighted
+// versions in proportion to their current weights, or evenly when they have
+// none. Calling it repeatedly with growing weights shifts traffic gradually.
+func (p *TrafficPolicy) Shift(version string, weight int) error {
+	if version == "" {
+		return errors.New("version is required")
+	}
+	if weight < 0 || weight > 100 {
+		return errors.New("weight must be between 0 and 100")
+	}
+
+	var others []int
+	current := 0
+	for i, w := range p.Weights {
+		if w.Version == version {
+			continue
+		}
+		others = append(others, i)
+		current += w.Weight
+	}
+	if len(others) == 0 && weight < 100 {
+		return fmt.Errorf("no other version to send the remaining %d%% of the traffic to", 100-weight)
+	}
+
+	// Largest remainder, so the weights keep adding up to 100.
+	rest := 100 - weight
+	remainders := make([]int, len(others))
+	assigned := 0
+	for n, i := range others {
+		share, denominator := p.Weights[i].Weight, current
+		if current == 0 {
+			share, denominator = 1, len(others)
+		}
+		p.Weights[i].Weight = rest * share / denominator
+		remainders[n] = rest * share % denominator
+		assigned += p.Weights[i].Weight
+	}
+	order := make([]int, len(others))
+	for n := range order {
+		order[n] = n
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return remainders[b] - remainders[a] })
+	for _, n := range order[:rest-assigned] {
+		p.Weights[others[n]].Weight++
+	}
+
+	if i := slices.IndexFunc(p.Weights, func(w VersionWeight) bool { return w.Version == version }); i >= 0 {
+		p.Weights[i].Weight = weight
+	} else {
+		p.Weights = append(p.Weights, VersionWeight{Version: version, Weight: weight})
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrafficPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  TrafficPolicy
+		wantErr bool
+	}{
+		{"empty", TrafficPolicy{}, false},
+		{"weights", TrafficPolicy{Weights: []VersionWeight{{Version: "v1", Weight: 90}, {Version: "v2", Weight: 10}}}, false},
+		{"header rule", TrafficPolicy{Rules: []VersionRule{{Header: &MatchPredicate{Name: "X-Canary", Value: "true"}, Versions: []string{"v2"}}}}, false},
+		{"cookie rule", TrafficPolicy{Rules: []VersionRule{{Cookie: &MatchPredicate{Name: "canary"}, Versions: []string{"v2"}}}}, false},
+		{"weights not adding up", TrafficPolicy{Weights: []VersionWeight{{Version: "v1", Weight: 90}}}, true},
+		{"negative weight", TrafficPolicy{Weights: []VersionWeight{{Version: "v1", Weight: 110}, {Version: "v2", Weight: -10}}}, true},
+		{"repeated version", TrafficPolicy{Weights: []VersionWeight{{Version: "v1", Weight: 50}, {Version: "v1", Weight: 50}}}, true},
+		{"weight without version", TrafficPolicy{Weights: []VersionWeight{{Weight: 100}}}, true},
+		{"rule without predicate", TrafficPolicy{Rules: []VersionRule{{Versions: []string{"v2"}}}}, true},
+		{"rule with header and cookie", TrafficPolicy{Rules: []VersionRule{{Header: &MatchPredicate{Name: "X-Canary"}, Cookie: &MatchPredicate{Name: "canary"}, Versions: []string{"v2"}}}}, true},
+		{"rule without name", TrafficPolicy{Rules: []VersionRule{{Header: &MatchPredicate{Value: "true"}, Versions: []string{"v2"}}}}, true},
+		{"rule without versions", TrafficPolicy{Rules: []VersionRule{{Header: &MatchPredicate{Name: "X-Canary"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVersionRule_Matches(t *testing.T) {
+	header := VersionRule{Header: &MatchPredicate{Name: "X-Canary", Value: "true"}, Versions: []string{"v2"}}
+	cookie := VersionRule{Cookie: &MatchPredicate{Name: "canary"}, Versions: []string{"v2"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header.Matches(req) || cookie.Matches(req) {
+		t.Error("a request without the header or cookie should not match")
+	}
+
+	req.Header.Set("x-canary", "false")
+	if header.Matches(req) {
+		t.Error("a header with another value should not match")
+	}
+	req.Header.Set("x-canary", "true")
+	if !header.Matches(req) {
+		t.Error("expected the header to match regardless of its case")
+	}
+
+	req.AddCookie(&http.Cookie{Name: "canary", Value: "1"})
+	if !cookie.Matches(req) {
+		t.Error("expected a cookie rule without value to match any value")
+	}
+}
+
+func TestTrafficPolicy_Shift(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []VersionWeight
+		version string
+		weight  int
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:    "adds a version",
+			weights: []VersionWeight{{Version: "v1", Weight: 100}},
+			version: "v2", weight: 10,
+			want: map[string]int{"v1": 90, "v2": 10},
+		},
+		{
+			name:    "keeps the others in proportion",
+			weights: []VersionWeight{{Version: "v1", Weight: 60}, {Version: "v2", Weight: 30}, {Version: "v3", Weight: 10}},
+			version: "v3", weight: 50,
+			want: map[string]int{"v1": 33, "v2": 17, "v3": 50},
+		},
+		{
+			name:    "shares evenly among versions without weight",
+			weights: []VersionWeight{{Version: "v1", Weight: 0}, {Version: "v2", Weight: 0}, {Version: "v3", Weight: 100}},
+			version: "v3", weight: 25,
+			want: map[string]int{"v1": 38, "v2": 37, "v3": 25},
+		},
+		{
+			name:    "completes a rollout",
+			weights: []VersionWeight{{Version: "v1", Weight: 50}, {Version: "v2", Weight: 50}},
+			version: "v2", weight: 100,
+			want: map[string]int{"v1": 0, "v2": 100},
+		},
+		{
+			name:    "no version left for the rest",
+			weights: []VersionWeight{{Version: "v2", Weight: 100}},
+			version: "v2", weight: 50,
+			wantErr: true,
+		},
+		{
+			name:    "weight out of range",
+			weights: []VersionWeight{{Version: "v1", Weight: 100}},
+			version: "v2", weight: 101,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &TrafficPolicy{Weights: tt.weights}
+			err := policy.Shift(tt.version, tt.weight)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := policy.Validate(); err != nil {
+				t.Fatalf("shifted policy is invalid: %v", err)
+			}
+			for _, w := range policy.Weights {
+				if tt.want[w.Version] != w.Weight {
+					t.Errorf("%s: expected weight %d, got %d", w.Version, tt.want[w.Version], w.Weight)
+				}
+			}
+			if len(policy.Weights) != len(tt.want) {
+				t.Errorf("expected %d versions, got %v", len(tt.want), policy.Weights)
+			}
+		})
+	}
+}
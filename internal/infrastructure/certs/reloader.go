@@ -0,0 +1,190 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader serves a certificate, and optionally a client CA bundle, read from
+// files, and reloads them when the files change so certificates can be
+// rotated without restarting the gateway. A reload that fails keeps the
+// previous certificate.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+
+	cert      atomic.Pointer[tls.Certificate]
+	clientCAs atomic.Pointer[x509.CertPool]
+	mu        sync.Mutex
+	modTimes  []time.Time
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewReloader loads the certificate pair and, when caFile is set, the CA
+// bundle used to verify client certificates.
+func NewReloader(certFile, keyFile, caFile string, interval time.Duration) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a certificate and a key file are required")
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pool, err = LoadCertPool(r.caFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.clientCAs.Store(pool)
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *Reloader) Start() {
+	go r.watch()
+}
+
+func (r *Reloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stopCh) })
+}
+
+func (r *Reloader) watch() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("certificate reload failed, keeping the previous certificate", "error", err)
+				continue
+			}
+			slog.Info("certificate reloaded", "cert_file", r.certFile)
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return false
+	}
+	for i := range modTimes {
+		if !modTimes[i].Equal(r.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) statFiles() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+// GetClientCertificate presents the latest certificate when the gateway is
+// asked for a client certificate, for use in tls.Config.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// ServerConfig returns a TLS configuration that always presents the latest
+// certificate. With a client CA bundle, client certificates are verified
+// against it, and required when requireClientCert is set.
+func (r *Reloader) ServerConfig(requireClientCert bool) *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+	}
+	if r.caFile == "" {
+		return base
+	}
+
+	clientAuth := tls.VerifyClientCertIfGiven
+	if requireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientAuth = clientAuth
+		config.ClientCAs = r.clientCAs.Load()
+		return config, nil
+	}
+	return base
+}
+
+// LoadCertPool reads a PEM bundle of CA certificates.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,147 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCertificate(t *testing.T, dir, commonName string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	cert, err := r.ServerConfig(false).GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader_ReloadsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCertificate(t, dir, "first", modTime)
+
+	r, err := NewReloader(certFile, keyFile, "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	defer r.Stop()
+
+	if got := servedCommonName(t, r); got != "first" {
+		t.Fatalf("expected the initial certificate, got %q", got)
+	}
+
+	writeCertificate(t, dir, "second", modTime.Add(time.Second))
+	deadline := time.Now().Add(2 * time.Second)
+	for servedCommonName(t, r) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloader_KeepsCertificateWhenReloadFails(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first", time.Now())
+
+	r, err := NewReloader(certFile, keyFile, "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected reload of an invalid key to fail")
+	}
+	if got := servedCommonName(t, r); got != "first" {
+		t.Errorf("expected the previous certificate to be kept, got %q", got)
+	}
+}
+
+func TestReloader_VerifiesClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "gateway", time.Now())
+
+	r, err := NewReloader(certFile, keyFile, certFile, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		require bool
+		want    tls.ClientAuthType
+	}{
+		{false, tls.VerifyClientCertIfGiven},
+		{true, tls.RequireAndVerifyClientCert},
+	} {
+		config, err := r.ServerConfig(tt.require).GetConfigForClient(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.ClientAuth != tt.want {
+			t.Errorf("require=%v: expected client auth %v, got %v", tt.require, tt.want, config.ClientAuth)
+		}
+		if config.ClientCAs == nil {
+			t.Errorf("require=%v: expected the client CA pool to be set", tt.require)
+		}
+	}
+}
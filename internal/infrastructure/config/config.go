@@ -6,15 +6,115 @@ import (
 	"github.com/kelseyhightower/envconfig"
 )
 
+const (
+	RegistryStoreMemory = "memory"
+	RegistryStoreRedis  = "redis"
+)
+
 type Config struct {
-	Port                int           `envconfig:"PORT" default:"8080"`
-	Env                 string        `envconfig:"ENV" default:"development"`
-	LogLevel            string        `envconfig:"LOG_LEVEL" default:"debug"`
-	CORSAllowedOrigins  []string      `envconfig:"CORS_ALLOWED_ORIGINS" default:"*"`
-	CORSAllowedMethods  []string      `envconfig:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,DELETE,OPTIONS"`
-	CORSAllowedHeaders  []string      `envconfig:"CORS_ALLOWED_HEADERS" default:"Origin,Content-Type,Accept,Authorization,X-Request-ID"`
-	HeartbeatTTL        time.Duration `envconfig:"HEARTBEAT_TTL" default:"30s"`
-	HealthCheckInterval time.Duration `envconfig:"HEALTH_CHECK_INTERVAL" default:"10s"`
+	Port                 int           `envconfig:"PORT" default:"8080"`
+	Env                  string        `envconfig:"ENV" default:"development"`
+	LogLevel             string        `envconfig:"LOG_LEVEL" default:"debug"`
+	CORSAllowedOrigins   []string      `envconfig:"CORS_ALLOWED_ORIGINS" default:"*"`
+	CORSAllowedMethods   []string      `envconfig:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,DELETE,OPTIONS"`
+	CORSAllowedHeaders   []string      `envconfig:"CORS_ALLOWED_HEADERS" default:"Origin,Content-Type,Accept,Authorization,X-Request-ID"`
+	HeartbeatTTL         time.Duration `envconfig:"HEARTBEAT_TTL" default:"30s"`
+	HealthCheckInterval  time.Duration `envconfig:"HEALTH_CHECK_INTERVAL" default:"10s"`
+	RegistryStore        string        `envconfig:"REGISTRY_STORE" default:"memory"`
+	RegistrySyncInterval time.Duration `envconfig:"REGISTRY_SYNC_INTERVAL" default:"1s"`
+
+	// TLSCertFile and TLSKeyFile switch the listener to HTTPS. With
+	// TLSClientCAFile, client certificates are verified against that bundle,
+	// and required when TLSRequireClientCert is set. The files are checked for
+	// changes every TLSReloadInterval.
+	TLSCertFile          string        `envconfig:"TLS_CERT_FILE" default:""`
+	TLSKeyFile           string        `envconfig:"TLS_KEY_FILE" default:""`
+	TLSClientCAFile      string        `envconfig:"TLS_CLIENT_CA_FILE" default:""`
+	TLSRequireClientCert bool          `envconfig:"TLS_REQUIRE_CLIENT_CERT" default:"false"`
+	TLSReloadInterval    time.Duration `envconfig:"TLS_RELOAD_INTERVAL" default:"10s"`
+
+	// Server*Timeout bound the gateway listener; a zero ServerWriteTimeout
+	// leaves response time to the route timeouts.
+	ServerReadTimeout       time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"30s"`
+	ServerReadHeaderTimeout time.Duration `envconfig:"SERVER_READ_HEADER_TIMEOUT" default:"10s"`
+	ServerWriteTimeout      time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"0"`
+	ServerIdleTimeout       time.Duration `envconfig:"SERVER_IDLE_TIMEOUT" default:"120s"`
+
+	// ServerH2CEnabled accepts HTTP/2 without TLS from clients that start
+	// with it, as plaintext gRPC clients do. Over TLS, HTTP/2 is negotiated.
+	ServerH2CEnabled bool `envconfig:"SERVER_H2C_ENABLED" default:"true"`
+
+	// UpstreamTimeout bounds proxied requests whose route declares no
+	// timeout_ms; UpstreamMaxTimeout caps the routes that do.
+	UpstreamTimeout    time.Duration `envconfig:"UPSTREAM_TIMEOUT" default:"30s"`
+	UpstreamMaxTimeout time.Duration `envconfig:"UPSTREAM_MAX_TIMEOUT" default:"5m"`
+
+	// Upstream* tune the connection pool kept to each upstream service.
+	UpstreamMaxIdleConns        int           `envconfig:"UPSTREAM_MAX_IDLE_CONNS" default:"1000"`
+	UpstreamMaxIdleConnsPerHost int           `envconfig:"UPSTREAM_MAX_IDLE_CONNS_PER_HOST" default:"100"`
+	UpstreamMaxConnsPerHost     int           `envconfig:"UPSTREAM_MAX_CONNS_PER_HOST" default:"0"`
+	UpstreamIdleConnTimeout     time.Duration `envconfig:"UPSTREAM_IDLE_CONN_TIMEOUT" default:"90s"`
+	UpstreamDialTimeout         time.Duration `envconfig:"UPSTREAM_DIAL_TIMEOUT" default:"5s"`
+	UpstreamKeepAlive           time.Duration `envconfig:"UPSTREAM_KEEP_ALIVE" default:"30s"`
+	UpstreamTLSHandshakeTimeout time.Duration `envconfig:"UPSTREAM_TLS_HANDSHAKE_TIMEOUT" default:"10s"`
+
+	// UpstreamTLSCAFile replaces the system roots when verifying instances
+	// registered with scheme https. UpstreamTLSCertFile and UpstreamTLSKeyFile
+	// are presented to them as the gateway's client certificate, reloaded like
+	// the listener certificate.
+	UpstreamTLSCAFile   string `envconfig:"UPSTREAM_TLS_CA_FILE" default:""`
+	UpstreamTLSCertFile string `envconfig:"UPSTREAM_TLS_CERT_FILE" default:""`
+	UpstreamTLSKeyFile  string `envconfig:"UPSTREAM_TLS_KEY_FILE" default:""`
+
+	// UpstreamUpgradeIdleTimeout closes WebSocket and other upgraded
+	// connections on streaming routes after that long without traffic; zero
+	// keeps them open until either side closes.
+	UpstreamUpgradeIdleTimeout time.Duration `envconfig:"UPSTREAM_UPGRADE_IDLE_TIMEOUT" default:"10m"`
+
+	// Mirror* bound the copies of requests routes send to shadow services:
+	// each may take MirrorTimeout, and copies beyond MirrorMaxConcurrent in
+	// flight are dropped.
+	MirrorTimeout       time.Duration `envconfig:"MIRROR_TIMEOUT" default:"5s"`
+	MirrorMaxConcurrent int           `envconfig:"MIRROR_MAX_CONCURRENT" default:"100"`
+
+	// Zone is the zone the gateway runs in. Instances registered with the same
+	// zone metadata are preferred while at least ZoneMinInstances of them are
+	// available; with fewer, requests spill over to every zone. An empty Zone
+	// spreads requests over all zones.
+	Zone             string `envconfig:"ZONE" default:""`
+	ZoneMinInstances int    `envconfig:"ZONE_MIN_INSTANCES" default:"1"`
+
+	HealthCheckEnabled            bool          `envconfig:"HEALTH_CHECK_ENABLED" default:"true"`
+	HealthCheckTimeout            time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	HealthCheckHealthyThreshold   int           `envconfig:"HEALTH_CHECK_HEALTHY_THRESHOLD" default:"2"`
+	HealthCheckUnhealthyThreshold int           `envconfig:"HEALTH_CHECK_UNHEALTHY_THRESHOLD" default:"3"`
+
+	// CircuitBreaker* configure the per-instance breakers in the proxy: an
+	// instance is skipped after CircuitBreakerConsecutiveFailures failures in a
+	// row, or once CircuitBreakerFailureRatio of at least
+	// CircuitBreakerMinRequests requests in CircuitBreakerWindow failed, and is
+	// probed again after CircuitBreakerOpenTimeout.
+	CircuitBreakerEnabled             bool          `envconfig:"CIRCUIT_BREAKER_ENABLED" default:"true"`
+	CircuitBreakerConsecutiveFailures int           `envconfig:"CIRCUIT_BREAKER_CONSECUTIVE_FAILURES" default:"5"`
+	CircuitBreakerFailureRatio        float64       `envconfig:"CIRCUIT_BREAKER_FAILURE_RATIO" default:"0.5"`
+	CircuitBreakerMinRequests         int           `envconfig:"CIRCUIT_BREAKER_MIN_REQUESTS" default:"20"`
+	CircuitBreakerWindow              time.Duration `envconfig:"CIRCUIT_BREAKER_WINDOW" default:"10s"`
+	CircuitBreakerOpenTimeout         time.Duration `envconfig:"CIRCUIT_BREAKER_OPEN_TIMEOUT" default:"30s"`
+	CircuitBreakerHalfOpenRequests    int           `envconfig:"CIRCUIT_BREAKER_HALF_OPEN_REQUESTS" default:"1"`
+
+	// Retry* bound the retries routes declare at registration: retries may add
+	// RetryBudgetRatio of a service's traffic in RetryBudgetWindow, and at
+	// least RetryBudgetMinPerSecond per second. Request bodies larger than
+	// RetryMaxBodyBytes are not buffered and so never retried or mirrored.
+	RetryBudgetRatio        float64       `envconfig:"RETRY_BUDGET_RATIO" default:"0.2"`
+	RetryBudgetMinPerSecond int           `envconfig:"RETRY_BUDGET_MIN_PER_SECOND" default:"10"`
+	RetryBudgetWindow       time.Duration `envconfig:"RETRY_BUDGET_WINDOW" default:"10s"`
+	RetryMaxBodyBytes       int64         `envconfig:"RETRY_MAX_BODY_BYTES" default:"1048576"`
+
+	LBStrategy          string            `envconfig:"LB_STRATEGY" default:"round_robin"`
+	LBServiceStrategies map[string]string `envconfig:"LB_SERVICE_STRATEGIES"`
+	LBHashKey           string            `envconfig:"LB_HASH_KEY" default:"ip"`
+	LBServiceHashKeys   map[string]string `envconfig:"LB_SERVICE_HASH_KEYS"`
 
 	JWTPublicKey      string        `envconfig:"JWT_PUBLIC_KEY"`
 	JWTPrivateKey     string        `envconfig:"JWT_PRIVATE_KEY"`
@@ -22,15 +122,39 @@ type Config struct {
 	JWTInternalTTL    time.Duration `envconfig:"JWT_INTERNAL_TTL" default:"5m"`
 	JWTAllowedIssuers []string      `envconfig:"JWT_ALLOWED_ISSUERS" default:"auth-service"`
 
+	// AdminScope is the scope an external token needs to use the /admin
+	// operator API. Without JWT keys the API is not mounted at all.
+	AdminScope string `envconfig:"ADMIN_SCOPE" default:"gateway:admin"`
+
 	TraceExporter     string `envconfig:"TRACE_EXPORTER" default:"noop"`
 	TraceOTLPEndpoint string `envconfig:"TRACE_OTLP_ENDPOINT" default:""`
 	TraceServiceName  string `envconfig:"TRACE_SERVICE_NAME" default:"gotway"`
 
-	RedisURL           string `envconfig:"REDIS_URL" default:""`
-	RateLimitEnabled   bool   `envconfig:"RATE_LIMIT_ENABLED" default:"false"`
-	RateLimitGlobalRPM int    `envconfig:"RATE_LIMIT_GLOBAL_RPM" default:"10000"`
-	RateLimitUserRPM   int    `envconfig:"RATE_LIMIT_USER_RPM" default:"100"`
-	RateLimitIPRPM     int    `envconfig:"RATE_LIMIT_IP_RPM" default:"60"`
+	RedisURL string `envconfig:"REDIS_URL" default:""`
+
+	// RateLimitEnabled turns on the gateway-wide quotas, checked after
+	// authentication: the global limit, per-user (raised by RateLimitScopeRPM,
+	// e.g. "premium:1000"), per-IP for anonymous callers, and per API key sent
+	// in RateLimitAPIKeyHeader. A quota of 0 is disabled.
+	// RateLimitRoutesEnabled independently enforces the rate_limit services
+	// declare on their routes at registration.
+	RateLimitEnabled       bool           `envconfig:"RATE_LIMIT_ENABLED" default:"false"`
+	RateLimitRoutesEnabled bool           `envconfig:"RATE_LIMIT_ROUTES_ENABLED" default:"true"`
+	RateLimitGlobalRPM     int            `envconfig:"RATE_LIMIT_GLOBAL_RPM" default:"10000"`
+	RateLimitUserRPM       int            `envconfig:"RATE_LIMIT_USER_RPM" default:"100"`
+	RateLimitScopeRPM      map[string]int `envconfig:"RATE_LIMIT_SCOPE_RPM"`
+	RateLimitIPRPM         int            `envconfig:"RATE_LIMIT_IP_RPM" default:"60"`
+	RateLimitAPIKeyRPM     int            `envconfig:"RATE_LIMIT_API_KEY_RPM" default:"0"`
+	RateLimitAPIKeyHeader  string         `envconfig:"RATE_LIMIT_API_KEY_HEADER" default:"X-API-Key"`
+
+	// RateLimitAlgorithm is sliding_window, token_bucket or gcra. Limits are
+	// counted per RateLimitWindow; RateLimitBurst of 0 lets token_bucket and
+	// gcra accept the whole limit back to back. RateLimitMemoryMaxKeys caps the
+	// keys tracked when no Redis is configured.
+	RateLimitAlgorithm     string        `envconfig:"RATE_LIMIT_ALGORITHM" default:"sliding_window"`
+	RateLimitWindow        time.Duration `envconfig:"RATE_LIMIT_WINDOW" default:"1m"`
+	RateLimitBurst         int           `envconfig:"RATE_LIMIT_BURST" default:"0"`
+	RateLimitMemoryMaxKeys int           `envconfig:"RATE_LIMIT_MEMORY_MAX_KEYS" default:"100000"`
 
 	Version, Commit, BuildDate string
 }
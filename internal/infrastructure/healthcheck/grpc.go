@@ -0,0 +1,55 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpcHealthCheckRequest is a length-prefixed, empty HealthCheckRequest: it
+// asks about the server as a whole.
+var grpcHealthCheckRequest = []byte{0, 0, 0, 0, 0}
+
+// grpcServing is the encoded HealthCheckResponse{status: SERVING}.
+var grpcServing = []byte{0x08, 0x01}
+
+// probeGRPC calls grpc.health.v1.Health/Check, decoding its one-field
+// response by hand rather than pulling in a protobuf runtime.
+func (p *HTTPProber) probeGRPC(ctx context.Context, instance *domain.ServiceInstance) error {
+	url := fmt.Sprintf("%s://%s%s", instance.URLScheme(), instance.Address(), grpcHealthCheckPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(grpcHealthCheckRequest))
+	if err != nil {
+		return fmt.Errorf("failed to build health request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("User-Agent", "gotway-health-check")
+
+	resp, err := p.client(instance).Do(req)
+	if err != nil {
+		return fmt.Errorf("health request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read health response: %w", err)
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if resp.StatusCode != http.StatusOK || status != "0" {
+		return fmt.Errorf("unhealthy grpc status %q (http %d)", status, resp.StatusCode)
+	}
+	if len(body) < 5 || !bytes.Equal(body[5:], grpcServing) {
+		return fmt.Errorf("grpc health check did not report SERVING")
+	}
+	return nil
+}
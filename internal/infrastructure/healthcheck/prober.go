@@ -0,0 +1,125 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// HTTPProber probes an instance by issuing a GET to its HealthURL. Any 2xx or
+// 3xx response is considered healthy. gRPC instances are probed with the
+// standard grpc.health.v1.Health/Check call instead, and must answer SERVING.
+type HTTPProber struct {
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+	// clients holds one client per TLS server name and protocol, since both
+	// are part of the transport.
+	clients map[clientKey]*http.Client
+}
+
+type clientKey struct {
+	serverName string
+	protocol   string
+}
+
+type ProberOption func(*HTTPProber)
+
+// WithTLSConfig sets the configuration used to probe https instances, e.g. the
+// CAs their certificates are issued by.
+func WithTLSConfig(cfg *tls.Config) ProberOption {
+	return func(p *HTTPProber) {
+		p.tlsConfig = cfg
+	}
+}
+
+// NewHTTPProber creates a prober. Timeouts are taken from the probe context.
+func NewHTTPProber(opts ...ProberOption) *HTTPProber {
+	p := &HTTPProber{clients: make(map[clientKey]*http.Client)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Probe performs a single health check against the instance.
+func (p *HTTPProber) Probe(ctx context.Context, instance *domain.ServiceInstance) error {
+	if instance.Protocol == domain.ProtocolGRPC {
+		return p.probeGRPC(ctx, instance)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL(instance), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build health request: %w", err)
+	}
+	req.Header.Set("User-Agent", "gotway-health-check")
+
+	resp, err := p.client(instance).Do(req)
+	if err != nil {
+		return fmt.Errorf("health request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unhealthy status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *HTTPProber) client(instance *domain.ServiceInstance) *http.Client {
+	key := clientKey{serverName: instance.TLSServerName, protocol: instance.UpstreamProtocol()}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, exists := p.clients[key]; exists {
+		return client
+	}
+	serverName := key.serverName
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if p.tlsConfig != nil {
+		transport.TLSClientConfig = p.tlsConfig.Clone()
+	}
+	if serverName != "" {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.ServerName = serverName
+	}
+	if key.protocol == domain.UpstreamProtocolH2C {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = &protocols
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	p.clients[key] = client
+	return client
+}
+
+func healthURL(instance *domain.ServiceInstance) string {
+	if strings.HasPrefix(instance.HealthURL, "http://") || strings.HasPrefix(instance.HealthURL, "https://") {
+		return instance.HealthURL
+	}
+
+	path := instance.HealthURL
+	if path == "" {
+		path = "/health"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s://%s%s", instance.URLScheme(), instance.Address(), path)
+}
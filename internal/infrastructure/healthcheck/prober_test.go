@@ -0,0 +1,115 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func instanceFor(t *testing.T, server *httptest.Server, healthURL string) *domain.ServiceInstance {
+	t.Helper()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	return &domain.ServiceInstance{
+		ID:        "instance-1",
+		Host:      u.Hostname(),
+		Port:      port,
+		HealthURL: healthURL,
+	}
+}
+
+func TestHTTPProber_Healthy(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := NewHTTPProber().Probe(context.Background(), instanceFor(t, server, "/status"))
+	if err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+	if gotPath != "/status" {
+		t.Errorf("expected probe on /status, got %s", gotPath)
+	}
+}
+
+func TestHTTPProber_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewHTTPProber().Probe(context.Background(), instanceFor(t, server, "/health")); err == nil {
+		t.Fatal("expected error for 500 response")
+	}
+}
+
+func TestHTTPProber_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	instance := instanceFor(t, server, "/health")
+	server.Close()
+
+	if err := NewHTTPProber().Probe(context.Background(), instance); err == nil {
+		t.Fatal("expected error for closed server")
+	}
+}
+
+func TestHealthURL(t *testing.T) {
+	tests := []struct {
+		healthURL string
+		expected  string
+	}{
+		{"/health", "http://svc:8080/health"},
+		{"", "http://svc:8080/health"},
+		{"ready", "http://svc:8080/ready"},
+		{"http://other:9000/ping", "http://other:9000/ping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.healthURL, func(t *testing.T) {
+			instance := &domain.ServiceInstance{Host: "svc", Port: 8080, HealthURL: tt.healthURL}
+			if got := healthURL(instance); got != tt.expected {
+				t.Errorf("healthURL(%q) = %q, want %q", tt.healthURL, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPProber_GRPCHealthCheck(t *testing.T) {
+	for status, wantErr := range map[byte]bool{1: false, 2: true} {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/grpc.health.v1.Health/Check" || r.ProtoMajor != 2 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/grpc")
+			_, _ = w.Write([]byte{0, 0, 0, 0, 2, 0x08, status})
+			w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		}))
+		server.Config.Protocols = &http.Protocols{}
+		server.Config.Protocols.SetHTTP1(true)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+		server.Start()
+
+		instance := instanceFor(t, server, "/health")
+		instance.Protocol = domain.ProtocolGRPC
+		err := NewHTTPProber().Probe(context.Background(), instance)
+		server.Close()
+
+		if (err != nil) != wantErr {
+			t.Errorf("status %d: Probe() error = %v, wantErr %v", status, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// AdminHandler serves the operator API, which inspects the registry and
+// changes it on behalf of on-call rather than of the services themselves.
+type AdminHandler struct {
+	registry *application.Registry
+	breakers *application.CircuitBreakers
+}
+
+// NewAdminHandler creates the operator API handler. breakers may be nil when
+// circuit breaking is off.
+func NewAdminHandler(registry *application.Registry, breakers *application.CircuitBreakers) *AdminHandler {
+	return &AdminHandler{registry: registry, breakers: breakers}
+}
+
+type routeView struct {
+	Key          string       `json:"key"`
+	Service      string       `json:"service"`
+	Method       string       `json:"method"`
+	Path         string       `json:"path"`
+	BasePath     string       `json:"base_path"`
+	Route        domain.Route `json:"route"`
+	RegisteredAt time.Time    `json:"registered_at"`
+}
+
+type SetRotationRequest struct {
+	InRotation *bool `json:"in_rotation" binding:"required"`
+}
+
+type SetWeightRequest struct {
+	Weight int `json:"weight" binding:"required"`
+}
+
+// ListRoutes lists the registered routes with the service owning each,
+// filtered by the service, method and path_prefix query parameters and paged
+// by limit and offset.
+func (h *AdminHandler) ListRoutes(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	entries, total := h.registry.ListRoutes(application.RouteFilter{
+		Service:    c.Query("service"),
+		Method:     c.Query("method"),
+		PathPrefix: c.Query("path_prefix"),
+	}, page)
+
+	routes := make([]routeView, 0, len(entries))
+	for _, entry := range entries {
+		routes = append(routes, routeView{
+			Key:          entry.Route.Key(entry.BasePath),
+			Service:      entry.ServiceName,
+			Method:       entry.Route.Method,
+			Path:         entry.Route.FullPath(entry.BasePath),
+			BasePath:     entry.BasePath,
+			Route:        entry.Route,
+			RegisteredAt: entry.RegisteredAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"routes": routes,
+		"total":  total,
+		"limit":  page.Limit,
+		"offset": page.Offset,
+	})
+}
+
+// RouteTable dumps the compiled route table requests are matched against.
+func (h *AdminHandler) RouteTable(c *gin.Context) {
+	c.JSON(http.StatusOK, h.registry.DumpRouteTable())
+}
+
+// ListInstances lists the registered instances, filtered by the service and
+// status query parameters and paged by limit and offset.
+func (h *AdminHandler) ListInstances(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	instances, total := h.registry.ListInstances(application.InstanceFilter{
+		Service: c.Query("service"),
+		Status:  domain.ServiceStatus(c.Query("status")),
+	}, page)
+
+	views := make([]instanceView, 0, len(instances))
+	for _, instance := range instances {
+		views = append(views, h.instanceView(instance))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"instances": views,
+		"total":     total,
+		"limit":     page.Limit,
+		"offset":    page.Offset,
+	})
+}
+
+func (h *AdminHandler) GetInstance(c *gin.Context) {
+	instance := h.registry.GetInstance(c.Param("id"))
+	if instance == nil {
+		writeInstanceError(c, domain.ErrInstanceNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, h.instanceView(instance))
+}
+
+// DeregisterInstance removes the instance at once, as if it had deregistered.
+func (h *AdminHandler) DeregisterInstance(c *gin.Context) {
+	if err := h.registry.Deregister(c.Param("id")); err != nil {
+		writeInstanceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deregistered"})
+}
+
+func (h *AdminHandler) DrainInstance(c *gin.Context) {
+	if err := h.registry.DrainInstance(c.Param("id")); err != nil {
+		writeInstanceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": domain.StatusDraining})
+}
+
+func (h *AdminHandler) SetRotation(c *gin.Context) {
+	var req SetRotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.registry.SetInstanceRotation(c.Param("id"), *req.InRotation); err != nil {
+		writeInstanceError(c, err)
+		return
+	}
+	status := domain.StatusDisabled
+	if *req.InRotation {
+		status = domain.StatusHealthy
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+func (h *AdminHandler) SetWeight(c *gin.Context) {
+	var req SetWeightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.registry.SetInstanceWeight(c.Param("id"), req.Weight); err != nil {
+		writeInstanceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"weight": req.Weight})
+}
+
+func (h *AdminHandler) instanceView(instance *domain.ServiceInstance) instanceView {
+	view := instanceView{ServiceInstance: instance}
+	if h.breakers != nil {
+		view.CircuitBreaker = h.breakers.State(instance.ID)
+	}
+	return view
+}
+
+func parsePage(c *gin.Context) (application.Page, bool) {
+	page := application.Page{Limit: defaultPageLimit}
+	for name, target := range map[string]*int{"limit": &page.Limit, "offset": &page.Offset} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": name + " must be a non-negative integer",
+			})
+			return page, false
+		}
+		*target = value
+	}
+	if page.Limit == 0 || page.Limit > maxPageLimit {
+		page.Limit = maxPageLimit
+	}
+	return page, true
+}
+
+func writeInstanceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInstanceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "instance_not_found",
+			"message": "the specified instance does not exist",
+		})
+	case errors.Is(err, domain.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "admin_operation_failed",
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func setupAdminRouter(t *testing.T) (*application.Registry, *gin.Engine, string) {
+	t.Helper()
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "orders",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/orders",
+		Routes:      []domain.Route{{Method: "GET", Path: "/"}, {Method: "POST", Path: "/"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	_, err = registry.Register(&domain.RegisterRequest{
+		ServiceName: "users",
+		Host:        "localhost",
+		Port:        8082,
+		BasePath:    "/users",
+		Routes:      []domain.Route{{Method: "GET", Path: "/{id}"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	handler := NewAdminHandler(registry, nil)
+	router := gin.New()
+	router.GET("/admin/routes", handler.ListRoutes)
+	router.GET("/admin/routes/table", handler.RouteTable)
+	router.GET("/admin/instances", handler.ListInstances)
+	router.GET("/admin/instances/:id", handler.GetInstance)
+	router.DELETE("/admin/instances/:id", handler.DeregisterInstance)
+	router.POST("/admin/instances/:id/drain", handler.DrainInstance)
+	router.PUT("/admin/instances/:id/rotation", handler.SetRotation)
+	router.PUT("/admin/instances/:id/weight", handler.SetWeight)
+	return registry, router, resp.InstanceID
+}
+
+func TestAdmin_ListRoutes(t *testing.T) {
+	_, router, _ := setupAdminRouter(t)
+
+	resp := sendJSON(router, "GET", "/admin/routes?service=orders&limit=1&offset=1", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var body struct {
+		Routes []routeView `json:"routes"`
+		Total  int         `json:"total"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if body.Total != 2 || len(body.Routes) != 1 {
+		t.Fatalf("expected 1 of 2 orders routes, got %d of %d", len(body.Routes), body.Total)
+	}
+	if got := body.Routes[0]; got.Service != "orders" || got.Method != "POST" || got.Path != "/orders/" {
+		t.Errorf("expected POST /orders/ owned by orders, got %+v", got)
+	}
+
+	for _, query := range []string{"limit=-1", "offset=abc"} {
+		if resp := sendJSON(router, "GET", "/admin/routes?"+query, nil); resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.Code)
+		}
+	}
+}
+
+func TestAdmin_RouteTable(t *testing.T) {
+	_, router, _ := setupAdminRouter(t)
+
+	resp := sendJSON(router, "GET", "/admin/routes/table", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	var dump application.RouteTableDump
+	if err := json.Unmarshal(resp.Body.Bytes(), &dump); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	get := dump.Methods["GET"]
+	if get == nil || len(get.Children) != 2 {
+		t.Fatalf("expected the orders and users segments under GET, got %+v", get)
+	}
+	users := get.Children[1]
+	if len(users.Children) != 1 || users.Children[0].Segment != "{param}" {
+		t.Errorf("expected a parameter under users, got %+v", users.Children)
+	}
+}
+
+func TestAdmin_ListAndGetInstances(t *testing.T) {
+	registry, router, id := setupAdminRouter(t)
+	_ = registry.SetInstanceRotation(id, false)
+
+	resp := sendJSON(router, "GET", "/admin/instances?status=disabled", nil)
+	var body struct {
+		Instances []domain.ServiceInstance `json:"instances"`
+		Total     int                      `json:"total"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if body.Total != 1 || body.Instances[0].ID != id {
+		t.Errorf("expected the disabled instance, got %+v", body)
+	}
+
+	if resp := sendJSON(router, "GET", "/admin/instances/"+id, nil); resp.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.Code)
+	}
+	if resp := sendJSON(router, "GET", "/admin/instances/missing", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.Code)
+	}
+}
+
+func TestAdmin_ChangesInstances(t *testing.T) {
+	registry, router, id := setupAdminRouter(t)
+	path := "/admin/instances/" + id
+
+	if resp := sendJSON(router, "POST", path+"/drain", nil); resp.Code != http.StatusOK {
+		t.Fatalf("drain: expected 200, got %d", resp.Code)
+	}
+	if got := registry.GetInstance(id).Status; got != domain.StatusDraining {
+		t.Errorf("expected draining, got %s", got)
+	}
+
+	if resp := sendJSON(router, "PUT", path+"/rotation", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("rotation without a body: expected 400, got %d", resp.Code)
+	}
+	if resp := sendJSON(router, "PUT", path+"/rotation", gin.H{"in_rotation": false}); resp.Code != http.StatusOK {
+		t.Fatalf("rotation: expected 200, got %d", resp.Code)
+	}
+	if got := registry.GetInstance(id).Status; got != domain.StatusDisabled {
+		t.Errorf("expected disabled, got %s", got)
+	}
+	if resp := sendJSON(router, "PUT", path+"/rotation", gin.H{"in_rotation": true}); resp.Code != http.StatusOK {
+		t.Fatalf("rotation: expected 200, got %d", resp.Code)
+	}
+	if !registry.GetInstance(id).IsHealthy() {
+		t.Error("expected the instance back in rotation")
+	}
+
+	if resp := sendJSON(router, "PUT", path+"/weight", gin.H{"weight": 7}); resp.Code != http.StatusOK {
+		t.Fatalf("weight: expected 200, got %d", resp.Code)
+	}
+	if got := registry.GetInstance(id).Weight; got != 7 {
+		t.Errorf("expected weight 7, got %d", got)
+	}
+	if resp := sendJSON(router, "PUT", path+"/weight", gin.H{"weight": domain.MaxWeight + 1}); resp.Code != http.StatusBadRequest {
+		t.Errorf("weight out of range: expected 400, got %d", resp.Code)
+	}
+
+	if resp := sendJSON(router, "DELETE", path, nil); resp.Code != http.StatusOK {
+		t.Fatalf("deregister: expected 200, got %d", resp.Code)
+	}
+	if registry.GetInstance(id) != nil {
+		t.Error("expected the instance to be gone")
+	}
+	if resp := sendJSON(router, "PUT", path+"/weight", gin.H{"weight": 3}); resp.Code != http.StatusNotFound {
+		t.Errorf("weight after deregister: expected 404, got %d", resp.Code)
+	}
+	if resp := sendJSON(router, "POST", path+"/drain", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("drain after deregister: expected 404, got %d", resp.Code)
+	}
+}
@@ -11,10 +11,25 @@ import (
 
 type RegistryHandler struct {
 	registry *application.Registry
+	breakers *application.CircuitBreakers
 }
 
-func NewRegistryHandler(registry *application.Registry) *RegistryHandler {
-	return &RegistryHandler{registry: registry}
+type RegistryHandlerOption func(*RegistryHandler)
+
+// WithCircuitBreakers reports the circuit breaker state of each instance in
+// ListServices.
+func WithCircuitBreakers(breakers *application.CircuitBreakers) RegistryHandlerOption {
+	return func(h *RegistryHandler) {
+		h.breakers = breakers
+	}
+}
+
+func NewRegistryHandler(registry *application.Registry, opts ...RegistryHandlerOption) *RegistryHandler {
+	h := &RegistryHandler{registry: registry}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *RegistryHandler) Register(c *gin.Context) {
@@ -114,9 +129,75 @@ func (h *RegistryHandler) Deregister(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "deregistered"})
 }
 
+// instanceView is a ServiceInstance as listed by ListServices, with the state
+// of this replica's circuit breaker for it.
+type instanceView struct {
+	*domain.ServiceInstance
+	CircuitBreaker application.BreakerState `json:"circuit_breaker,omitempty"`
+}
+
+// Drain takes the instance out of rotation while it finishes the requests it
+// is serving. It keeps its routes and heartbeat until it deregisters. Only
+// the service owning the instance may drain it.
+func (h *RegistryHandler) Drain(c *gin.Context) {
+	var req domain.DrainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	instance := h.registry.GetInstance(req.InstanceID)
+	if instance == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "instance_not_found",
+			"message": "the specified instance does not exist",
+		})
+		return
+	}
+	if !authorizedFor(c, instance.ServiceName) {
+		return
+	}
+
+	if err := h.registry.DrainInstance(req.InstanceID); err != nil {
+		if errors.Is(err, domain.ErrInstanceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "instance_not_found",
+				"message": "the specified instance does not exist",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "drain_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": domain.StatusDraining})
+}
+
 func (h *RegistryHandler) ListServices(c *gin.Context) {
 	services := h.registry.GetAllServices()
+	if h.breakers == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"services": services,
+		})
+		return
+	}
+
+	views := make(map[string][]instanceView, len(services))
+	for serviceName, instances := range services {
+		for _, instance := range instances {
+			views[serviceName] = append(views[serviceName], instanceView{
+				ServiceInstance: instance,
+				CircuitBreaker:  h.breakers.State(instance.ID),
+			})
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"services": services,
+		"services": views,
 	})
 }
@@ -23,6 +23,7 @@ func setupTestRouter(registry *application.Registry) *gin.Engine {
 	router.POST("/internal/registry/register", handler.Register)
 	router.POST("/internal/registry/heartbeat", handler.Heartbeat)
 	router.POST("/internal/registry/deregister", handler.Deregister)
+	router.POST("/internal/registry/drain", handler.Drain)
 	router.GET("/internal/registry/services", handler.ListServices)
 	return router
 }
@@ -384,6 +385,77 @@ func TestDeregister_Success(t *testing.T) {
 	}
 }
 
+func TestDrain_KeepsRoutesAndHeartbeat(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{
+		HeartbeatTTL: 30 * time.Second,
+	})
+	router := setupTestRouter(registry)
+
+	registerResp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/users"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	resp := sendJSON(router, "POST", "/internal/registry/drain", domain.DrainRequest{InstanceID: registerResp.InstanceID})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if len(registry.GetHealthyInstances("test-service")) != 0 {
+		t.Error("expected a draining instance to receive no new requests")
+	}
+	if entry, _ := registry.MatchRoute(&domain.RouteRequest{Method: "GET", Path: "/api/v1/users"}); entry == nil {
+		t.Error("expected a draining instance to keep its routes")
+	}
+
+	resp = sendJSON(router, "POST", "/internal/registry/heartbeat", domain.HeartbeatRequest{InstanceID: registerResp.InstanceID})
+	if resp.Code != http.StatusOK {
+		t.Errorf("expected heartbeats to be accepted while draining, got %d", resp.Code)
+	}
+	if got := registry.GetInstance(registerResp.InstanceID).Status; got != domain.StatusDraining {
+		t.Errorf("expected the instance to stay draining after a heartbeat, got %s", got)
+	}
+
+	resp = sendJSON(router, "POST", "/internal/registry/drain", domain.DrainRequest{InstanceID: "non-existent-instance"})
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.Code)
+	}
+}
+
+func TestDrain_RejectsOtherServices(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{
+		HeartbeatTTL: 30 * time.Second,
+	})
+	registerResp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/users"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	handler := NewRegistryHandler(registry)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("service_name", "other-service") })
+	router.POST("/internal/registry/drain", handler.Drain)
+
+	resp := sendJSON(router, "POST", "/internal/registry/drain", domain.DrainRequest{InstanceID: registerResp.InstanceID})
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", resp.Code)
+	}
+	if !registry.GetInstance(registerResp.InstanceID).IsHealthy() {
+		t.Error("expected another service's drain to be ignored")
+	}
+}
+
 func TestDeregister_InstanceNotFound(t *testing.T) {
 	registry := application.NewRegistry(application.RegistryConfig{})
 	router := setupTestRouter(registry)
@@ -473,3 +545,48 @@ func TestListServices_Empty(t *testing.T) {
 		t.Errorf("expected status 200, got %d", resp.Code)
 	}
 }
+
+func TestListServices_ReportsCircuitBreakerState(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{
+		HeartbeatTTL: 30 * time.Second,
+	})
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "test-service",
+		Host:        "localhost",
+		Port:        8081,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/users"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	breakers := application.NewCircuitBreakers(application.CircuitBreakerConfig{ConsecutiveFailures: 1})
+	breakers.Acquire(resp.InstanceID)
+	breakers.Record(resp.InstanceID, false)
+
+	router := gin.New()
+	router.GET("/internal/registry/services", NewRegistryHandler(registry, WithCircuitBreakers(breakers)).ListServices)
+
+	req, _ := http.NewRequest("GET", "/internal/registry/services", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Services map[string][]struct {
+			ID             string `json:"id"`
+			CircuitBreaker string `json:"circuit_breaker"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	instances := response.Services["test-service"]
+	if len(instances) != 1 || instances[0].ID != resp.InstanceID {
+		t.Fatalf("expected the registered instance, got %+v", instances)
+	}
+	if instances[0].CircuitBreaker != string(application.BreakerOpen) {
+		t.Errorf("expected circuit_breaker open, got %q", instances[0].CircuitBreaker)
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// ShiftTrafficRequest moves a share of a service's traffic to one version.
+type ShiftTrafficRequest struct {
+	Version string `json:"version" binding:"required"`
+	Weight  *int   `json:"weight" binding:"required"`
+}
+
+func (h *RegistryHandler) GetTrafficPolicy(c *gin.Context) {
+	serviceName := c.Param("service")
+	if !authorizedFor(c, serviceName) {
+		return
+	}
+
+	policy := h.registry.TrafficPolicy(serviceName)
+	if policy == nil {
+		writeTrafficError(c, domain.ErrTrafficPolicyNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *RegistryHandler) SetTrafficPolicy(c *gin.Context) {
+	serviceName := c.Param("service")
+	if !authorizedFor(c, serviceName) {
+		return
+	}
+
+	var policy domain.TrafficPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.registry.SetTrafficPolicy(serviceName, &policy); err != nil {
+		writeTrafficError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, &policy)
+}
+
+func (h *RegistryHandler) ShiftTraffic(c *gin.Context) {
+	serviceName := c.Param("service")
+	if !authorizedFor(c, serviceName) {
+		return
+	}
+
+	var req ShiftTrafficRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	policy, err := h.registry.ShiftTraffic(serviceName, req.Version, *req.Weight)
+	if err != nil {
+		writeTrafficError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *RegistryHandler) DeleteTrafficPolicy(c *gin.Context) {
+	serviceName := c.Param("service")
+	if !authorizedFor(c, serviceName) {
+		return
+	}
+
+	if err := h.registry.DeleteTrafficPolicy(serviceName); err != nil {
+		writeTrafficError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// authorizedFor rejects requests authenticated as a service other than
+// serviceName, so a service can only change how its own traffic is split.
+func authorizedFor(c *gin.Context, serviceName string) bool {
+	if authenticated, exists := c.Get("service_name"); exists {
+		if name, ok := authenticated.(string); ok && name != serviceName {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "service name does not match authenticated identity",
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func writeTrafficError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_traffic_policy",
+			"message": err.Error(),
+		})
+	case errors.Is(err, domain.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "service_not_found",
+			"message": "the specified service has no registered instances",
+		})
+	case errors.Is(err, domain.ErrTrafficPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "traffic_policy_not_found",
+			"message": "the specified service has no traffic policy",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "traffic_policy_failed",
+			"message": err.Error(),
+		})
+	}
+}
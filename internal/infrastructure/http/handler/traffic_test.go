@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func setupTrafficRouter(t *testing.T, authenticatedAs string) (*application.Registry, *gin.Engine) {
+	t.Helper()
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "orders",
+		Host:        "localhost",
+		Port:        8081,
+		Version:     "v1",
+		BasePath:    "/orders",
+		Routes:      []domain.Route{{Method: "GET", Path: "/"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	handler := NewRegistryHandler(registry)
+	router := gin.New()
+	if authenticatedAs != "" {
+		router.Use(func(c *gin.Context) { c.Set("service_name", authenticatedAs) })
+	}
+	router.GET("/internal/registry/services/:service/traffic", handler.GetTrafficPolicy)
+	router.PUT("/internal/registry/services/:service/traffic", handler.SetTrafficPolicy)
+	router.POST("/internal/registry/services/:service/traffic/shift", handler.ShiftTraffic)
+	router.DELETE("/internal/registry/services/:service/traffic", handler.DeleteTrafficPolicy)
+	return registry, router
+}
+
+func sendJSON(router *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestTrafficPolicy_SetShiftAndDelete(t *testing.T) {
+	registry, router := setupTrafficRouter(t, "orders")
+	const path = "/internal/registry/services/orders/traffic"
+
+	if resp := sendJSON(router, "GET", path, nil); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before a policy is set, got %d", resp.Code)
+	}
+
+	policy := domain.TrafficPolicy{
+		Rules:   []domain.VersionRule{{Header: &domain.MatchPredicate{Name: "X-Canary", Value: "true"}, Versions: []string{"v2"}}},
+		Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}},
+	}
+	if resp := sendJSON(router, "PUT", path, policy); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	resp := sendJSON(router, "POST", path+"/shift", gin.H{"version": "v2", "weight": 25})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var shifted domain.TrafficPolicy
+	if err := json.Unmarshal(resp.Body.Bytes(), &shifted); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(shifted.Rules) != 1 || len(shifted.Weights) != 2 || shifted.Weights[1].Weight != 25 {
+		t.Errorf("expected the rule kept and v2 at 25, got %+v", shifted)
+	}
+	if stored := registry.TrafficPolicy("orders"); stored == nil || stored.Weights[0].Weight != 75 {
+		t.Errorf("expected v1 at 75 in the stored policy, got %+v", stored)
+	}
+
+	if resp := sendJSON(router, "DELETE", path, nil); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	if resp := sendJSON(router, "DELETE", path, nil); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing policy, got %d", resp.Code)
+	}
+}
+
+func TestTrafficPolicy_Errors(t *testing.T) {
+	_, router := setupTrafficRouter(t, "")
+
+	invalid := domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 60}}}
+	if resp := sendJSON(router, "PUT", "/internal/registry/services/orders/traffic", invalid); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for weights not adding up to 100, got %d", resp.Code)
+	}
+
+	valid := domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}}}
+	if resp := sendJSON(router, "PUT", "/internal/registry/services/unknown/traffic", valid); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown service, got %d", resp.Code)
+	}
+
+	if resp := sendJSON(router, "POST", "/internal/registry/services/orders/traffic/shift", gin.H{"version": "v2"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a shift without weight, got %d", resp.Code)
+	}
+	if resp := sendJSON(router, "POST", "/internal/registry/services/orders/traffic/shift", gin.H{"version": "v2", "weight": 10}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 shifting without a policy, got %d", resp.Code)
+	}
+}
+
+func TestTrafficPolicy_AntiImpersonation(t *testing.T) {
+	_, router := setupTrafficRouter(t, "payments")
+
+	policy := domain.TrafficPolicy{Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}}}
+	if resp := sendJSON(router, "PUT", "/internal/registry/services/orders/traffic", policy); resp.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another service's policy, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/apascualco/gotway/internal/infrastructure/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware guards the operator API. It accepts only external
+// bearer tokens carrying the admin scope, so neither service tokens nor
+// ordinary user tokens can change the registry through it.
+type AdminAuthMiddleware struct {
+	jwtService *jwt.Service
+	scope      string
+}
+
+func NewAdminAuthMiddleware(jwtService *jwt.Service, scope string) *AdminAuthMiddleware {
+	return &AdminAuthMiddleware{jwtService: jwtService, scope: scope}
+}
+
+func (m *AdminAuthMiddleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := extractBearerToken(c)
+		if tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "missing authorization token",
+			})
+			return
+		}
+
+		claims, err := m.jwtService.ValidateExternalToken(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "invalid token",
+			})
+			return
+		}
+
+		if !slices.Contains(claims.Scopes, m.scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":    "forbidden",
+				"message":  "insufficient scopes",
+				"required": []string{m.scope},
+			})
+			return
+		}
+
+		setIdentity(c, claims)
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	privateKey := setupTestKeys(t)
+	jwtService := createTestJWTService(t, privateKey)
+	adminAuth := NewAdminAuthMiddleware(jwtService, "gateway:admin")
+
+	adminToken := generateExternalToken(t, privateKey, &domain.ExternalClaims{
+		Subject: "oncall-1",
+		Scopes:  []string{"gateway:admin"},
+		Issuer:  "auth-service",
+	})
+	userToken := generateExternalToken(t, privateKey, &domain.ExternalClaims{
+		Subject: "user-123",
+		Scopes:  []string{"read", "write"},
+		Issuer:  "auth-service",
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer invalid-token", http.StatusUnauthorized},
+		{"without the admin scope", "Bearer " + userToken, http.StatusForbidden},
+		{"with the admin scope", "Bearer " + adminToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/admin/routes", adminAuth.Authenticate(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/routes", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
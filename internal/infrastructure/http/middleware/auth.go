@@ -79,6 +79,7 @@ func (a *AuthMiddleware) Authenticate(route *domain.RouteEntry, serviceName stri
 
 		c.Request.Header.Set(HeaderAuthorization, BearerPrefix+internalToken)
 		c.Request.Header.Set(HeaderOriginalIssuer, claims.Issuer)
+		setIdentity(c, claims)
 
 		c.Next()
 	}
@@ -131,10 +132,20 @@ func (a *AuthMiddleware) AuthenticateRequest(c *gin.Context, route *domain.Route
 
 	c.Request.Header.Set(HeaderAuthorization, BearerPrefix+internalToken)
 	c.Request.Header.Set(HeaderOriginalIssuer, claims.Issuer)
+	setIdentity(c, claims)
 
 	return true
 }
 
+// setIdentity exposes the authenticated caller to later handlers, such as the
+// rate limiter, which keys its quotas on ContextKeyUserID and ContextKeyScopes.
+func setIdentity(c *gin.Context, claims *domain.ExternalClaims) {
+	c.Set(ContextKeyClaims, claims)
+	c.Set(ContextKeyUserID, claims.Subject)
+	c.Set(ContextKeyEmail, claims.Email)
+	c.Set(ContextKeyScopes, claims.Scopes)
+}
+
 func extractBearerToken(c *gin.Context) string {
 	auth := c.GetHeader(HeaderAuthorization)
 	if auth == "" {
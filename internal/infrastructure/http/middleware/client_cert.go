@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextKeyClientCertSubject holds the subject of the client certificate
+	// verified during the TLS handshake.
+	ContextKeyClientCertSubject = "client_cert_subject"
+
+	HeaderClientCertSubject = "X-Client-Cert-Subject"
+)
+
+// ClientCertificate exposes the verified client certificate subject to later
+// handlers and to upstreams. Any X-Client-Cert-Subject sent by the client is
+// dropped so upstreams can trust the header.
+func ClientCertificate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Header.Del(HeaderClientCertSubject)
+
+		if tlsState := c.Request.TLS; tlsState != nil && len(tlsState.VerifiedChains) > 0 && len(tlsState.VerifiedChains[0]) > 0 {
+			subject := tlsState.VerifiedChains[0][0].Subject.String()
+			c.Set(ContextKeyClientCertSubject, subject)
+			c.Request.Header.Set(HeaderClientCertSubject, subject)
+		}
+
+		c.Next()
+	}
+}
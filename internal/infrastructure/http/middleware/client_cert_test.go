@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCertificate_ExposesVerifiedSubject(t *testing.T) {
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "orders", Organization: []string{"acme"}}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/orders", nil)
+	c.Request.Header.Set(HeaderClientCertSubject, "CN=spoofed")
+	c.Request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+
+	ClientCertificate()(c)
+
+	assert.Equal(t, "CN=orders,O=acme", c.GetString(ContextKeyClientCertSubject))
+	assert.Equal(t, "CN=orders,O=acme", c.Request.Header.Get(HeaderClientCertSubject))
+}
+
+func TestClientCertificate_DropsSpoofedHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/orders", nil)
+	c.Request.Header.Set(HeaderClientCertSubject, "CN=spoofed")
+
+	ClientCertificate()(c)
+
+	_, exists := c.Get(ContextKeyClientCertSubject)
+	assert.False(t, exists)
+	assert.Empty(t, c.Request.Header.Get(HeaderClientCertSubject))
+}
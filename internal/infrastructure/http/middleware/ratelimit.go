@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,26 +12,55 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimitMiddleware creates a rate limiting middleware.
-func RateLimitMiddleware(limiter ratelimit.RateLimiter, cfg *config.Config) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		key, limit := determineKeyAndLimit(c, cfg)
+// QuotaLimiter enforces the gateway-wide quotas: the global limit shared by
+// every request, then a per-caller limit keyed on the authenticated user or,
+// for anonymous requests, the client IP, and finally a per-API-key limit when
+// the request carries one. It reads the identity set by AuthMiddleware, so it
+// must run after authentication. A limit of zero or less disables that quota.
+type QuotaLimiter struct {
+	limiter ratelimit.RateLimiter
+	cfg     *config.Config
+}
 
-		result, err := limiter.Allow(c.Request.Context(), key, limit)
-		if err != nil {
-			c.Next()
-			return
+func NewQuotaLimiter(limiter ratelimit.RateLimiter, cfg *config.Config) *QuotaLimiter {
+	return &QuotaLimiter{
+		limiter: limiter,
+		cfg:     cfg,
+	}
+}
+
+// Allow checks every quota that applies to the request and aborts with 429 on
+// the first one exceeded.
+func (q *QuotaLimiter) Allow(c *gin.Context) bool {
+	if limit := q.cfg.RateLimitGlobalRPM; limit > 0 {
+		if !AllowRequest(c, q.limiter, "ratelimit:global", limit, "the gateway is receiving too many requests, please try again later") {
+			return false
+		}
+	}
+
+	if key, limit := determineKeyAndLimit(c, q.cfg); limit > 0 {
+		if !AllowRequest(c, q.limiter, key, limit, "too many requests, please try again later") {
+			return false
 		}
+	}
 
-		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
-		c.Header("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+	if limit := q.cfg.RateLimitAPIKeyRPM; limit > 0 && q.cfg.RateLimitAPIKeyHeader != "" {
+		if apiKey := c.GetHeader(q.cfg.RateLimitAPIKeyHeader); apiKey != "" {
+			if !AllowRequest(c, q.limiter, "ratelimit:apikey:"+apiKeyFingerprint(apiKey), limit, "too many requests for this API key, please try again later") {
+				return false
+			}
+		}
+	}
 
-		if !result.Allowed {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": "too many requests, please try again later",
-			})
+	return true
+}
+
+// RateLimitMiddleware creates a rate limiting middleware. It must be
+// installed after the middleware that authenticates the caller.
+func RateLimitMiddleware(limiter ratelimit.RateLimiter, cfg *config.Config) gin.HandlerFunc {
+	quotas := NewQuotaLimiter(limiter, cfg)
+	return func(c *gin.Context) {
+		if !quotas.Allow(c) {
 			return
 		}
 
@@ -37,43 +68,80 @@ func RateLimitMiddleware(limiter ratelimit.RateLimiter, cfg *config.Config) gin.
 	}
 }
 
+// determineKeyAndLimit picks the per-caller quota. Authenticated users get
+// RateLimitUserRPM, raised to the highest RateLimitScopeRPM among their
+// scopes; anonymous requests are limited per IP.
 func determineKeyAndLimit(c *gin.Context, cfg *config.Config) (string, int) {
-	if userID, exists := c.Get("user_id"); exists {
-		return fmt.Sprintf("ratelimit:user:%v", userID), cfg.RateLimitUserRPM
+	if userID, exists := c.Get(ContextKeyUserID); exists {
+		limit := cfg.RateLimitUserRPM
+		for _, scope := range c.GetStringSlice(ContextKeyScopes) {
+			if scopeLimit := cfg.RateLimitScopeRPM[scope]; scopeLimit > limit {
+				limit = scopeLimit
+			}
+		}
+		return fmt.Sprintf("ratelimit:user:%v", userID), limit
+	}
+	// Callers without a token but with a verified client certificate are
+	// identified by its subject, so they do not share the limit of their IP.
+	if subject := c.GetString(ContextKeyClientCertSubject); subject != "" {
+		return fmt.Sprintf("ratelimit:cert:%s", subject), cfg.RateLimitUserRPM
 	}
 
 	clientIP := c.ClientIP()
 	return fmt.Sprintf("ratelimit:ip:%s", clientIP), cfg.RateLimitIPRPM
 }
 
+// apiKeyFingerprint keeps raw API keys out of the limiter store.
+func apiKeyFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:16])
+}
+
 // RouteRateLimitMiddleware creates a rate limiting middleware for specific routes.
 func RouteRateLimitMiddleware(limiter ratelimit.RateLimiter, routeLimit int) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var key string
-		if userID, exists := c.Get("user_id"); exists {
-			key = fmt.Sprintf("ratelimit:route:%s:user:%v", c.FullPath(), userID)
-		} else {
-			key = fmt.Sprintf("ratelimit:route:%s:ip:%s", c.FullPath(), c.ClientIP())
-		}
+		key := RouteRateLimitKey(c, c.FullPath())
 
-		result, err := limiter.Allow(c.Request.Context(), key, routeLimit)
-		if err != nil {
-			c.Next()
+		if !AllowRequest(c, limiter, key, routeLimit, "too many requests for this endpoint, please try again later") {
 			return
 		}
 
-		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
-		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
-		c.Header("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+		c.Next()
+	}
+}
 
-		if !result.Allowed {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": "too many requests for this endpoint, please try again later",
-			})
-			return
-		}
+// RouteRateLimitKey builds the limiter key for a route pattern, scoped to the
+// authenticated user when known, then to the client certificate subject and
+// to the client IP otherwise.
+func RouteRateLimitKey(c *gin.Context, route string) string {
+	if userID, exists := c.Get(ContextKeyUserID); exists {
+		return fmt.Sprintf("ratelimit:route:%s:user:%v", route, userID)
+	}
+	if subject := c.GetString(ContextKeyClientCertSubject); subject != "" {
+		return fmt.Sprintf("ratelimit:route:%s:cert:%s", route, subject)
+	}
+	return fmt.Sprintf("ratelimit:route:%s:ip:%s", route, c.ClientIP())
+}
 
-		c.Next()
+// AllowRequest checks key against limit, sets the X-RateLimit-* headers and
+// aborts with 429 when the limit is exceeded. Limiter errors fail open.
+func AllowRequest(c *gin.Context, limiter ratelimit.RateLimiter, key string, limit int, message string) bool {
+	result, err := limiter.Allow(c.Request.Context(), key, limit)
+	if err != nil {
+		return true
 	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+	if !result.Allowed {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":   "rate_limit_exceeded",
+			"message": message,
+		})
+		return false
+	}
+
+	return true
 }
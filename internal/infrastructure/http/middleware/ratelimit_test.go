@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -213,3 +214,116 @@ func TestRouteRateLimitMiddleware(t *testing.T) {
 		t.Errorf("/unlimited should not be affected, got %d", w.Code)
 	}
 }
+
+func TestRateLimitMiddleware_EnforcesGlobalLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := ratelimit.NewInMemoryLimiter()
+	cfg := &config.Config{
+		RateLimitGlobalRPM: 3,
+		RateLimitIPRPM:     100,
+	}
+
+	router := gin.New()
+	router.Use(RateLimitMiddleware(limiter, cfg))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = fmt.Sprintf("192.168.1.%d:12345", i+1)
+		router.ServeHTTP(w, req)
+
+		expected := http.StatusOK
+		if i == 3 {
+			expected = http.StatusTooManyRequests
+		}
+		if w.Code != expected {
+			t.Errorf("request %d from a new IP: expected %d, got %d", i, expected, w.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_ScopeQuotaRaisesUserLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := ratelimit.NewInMemoryLimiter()
+	cfg := &config.Config{
+		RateLimitUserRPM:  2,
+		RateLimitScopeRPM: map[string]int{"premium": 4, "read": 1},
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextKeyUserID, c.GetHeader("X-Test-User"))
+		if c.GetHeader("X-Test-User") == "premium-user" {
+			c.Set(ContextKeyScopes, []string{"read", "premium"})
+		}
+		c.Next()
+	})
+	router.Use(RateLimitMiddleware(limiter, cfg))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	allowed := func(user string) int {
+		count := 0
+		for i := 0; i < 10; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("X-Test-User", user)
+			router.ServeHTTP(w, req)
+			if w.Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := allowed("basic-user"); got != 2 {
+		t.Errorf("expected the default user quota of 2, got %d", got)
+	}
+	if got := allowed("premium-user"); got != 4 {
+		t.Errorf("expected the highest scope quota of 4, got %d", got)
+	}
+}
+
+func TestRateLimitMiddleware_EnforcesAPIKeyQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := ratelimit.NewInMemoryLimiter()
+	cfg := &config.Config{
+		RateLimitIPRPM:        100,
+		RateLimitAPIKeyRPM:    2,
+		RateLimitAPIKeyHeader: "X-API-Key",
+	}
+
+	router := gin.New()
+	router.Use(RateLimitMiddleware(limiter, cfg))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	send := func(apiKey string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-API-Key", apiKey)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := send("key-a"); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := send("key-a"); code != http.StatusTooManyRequests {
+		t.Errorf("expected key-a to be limited, got %d", code)
+	}
+	if code := send("key-b"); code != http.StatusOK {
+		t.Errorf("expected key-b to have its own quota, got %d", code)
+	}
+}
@@ -16,6 +16,11 @@ func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				// The proxy aborts this way when a response cannot be completed,
+				// e.g. on timeout mid-body; let net/http drop the connection.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
 				requestID, _ := c.Get("request_id")
 				traceID, _ := c.Get("trace_id")
 				slog.Error("panic recovered",
@@ -2,13 +2,16 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/infrastructure/certs"
 	"github.com/apascualco/gotway/internal/infrastructure/config"
+	"github.com/apascualco/gotway/internal/infrastructure/healthcheck"
 	"github.com/apascualco/gotway/internal/infrastructure/http/handler"
 	"github.com/apascualco/gotway/internal/infrastructure/http/middleware"
 	"github.com/apascualco/gotway/internal/infrastructure/jwt"
@@ -25,28 +28,99 @@ type Server struct {
 	httpServer     *http.Server
 	startTime      time.Time
 	registry       *application.Registry
+	registryCache  *application.CachedRepository
+	healthChecker  *application.HealthChecker
+	loadBalancer   *application.ServiceBalancer
+	breakers       *application.CircuitBreakers
 	jwtService     *jwt.Service
 	authMiddleware *middleware.AuthMiddleware
 	redisClient    *redis.Client
 	rateLimiter    ratelimit.RateLimiter
+	proxyHandler   *proxy.ProxyHandler
+	certReloader   *certs.Reloader
+	upstreamTLS    *tls.Config
+	upstreamCerts  *certs.Reloader
 	spanExporter   tracing.SpanExporter
 }
 
 func NewServer(cfg *config.Config) (*Server, error) {
-	slog.Debug("new application registry",
-		slog.Duration("heartbeat_ttl", cfg.HeartbeatTTL),
-		slog.Duration("health_check_interval", cfg.HealthCheckInterval),
-	)
-	registry := application.NewRegistry(application.RegistryConfig{
-		HeartbeatTTL:        cfg.HeartbeatTTL,
-		HealthCheckInterval: cfg.HealthCheckInterval,
+	var redisClient *redis.Client
+	if cfg.RedisURL != "" && (cfg.RateLimitEnabled || cfg.RateLimitRoutesEnabled || cfg.RegistryStore == config.RegistryStoreRedis) {
+		var err error
+		redisClient, err = redis.NewClient(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redis client: %w", err)
+		}
+	}
+
+	registry, registryCache, err := newRegistry(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamTLS, upstreamCerts, err := newUpstreamTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var healthChecker *application.HealthChecker
+	if cfg.HealthCheckEnabled {
+		healthConfig := application.HealthCheckConfig{
+			Interval:           cfg.HealthCheckInterval,
+			Timeout:            cfg.HealthCheckTimeout,
+			HealthyThreshold:   cfg.HealthCheckHealthyThreshold,
+			UnhealthyThreshold: cfg.HealthCheckUnhealthyThreshold,
+		}
+		if cfg.RegistryStore == config.RegistryStoreRedis {
+			healthConfig.Leader = redis.NewLock(redisClient, "healthcheck")
+		}
+		prober := healthcheck.NewHTTPProber(healthcheck.WithTLSConfig(upstreamTLS))
+		healthChecker = application.NewHealthChecker(registry, prober, healthConfig)
+		slog.Info("active health checking enabled", slog.Duration("interval", cfg.HealthCheckInterval))
+	}
+
+	serviceStrategies := make(map[string]application.BalancerStrategy, len(cfg.LBServiceStrategies))
+	for service, strategy := range cfg.LBServiceStrategies {
+		serviceStrategies[service] = application.BalancerStrategy(strategy)
+	}
+	defaultHashKey, err := application.ParseHashKey(cfg.LBHashKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LB_HASH_KEY: %w", err)
+	}
+	serviceHashKeys := make(map[string]application.HashKey, len(cfg.LBServiceHashKeys))
+	for service, raw := range cfg.LBServiceHashKeys {
+		hashKey, err := application.ParseHashKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LB_SERVICE_HASH_KEYS entry for %s: %w", service, err)
+		}
+		serviceHashKeys[service] = hashKey
+	}
+	loadBalancer, err := application.NewServiceBalancer(application.ServiceBalancerConfig{
+		DefaultStrategy:   application.BalancerStrategy(cfg.LBStrategy),
+		ServiceStrategies: serviceStrategies,
+		DefaultHashKey:    defaultHashKey,
+		ServiceHashKeys:   serviceHashKeys,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create load balancer: %w", err)
+	}
+
+	var breakers *application.CircuitBreakers
+	if cfg.CircuitBreakerEnabled {
+		breakers = application.NewCircuitBreakers(application.CircuitBreakerConfig{
+			ConsecutiveFailures: cfg.CircuitBreakerConsecutiveFailures,
+			FailureRatio:        cfg.CircuitBreakerFailureRatio,
+			MinRequests:         cfg.CircuitBreakerMinRequests,
+			Window:              cfg.CircuitBreakerWindow,
+			OpenTimeout:         cfg.CircuitBreakerOpenTimeout,
+			HalfOpenRequests:    cfg.CircuitBreakerHalfOpenRequests,
+		})
+	}
 
 	var jwtService *jwt.Service
 	var authMiddleware *middleware.AuthMiddleware
 
 	if cfg.JWTPublicKey != "" || cfg.JWTPrivateKey != "" {
-		var err error
 		jwtService, err = jwt.NewService(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create JWT service: %w", err)
@@ -57,24 +131,23 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		slog.Warn("JWT keys not configured, authentication disabled")
 	}
 
-	var redisClient *redis.Client
-	var rateLimiter ratelimit.RateLimiter
-
-	if cfg.RateLimitEnabled {
-		if cfg.RedisURL != "" {
-			var err error
-			redisClient, err = redis.NewClient(cfg.RedisURL)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create redis client: %w", err)
-			}
-			rateLimiter = ratelimit.NewLimiter(redisClient.Client)
-			slog.Info("rate limiting enabled with Redis")
-		} else {
-			rateLimiter = ratelimit.NewInMemoryLimiter()
-			slog.Warn("rate limiting enabled with in-memory limiter (not recommended for production)")
+	rateLimiter, err := newRateLimiter(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var certReloader *certs.Reloader
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		certReloader, err = certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, cfg.TLSReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
 		}
-	} else {
-		slog.Debug("rate limiting disabled")
+		slog.Info("tls enabled",
+			slog.Bool("client_ca", cfg.TLSClientCAFile != ""),
+			slog.Bool("require_client_cert", cfg.TLSRequireClientCert),
+		)
+	} else if cfg.TLSClientCAFile != "" {
+		return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
 	}
 
 	spanExporter := tracing.NewExporter(cfg)
@@ -83,16 +156,125 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		config:         cfg,
 		startTime:      time.Now(),
 		registry:       registry,
+		registryCache:  registryCache,
+		healthChecker:  healthChecker,
+		loadBalancer:   loadBalancer,
+		breakers:       breakers,
 		jwtService:     jwtService,
 		authMiddleware: authMiddleware,
 		redisClient:    redisClient,
 		rateLimiter:    rateLimiter,
+		certReloader:   certReloader,
+		upstreamTLS:    upstreamTLS,
+		upstreamCerts:  upstreamCerts,
 		spanExporter:   spanExporter,
 	}
 	s.setupRouter()
 	return s, nil
 }
 
+// newRateLimiter builds the limiter shared by the gateway-wide limits
+// (RATE_LIMIT_ENABLED) and the per-route limits services declare at
+// registration (RATE_LIMIT_ROUTES_ENABLED). It returns nil when both are off.
+func newRateLimiter(cfg *config.Config, redisClient *redis.Client) (ratelimit.RateLimiter, error) {
+	if !cfg.RateLimitEnabled && !cfg.RateLimitRoutesEnabled {
+		slog.Debug("rate limiting disabled")
+		return nil, nil
+	}
+
+	opts := ratelimit.Options{
+		Algorithm: ratelimit.Algorithm(cfg.RateLimitAlgorithm),
+		Window:    cfg.RateLimitWindow,
+		Burst:     cfg.RateLimitBurst,
+		MaxKeys:   cfg.RateLimitMemoryMaxKeys,
+	}
+	attrs := []any{
+		slog.Bool("global", cfg.RateLimitEnabled),
+		slog.Bool("routes", cfg.RateLimitRoutesEnabled),
+		slog.String("algorithm", string(opts.Algorithm)),
+		slog.Duration("window", opts.Window),
+	}
+
+	if redisClient == nil {
+		limiter, err := ratelimit.New(nil, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+		}
+		attrs = append(attrs, slog.Int("max_keys", opts.MaxKeys))
+		slog.Warn("rate limiting enabled with in-memory limiter (not recommended for production)", attrs...)
+		return limiter, nil
+	}
+
+	limiter, err := ratelimit.New(redisClient.Client, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+	}
+	slog.Info("rate limiting enabled with Redis", attrs...)
+	return limiter, nil
+}
+
+// newUpstreamTLSConfig builds the TLS configuration used to reach instances
+// registered with scheme https. It returns nil when nothing is configured, so
+// the system roots are trusted and no client certificate is presented.
+func newUpstreamTLSConfig(cfg *config.Config) (*tls.Config, *certs.Reloader, error) {
+	if cfg.UpstreamTLSCAFile == "" && cfg.UpstreamTLSCertFile == "" && cfg.UpstreamTLSKeyFile == "" {
+		return nil, nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.UpstreamTLSCAFile != "" {
+		pool, err := certs.LoadCertPool(cfg.UpstreamTLSCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load UPSTREAM_TLS_CA_FILE: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	var reloader *certs.Reloader
+	if cfg.UpstreamTLSCertFile != "" || cfg.UpstreamTLSKeyFile != "" {
+		var err error
+		reloader, err = certs.NewReloader(cfg.UpstreamTLSCertFile, cfg.UpstreamTLSKeyFile, "", cfg.TLSReloadInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load upstream client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	slog.Info("upstream tls configured",
+		slog.Bool("custom_ca", cfg.UpstreamTLSCAFile != ""),
+		slog.Bool("client_cert", reloader != nil),
+	)
+	return tlsConfig, reloader, nil
+}
+
+// newRegistry builds the registry for the configured store. Shared stores are
+// wrapped in a CachedRepository, which is returned so the caller can run it.
+func newRegistry(cfg *config.Config, redisClient *redis.Client) (*application.Registry, *application.CachedRepository, error) {
+	slog.Debug("new application registry",
+		slog.Duration("heartbeat_ttl", cfg.HeartbeatTTL),
+		slog.Duration("health_check_interval", cfg.HealthCheckInterval),
+		slog.String("store", cfg.RegistryStore),
+	)
+	registryConfig := application.RegistryConfig{
+		HeartbeatTTL:        cfg.HeartbeatTTL,
+		HealthCheckInterval: cfg.HealthCheckInterval,
+	}
+
+	switch cfg.RegistryStore {
+	case config.RegistryStoreMemory:
+		return application.NewRegistry(registryConfig), nil, nil
+	case config.RegistryStoreRedis:
+		if redisClient == nil {
+			return nil, nil, fmt.Errorf("REDIS_URL is required when REGISTRY_STORE is %q", config.RegistryStoreRedis)
+		}
+		slog.Info("registry backed by Redis", slog.Duration("sync_interval", cfg.RegistrySyncInterval))
+		cache := application.NewCachedRepository(redis.NewRepository(redisClient), cfg.RegistrySyncInterval)
+		return application.NewRegistryWithRepository(registryConfig, cache), cache, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown registry store %q", cfg.RegistryStore)
+	}
+}
+
 func (s *Server) setupRouter() {
 	if s.config.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -102,25 +284,27 @@ func (s *Server) setupRouter() {
 	s.router.Use(middleware.Logger())
 	s.router.Use(middleware.TraceMiddleware(middleware.NewW3CTraceProvider(), s.spanExporter))
 	s.router.Use(middleware.RequestID())
+	s.router.Use(middleware.ClientCertificate())
 	s.router.Use(middleware.CORS(middleware.CORSConfig{
 		AllowedOrigins: s.config.CORSAllowedOrigins,
 		AllowedMethods: s.config.CORSAllowedMethods,
 		AllowedHeaders: s.config.CORSAllowedHeaders,
 	}))
 
-	if s.rateLimiter != nil {
-		s.router.Use(middleware.RateLimitMiddleware(s.rateLimiter, s.config))
-	}
-
 	s.router.GET("/health", handler.HealthHandler(s.startTime, s.config.Version))
 	s.router.GET("/ready", handler.ReadyHandler())
 
 	s.setupRegistryRoutes()
+	s.setupAdminRoutes()
 	s.setupProxyRoute()
 }
 
 func (s *Server) setupRegistryRoutes() {
-	registryHandler := handler.NewRegistryHandler(s.registry)
+	var handlerOpts []handler.RegistryHandlerOption
+	if s.breakers != nil {
+		handlerOpts = append(handlerOpts, handler.WithCircuitBreakers(s.breakers))
+	}
+	registryHandler := handler.NewRegistryHandler(s.registry, handlerOpts...)
 
 	internal := s.router.Group("/internal/registry")
 	if s.jwtService != nil {
@@ -131,36 +315,145 @@ func (s *Server) setupRegistryRoutes() {
 		internal.POST("/register", registryHandler.Register)
 		internal.POST("/heartbeat", registryHandler.Heartbeat)
 		internal.POST("/deregister", registryHandler.Deregister)
+		internal.POST("/drain", registryHandler.Drain)
 		internal.GET("/services", registryHandler.ListServices)
+		internal.GET("/services/:service/traffic", registryHandler.GetTrafficPolicy)
+		internal.PUT("/services/:service/traffic", registryHandler.SetTrafficPolicy)
+		internal.POST("/services/:service/traffic/shift", registryHandler.ShiftTraffic)
+		internal.DELETE("/services/:service/traffic", registryHandler.DeleteTrafficPolicy)
+	}
+}
+
+// setupAdminRoutes mounts the operator API only when tokens can be verified;
+// it can deregister and reroute any service, so it is never left open.
+func (s *Server) setupAdminRoutes() {
+	if s.jwtService == nil {
+		slog.Warn("JWT keys not configured, admin API disabled")
+		return
+	}
+
+	adminHandler := handler.NewAdminHandler(s.registry, s.breakers)
+	registryHandler := handler.NewRegistryHandler(s.registry)
+
+	admin := s.router.Group("/admin")
+	admin.Use(middleware.NewAdminAuthMiddleware(s.jwtService, s.config.AdminScope).Authenticate())
+	{
+		admin.GET("/routes", adminHandler.ListRoutes)
+		admin.GET("/routes/table", adminHandler.RouteTable)
+		admin.GET("/instances", adminHandler.ListInstances)
+		admin.GET("/instances/:id", adminHandler.GetInstance)
+		admin.DELETE("/instances/:id", adminHandler.DeregisterInstance)
+		admin.POST("/instances/:id/drain", adminHandler.DrainInstance)
+		admin.PUT("/instances/:id/rotation", adminHandler.SetRotation)
+		admin.PUT("/instances/:id/weight", adminHandler.SetWeight)
+		admin.GET("/services/:service/traffic", registryHandler.GetTrafficPolicy)
+		admin.PUT("/services/:service/traffic", registryHandler.SetTrafficPolicy)
+		admin.POST("/services/:service/traffic/shift", registryHandler.ShiftTraffic)
+		admin.DELETE("/services/:service/traffic", registryHandler.DeleteTrafficPolicy)
 	}
 }
 
 func (s *Server) setupProxyRoute() {
-	loadBalancer := application.NewRoundRobinBalancer()
-	proxyHandler := proxy.NewProxyHandler(s.registry, loadBalancer, s.authMiddleware)
-	s.router.NoRoute(proxyHandler.Handle)
+	var opts []proxy.Option
+	if s.rateLimiter != nil && s.config.RateLimitRoutesEnabled {
+		opts = append(opts, proxy.WithRateLimiter(s.rateLimiter))
+	}
+	if s.breakers != nil {
+		opts = append(opts, proxy.WithCircuitBreakers(s.breakers))
+	}
+	opts = append(opts,
+		proxy.WithRetryBudget(application.NewRetryBudget(application.RetryBudgetConfig{
+			Ratio:               s.config.RetryBudgetRatio,
+			MinRetriesPerSecond: s.config.RetryBudgetMinPerSecond,
+			Window:              s.config.RetryBudgetWindow,
+		})),
+		proxy.WithMaxRetryBodyBytes(s.config.RetryMaxBodyBytes),
+		proxy.WithTimeouts(s.config.UpstreamTimeout, s.config.UpstreamMaxTimeout),
+		proxy.WithTransportConfig(proxy.TransportConfig{
+			MaxIdleConns:        s.config.UpstreamMaxIdleConns,
+			MaxIdleConnsPerHost: s.config.UpstreamMaxIdleConnsPerHost,
+			MaxConnsPerHost:     s.config.UpstreamMaxConnsPerHost,
+			IdleConnTimeout:     s.config.UpstreamIdleConnTimeout,
+			DialTimeout:         s.config.UpstreamDialTimeout,
+			KeepAlive:           s.config.UpstreamKeepAlive,
+			TLSHandshakeTimeout: s.config.UpstreamTLSHandshakeTimeout,
+			TLS:                 s.upstreamTLS,
+		}),
+		proxy.WithUpgradeIdleTimeout(s.config.UpstreamUpgradeIdleTimeout),
+		proxy.WithMirrorLimits(s.config.MirrorTimeout, s.config.MirrorMaxConcurrent),
+		proxy.WithZoneAffinity(application.ZoneAffinity{
+			Zone:         s.config.Zone,
+			MinInstances: s.config.ZoneMinInstances,
+		}),
+	)
+	if s.rateLimiter != nil && s.config.RateLimitEnabled {
+		opts = append(opts, proxy.WithQuotas(middleware.NewQuotaLimiter(s.rateLimiter, s.config)))
+	}
+	s.proxyHandler = proxy.NewProxyHandler(s.registry, s.loadBalancer, s.authMiddleware, opts...)
+	s.router.NoRoute(s.proxyHandler.Handle)
 }
 
 func (s *Server) Run() error {
+	if s.registryCache != nil {
+		s.registryCache.Start()
+	}
 	s.registry.Start()
+	if s.healthChecker != nil {
+		s.healthChecker.Start()
+	}
+	if s.upstreamCerts != nil {
+		s.upstreamCerts.Start()
+	}
 
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.config.Port),
-		Handler: s.router,
+		Addr:              fmt.Sprintf(":%d", s.config.Port),
+		Handler:           s.router,
+		ReadTimeout:       s.config.ServerReadTimeout,
+		ReadHeaderTimeout: s.config.ServerReadHeaderTimeout,
+		WriteTimeout:      s.config.ServerWriteTimeout,
+		IdleTimeout:       s.config.ServerIdleTimeout,
+		Protocols:         new(http.Protocols),
+	}
+	s.httpServer.Protocols.SetHTTP1(true)
+	s.httpServer.Protocols.SetHTTP2(true)
+	s.httpServer.Protocols.SetUnencryptedHTTP2(s.config.ServerH2CEnabled)
+	if s.certReloader == nil {
+		return s.httpServer.ListenAndServe()
 	}
-	return s.httpServer.ListenAndServe()
+
+	s.httpServer.TLSConfig = s.certReloader.ServerConfig(s.config.TLSRequireClientCert)
+	s.certReloader.Start()
+	return s.httpServer.ListenAndServeTLS("", "")
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.registry.Stop()
+	if s.healthChecker != nil {
+		s.healthChecker.Stop()
+	}
+	if s.registryCache != nil {
+		s.registryCache.Stop()
+	}
+	if s.certReloader != nil {
+		s.certReloader.Stop()
+	}
+	if s.upstreamCerts != nil {
+		s.upstreamCerts.Stop()
+	}
+	if stopper, ok := s.rateLimiter.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
 	if err := s.spanExporter.Shutdown(ctx); err != nil {
 		slog.Error("failed to shutdown span exporter", slog.String("error", err.Error()))
 	}
+	// The proxy and Redis are closed even when requests outlast ctx, so open
+	// tunnels and mirrored requests do not outlive the server.
+	err := s.httpServer.Shutdown(ctx)
+	s.proxyHandler.Shutdown(ctx)
 	if s.redisClient != nil {
-		err := s.redisClient.Close()
-		if err != nil {
-			return err
+		if closeErr := s.redisClient.Close(); err == nil {
+			err = closeErr
 		}
 	}
-	return s.httpServer.Shutdown(ctx)
+	return err
 }
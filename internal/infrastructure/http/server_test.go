@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/infrastructure/config"
+	"github.com/apascualco/gotway/internal/infrastructure/proxy"
+	"github.com/apascualco/gotway/internal/infrastructure/redis"
+	"github.com/apascualco/gotway/internal/infrastructure/tracing"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		global      bool
+		routes      bool
+		wantLimiter bool
+	}{
+		{"disabled", false, false, false},
+		{"routes only", false, true, true},
+		{"global only", true, false, true},
+		{"both", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := newRateLimiter(&config.Config{
+				RateLimitEnabled:       tt.global,
+				RateLimitRoutesEnabled: tt.routes,
+				RateLimitAlgorithm:     "sliding_window",
+				RateLimitWindow:        time.Minute,
+			}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (limiter != nil) != tt.wantLimiter {
+				t.Errorf("limiter = %v, want limiter: %v", limiter, tt.wantLimiter)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter_UnknownAlgorithm(t *testing.T) {
+	_, err := newRateLimiter(&config.Config{
+		RateLimitRoutesEnabled: true,
+		RateLimitAlgorithm:     "leaky_bucket",
+	}, nil)
+	if err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+}
+
+func TestSetupAdminRoutes_DisabledWithoutJWT(t *testing.T) {
+	s := &Server{
+		router:   gin.New(),
+		config:   &config.Config{AdminScope: "gateway:admin"},
+		registry: application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second}),
+	}
+	s.setupAdminRoutes()
+
+	for _, route := range s.router.Routes() {
+		if strings.HasPrefix(route.Path, "/admin") {
+			t.Errorf("expected no admin routes without JWT keys, got %s %s", route.Method, route.Path)
+		}
+	}
+}
+
+func TestShutdown_ClosesEverythingWhenRequestsOutlastDeadline(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	release := make(chan struct{})
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	started := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	go func() { _ = httpServer.Serve(listener) }()
+	go func() { _, _ = http.Get("http://" + listener.Addr().String()) }()
+	<-started
+
+	redisClient := &redis.Client{Client: goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:0"})}
+	s := &Server{
+		httpServer:   httpServer,
+		registry:     registry,
+		spanExporter: &tracing.NoopExporter{},
+		proxyHandler: proxy.NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil),
+		redisClient:  redisClient,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the HTTP shutdown error, got %v", err)
+	}
+	if err := redisClient.Ping(context.Background()).Err(); !errors.Is(err, goredis.ErrClosed) {
+		t.Errorf("expected redis to be closed despite the error, got %v", err)
+	}
+}
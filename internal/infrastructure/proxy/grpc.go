@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gRPC status codes the gateway answers with, from
+// https://grpc.github.io/grpc/core/md_doc_statuscodes.html.
+const (
+	grpcUnknown           = 2
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+// maxGRPCErrorBody bounds how much of an error body is kept to build
+// grpc-message.
+const maxGRPCErrorBody = 4 << 10
+
+// isGRPCContentType reports whether ct is application/grpc or one of its
+// subtypes such as application/grpc+proto. gRPC-Web is not included, as its
+// clients expect trailers in the body.
+func isGRPCContentType(ct string) bool {
+	if !strings.HasPrefix(ct, "application/grpc") {
+		return false
+	}
+	rest := ct[len("application/grpc"):]
+	return rest == "" || rest[0] == '+' || rest[0] == ';'
+}
+
+// grpcStatusFromHTTP maps the status of a gateway or non-gRPC upstream error
+// to the gRPC code a client would expect from the same failure.
+func grpcStatusFromHTTP(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return grpcInvalidArgument
+	case http.StatusUnauthorized:
+		return grpcUnauthenticated
+	case http.StatusForbidden:
+		return grpcPermissionDenied
+	case http.StatusNotFound:
+		return grpcUnimplemented
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return grpcResourceExhausted
+	case http.StatusInternalServerError:
+		return grpcInternal
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return grpcUnavailable
+	case http.StatusGatewayTimeout:
+		return grpcDeadlineExceeded
+	default:
+		return grpcUnknown
+	}
+}
+
+// grpcErrorWriter lets gRPC responses from the upstream through untouched and
+// turns every other response, such as the gateway's JSON errors, into a
+// trailers-only gRPC response carrying grpc-status and grpc-message, so
+// failures look native to gRPC clients.
+type grpcErrorWriter struct {
+	gin.ResponseWriter
+	status  int
+	decided bool
+	convert bool
+	body    bytes.Buffer
+}
+
+func newGRPCErrorWriter(w gin.ResponseWriter) *grpcErrorWriter {
+	return &grpcErrorWriter{ResponseWriter: w}
+}
+
+// converting decides, on the first write, whether the response is converted.
+func (w *grpcErrorWriter) converting() bool {
+	if !w.decided {
+		w.decided = true
+		w.convert = !isGRPCContentType(w.ResponseWriter.Header().Get("Content-Type"))
+		if !w.convert && w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	return w.convert
+}
+
+func (w *grpcErrorWriter) WriteHeader(code int) {
+	w.status = code
+	if w.decided && !w.convert {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *grpcErrorWriter) WriteHeaderNow() {
+	if !w.converting() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *grpcErrorWriter) Write(data []byte) (int, error) {
+	if !w.converting() {
+		return w.ResponseWriter.Write(data)
+	}
+	if room := maxGRPCErrorBody - w.body.Len(); room > 0 {
+		w.body.Write(data[:min(len(data), room)])
+	}
+	return len(data), nil
+}
+
+func (w *grpcErrorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *grpcErrorWriter) Flush() {
+	if !w.converting() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *grpcErrorWriter) Written() bool {
+	if w.decided && w.convert {
+		return true
+	}
+	return w.ResponseWriter.Written()
+}
+
+// finish writes the converted response, if any. It must run once the handler
+// is done writing.
+func (w *grpcErrorWriter) finish() {
+	if !w.decided && w.status == 0 {
+		return
+	}
+	if !w.converting() {
+		return
+	}
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	code := grpcStatusFromHTTP(status)
+	message := http.StatusText(status)
+
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(w.body.Bytes(), &payload) == nil {
+		switch {
+		case payload.Message != "":
+			message = payload.Message
+		case payload.Error != "":
+			message = payload.Error
+		}
+	}
+	if status == http.StatusOK {
+		message = fmt.Sprintf("unexpected content type %q", w.ResponseWriter.Header().Get("Content-Type"))
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/grpc")
+	header.Set("Grpc-Status", strconv.Itoa(code))
+	header.Set("Grpc-Message", encodeGRPCMessage(message))
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// encodeGRPCMessage percent-encodes message as grpc-message requires.
+func encodeGRPCMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func newH2CServer(handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.Protocols = &http.Protocols{}
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	return server
+}
+
+func newH2CClient() *http.Client {
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+}
+
+func setupGRPCGateway(t *testing.T, backend http.Handler) *httptest.Server {
+	t.Helper()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil).Handle)
+	gateway := newH2CServer(router)
+	t.Cleanup(gateway.Close)
+
+	if backend != nil {
+		upstream := newH2CServer(backend)
+		t.Cleanup(upstream.Close)
+
+		host, port := parseHostPort(upstream.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "echo-grpc",
+			Host:        host,
+			Port:        port,
+			Protocol:    domain.ProtocolGRPC,
+			BasePath:    "/echo.v1.Echo",
+			Routes:      []domain.Route{{Method: "POST", Path: "/Say"}},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+	return gateway
+}
+
+func grpcCall(t *testing.T, gateway *httptest.Server, path string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, gateway.URL+path, bytes.NewReader([]byte{0, 0, 0, 0, 2, 'h', 'i'}))
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	resp, err := newH2CClient().Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestProxy_GRPCForwardsOverHTTP2WithTrailers(t *testing.T) {
+	gateway := setupGRPCGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Te") != "trailers" {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "")
+	}))
+
+	resp, body := grpcCall(t, gateway, "/echo.v1.Echo/Say")
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !bytes.Equal(body, []byte{0, 0, 0, 0, 2, 'h', 'i'}) {
+		t.Errorf("expected the message echoed, got %v", body)
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("expected grpc-status trailer 0, got %q", got)
+	}
+}
+
+func TestProxy_GRPCErrorsMapToGRPCStatus(t *testing.T) {
+	gateway := setupGRPCGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("overloaded"))
+	}))
+
+	tests := []struct {
+		path       string
+		wantStatus string
+	}{
+		{"/echo.v1.Echo/Unknown", "12"},
+		{"/echo.v1.Echo/Say", "14"},
+	}
+	for _, tt := range tests {
+		resp, body := grpcCall(t, gateway, tt.path)
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: gRPC errors should be sent with HTTP 200, got %d", tt.path, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/grpc" {
+			t.Errorf("%s: expected application/grpc, got %q", tt.path, ct)
+		}
+		if got := resp.Header.Get("Grpc-Status"); got != tt.wantStatus {
+			t.Errorf("%s: expected grpc-status %s, got %q", tt.path, tt.wantStatus, got)
+		}
+		if resp.Header.Get("Grpc-Message") == "" {
+			t.Errorf("%s: expected a grpc-message", tt.path)
+		}
+		if len(body) != 0 {
+			t.Errorf("%s: expected a trailers-only response, got body %q", tt.path, body)
+		}
+	}
+}
+
+func TestEncodeGRPCMessage(t *testing.T) {
+	if got := encodeGRPCMessage("no route: 100% ünknown"); got != "no route: 100%25 %C3%BCnknown" {
+		t.Errorf("unexpected encoding %q", got)
+	}
+}
@@ -1,6 +1,10 @@
 package proxy
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,7 +61,7 @@ func TestMatchRoute_Exact(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := MatchRoute(registry, tt.method, tt.path)
+			result := MatchRoute(registry, httptest.NewRequest(tt.method, tt.path, nil))
 			if result == nil {
 				t.Fatalf("expected match for %s %s, got nil", tt.method, tt.path)
 			}
@@ -103,7 +107,7 @@ func TestMatchRoute_WithParams(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := MatchRoute(registry, tt.method, tt.path)
+			result := MatchRoute(registry, httptest.NewRequest(tt.method, tt.path, nil))
 			if result == nil {
 				t.Fatalf("expected match for %s %s, got nil", tt.method, tt.path)
 			}
@@ -135,7 +139,7 @@ func TestMatchRoute_NotFound(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := MatchRoute(registry, tt.method, tt.path)
+			result := MatchRoute(registry, httptest.NewRequest(tt.method, tt.path, nil))
 			if result != nil {
 				t.Errorf("expected no match for %s %s, got %v", tt.method, tt.path, result.Entry.ServiceName)
 			}
@@ -143,6 +147,53 @@ func TestMatchRoute_NotFound(t *testing.T) {
 	}
 }
 
+func TestMatchRoute_HostAndHeaderConditions(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	for _, req := range []*domain.RegisterRequest{
+		{ServiceName: "api", BasePath: "/v1", Routes: []domain.Route{{Method: "GET", Path: "/users", Hosts: []string{"api.example.com"}}}},
+		{ServiceName: "admin", BasePath: "/v1", Routes: []domain.Route{{Method: "GET", Path: "/users", Hosts: []string{"admin.example.com"}}}},
+		{ServiceName: "api-v2", BasePath: "/v1", Routes: []domain.Route{{Method: "GET", Path: "/users", Hosts: []string{"api.example.com"},
+			Headers: []domain.MatchPredicate{{Name: "X-Api-Version", Value: "2"}}}}},
+	} {
+		req.Host, req.Port = "localhost", 8080
+		if _, err := registry.Register(req); err != nil {
+			t.Fatalf("register %s failed: %v", req.ServiceName, err)
+		}
+	}
+
+	tests := []struct {
+		host        string
+		version     string
+		wantService string
+	}{
+		{"api.example.com", "", "api"},
+		{"admin.example.com:8443", "", "admin"},
+		{"api.example.com", "2", "api-v2"},
+		{"other.example.com", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+" "+tt.version, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/users", nil)
+			req.Host = tt.host
+			if tt.version != "" {
+				req.Header.Set("X-Api-Version", tt.version)
+			}
+
+			result := MatchRoute(registry, req)
+			if tt.wantService == "" {
+				if result != nil {
+					t.Errorf("expected no match, got %s", result.Entry.ServiceName)
+				}
+				return
+			}
+			if result == nil || result.Entry.ServiceName != tt.wantService {
+				t.Errorf("expected %s, got %v", tt.wantService, result)
+			}
+		})
+	}
+}
+
 func TestMatchPathWithParams(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -209,3 +260,118 @@ func TestMatchPathWithParams(t *testing.T) {
 		})
 	}
 }
+
+// linearMatchRoute is the matcher the compiled route table replaced, kept as
+// the baseline for the benchmarks below: it scans every route on each
+// request.
+func linearMatchRoute(registry *application.Registry, r *http.Request) *MatchResult {
+	method, path := r.Method, r.URL.Path
+	routes := registry.GetAllRoutes()
+
+	if entry, exists := routes[method+":"+path]; exists {
+		return &MatchResult{Entry: entry}
+	}
+	for key, entry := range routes {
+		routeMethod, routePath, ok := strings.Cut(key, ":")
+		if !ok || routeMethod != method {
+			continue
+		}
+		if params, ok := matchPathWithParams(routePath, path); ok {
+			return &MatchResult{Entry: entry, Params: params}
+		}
+	}
+	return nil
+}
+
+func matchPathWithParams(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(patternSegments) != len(pathSegments) {
+		if len(patternSegments) > 0 && patternSegments[len(patternSegments)-1] == "*" {
+			if len(pathSegments) < len(patternSegments)-1 {
+				return nil, false
+			}
+		} else {
+			return nil, false
+		}
+	}
+
+	params := make(map[string]string)
+
+	for i, patternSeg := range patternSegments {
+		if patternSeg == "*" {
+			remaining := strings.Join(pathSegments[i:], "/")
+			params["*"] = remaining
+			return params, true
+		}
+
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+
+		pathSeg := pathSegments[i]
+
+		if strings.HasPrefix(patternSeg, ":") {
+			paramName := patternSeg[1:]
+			params[paramName] = pathSeg
+			continue
+		}
+
+		if patternSeg != pathSeg {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// setupBenchmarkRegistry registers services services, each with a static and
+// a parameterised route, the shape of a gateway fronting many services.
+func setupBenchmarkRegistry(b *testing.B, services int) *application.Registry {
+	b.Helper()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	for i := 0; i < services; i++ {
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: fmt.Sprintf("service-%d", i),
+			Host:        "localhost",
+			Port:        8080,
+			BasePath:    fmt.Sprintf("/api/v1/service-%d", i),
+			Routes: []domain.Route{
+				{Method: "GET", Path: "/items"},
+				{Method: "GET", Path: "/items/:id/details"},
+			},
+		})
+		if err != nil {
+			b.Fatalf("register failed: %v", err)
+		}
+	}
+	return registry
+}
+
+func benchmarkMatch(b *testing.B, match func(*application.Registry, *http.Request) *MatchResult) {
+	for _, services := range []int{10, 100, 500} {
+		registry := setupBenchmarkRegistry(b, services)
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/service-%d/items/42/details", services-1), nil)
+
+		b.Run(fmt.Sprintf("routes=%d", 2*services), func(b *testing.B) {
+			// The first lookup compiles the route table.
+			match(registry, req)
+			b.ReportAllocs()
+			for b.Loop() {
+				if match(registry, req) == nil {
+					b.Fatal("expected a match")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMatchRoute(b *testing.B) {
+	benchmarkMatch(b, MatchRoute)
+}
+
+func BenchmarkMatchRoute_LinearScan(b *testing.B) {
+	benchmarkMatch(b, linearMatchRoute)
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderMirroredFrom marks the copies of requests sent to a shadow service,
+// naming the service that serves the original.
+const HeaderMirroredFrom = "X-Mirrored-From"
+
+const (
+	defaultMirrorTimeout       = 5 * time.Second
+	defaultMirrorMaxConcurrent = 100
+)
+
+// mirrorDispatcher sends the copies of requests in the background, each
+// bounded by timeout. Copies beyond maxConcurrent in flight are dropped
+// rather than queued, so mirroring never holds back the original requests.
+type mirrorDispatcher struct {
+	timeout time.Duration
+	slots   chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newMirrorDispatcher(timeout time.Duration, maxConcurrent int) *mirrorDispatcher {
+	if timeout <= 0 {
+		timeout = defaultMirrorTimeout
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMirrorMaxConcurrent
+	}
+	return &mirrorDispatcher{
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrent),
+	}
+}
+
+// WithMirrorLimits bounds the copies sent by route mirror policies: each
+// may take up to timeout, and at most maxConcurrent are in flight.
+func WithMirrorLimits(timeout time.Duration, maxConcurrent int) Option {
+	return func(p *ProxyHandler) {
+		p.mirrors = newMirrorDispatcher(timeout, maxConcurrent)
+	}
+}
+
+// sampleMirror reports whether the request should be copied under policy.
+func sampleMirror(policy *domain.MirrorPolicy) bool {
+	return policy != nil && rand.IntN(100) < policy.Percent
+}
+
+// mirror sends a copy of the request, with body, to an instance of the
+// route's mirror target. The copy is prepared before returning, as c is not
+// valid once the request is served; it is sent in the background and its
+// response and failures are discarded.
+func (p *ProxyHandler) mirror(c *gin.Context, entry *domain.RouteEntry, body []byte) {
+	policy := entry.Route.Mirror
+	target := policy.Target(entry.ServiceName)
+
+	instances := p.registry.GetHealthyInstances(target)
+	if policy.Version != "" {
+		instances = slices.DeleteFunc(slices.Clone(instances), func(instance *domain.ServiceInstance) bool {
+			return instance.Version != policy.Version
+		})
+	}
+	if len(instances) == 0 {
+		slog.Debug("no instance to mirror to", "service", target, "version", policy.Version)
+		return
+	}
+
+	select {
+	case p.mirrors.slots <- struct{}{}:
+	default:
+		slog.Debug("dropping mirrored request, too many in flight", "service", target)
+		return
+	}
+
+	instance := p.loadBalancer.Select(instances)
+	if instance == nil {
+		<-p.mirrors.slots
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.mirrors.timeout)
+	req := c.Request.Clone(ctx)
+	req.RequestURI = ""
+	req.Body = http.NoBody
+	req.ContentLength = int64(len(body))
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	p.director(c, entry, instance)(req)
+	req.Header.Set(HeaderMirroredFrom, entry.ServiceName)
+	req.Header.Set("X-Forwarded-Service", target)
+	transport := p.transports.forInstance(instance)
+
+	p.mirrors.wg.Go(func() {
+		defer func() { <-p.mirrors.slots }()
+		defer cancel()
+
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			slog.Debug("mirrored request failed", "service", target, "instance_id", instance.ID, "error", err)
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	})
+}
+
+// wait blocks until the copies in flight are done or ctx ends.
+func (m *mirrorDispatcher) wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
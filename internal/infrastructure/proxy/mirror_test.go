@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type mirroredRequest struct {
+	path       string
+	body       string
+	mirroredOf string
+}
+
+func setupMirrorGateway(t *testing.T, primary, shadow http.Handler, mirror *domain.MirrorPolicy, opts ...Option) *httptest.Server {
+	t.Helper()
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	router := gin.New()
+	proxyHandler := NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil, opts...)
+	router.NoRoute(proxyHandler.Handle)
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+
+	for _, svc := range []struct {
+		name    string
+		handler http.Handler
+		mirror  *domain.MirrorPolicy
+	}{
+		{"orders", primary, mirror},
+		{"orders-shadow", shadow, nil},
+	} {
+		backend := httptest.NewServer(svc.handler)
+		t.Cleanup(backend.Close)
+		host, port := parseHostPort(backend.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: svc.name,
+			Host:        host,
+			Port:        port,
+			BasePath:    "/" + svc.name,
+			Routes:      []domain.Route{{Method: "POST", Path: "/items", Mirror: svc.mirror}},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+	return gateway
+}
+
+func TestProxy_MirrorsRequestsToShadowService(t *testing.T) {
+	mirrored := make(chan mirroredRequest, 1)
+	var primaryMarker string
+	primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryMarker = r.Header.Get(HeaderMirroredFrom)
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte("primary:"), body...))
+	})
+	shadow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrored <- mirroredRequest{path: r.URL.Path, body: string(body), mirroredOf: r.Header.Get(HeaderMirroredFrom)}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	gateway := setupMirrorGateway(t, primary, shadow, &domain.MirrorPolicy{Service: "orders-shadow", Percent: 100})
+
+	req, _ := http.NewRequest("POST", gateway.URL+"/orders/items", strings.NewReader(`{"sku":"42"}`))
+	req.Header.Set(HeaderMirroredFrom, "spoofed")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != `primary:{"sku":"42"}` {
+		t.Errorf("expected the primary response despite the shadow failing, got %d %s", resp.StatusCode, body)
+	}
+	if primaryMarker != "" {
+		t.Errorf("expected the client's %s header to be stripped, got %q", HeaderMirroredFrom, primaryMarker)
+	}
+
+	select {
+	case got := <-mirrored:
+		if got.body != `{"sku":"42"}` {
+			t.Errorf("expected the copy to carry the body, got %q", got.body)
+		}
+		if got.mirroredOf != "orders" {
+			t.Errorf("expected %s: orders, got %q", HeaderMirroredFrom, got.mirroredOf)
+		}
+		if got.path != "/orders/items" {
+			t.Errorf("expected the copy on the original path, got %s", got.path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the shadow service to receive a copy")
+	}
+}
+
+func TestProxy_SlowMirrorDoesNotDelayPrimary(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	received := make(chan struct{}, 10)
+	primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	shadow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	})
+	gateway := setupMirrorGateway(t, primary, shadow, &domain.MirrorPolicy{Service: "orders-shadow", Percent: 100},
+		WithMirrorLimits(time.Minute, 1))
+
+	for range 3 {
+		start := time.Now()
+		resp, err := http.Post(gateway.URL+"/orders/items", "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || time.Since(start) > time.Second {
+			t.Fatalf("expected a prompt primary response, got %d after %s", resp.StatusCode, time.Since(start))
+		}
+	}
+
+	<-received
+	select {
+	case <-received:
+		t.Error("expected copies beyond the concurrency cap to be dropped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
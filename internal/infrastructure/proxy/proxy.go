@@ -1,16 +1,31 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
 	"github.com/apascualco/gotway/internal/infrastructure/http/middleware"
+	"github.com/apascualco/gotway/internal/infrastructure/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
+// HeaderRequestTimeout carries the milliseconds left before the gateway stops
+// waiting for the upstream response.
+const HeaderRequestTimeout = "X-Request-Timeout"
+
 var hopByHopHeaders = []string{
 	"Connection",
 	"Keep-Alive",
@@ -26,18 +41,134 @@ type ProxyHandler struct {
 	registry       *application.Registry
 	loadBalancer   application.LoadBalancer
 	authMiddleware *middleware.AuthMiddleware
+	rateLimiter    ratelimit.RateLimiter
+	quotas         *middleware.QuotaLimiter
+	breakers       *application.CircuitBreakers
+	retryBudget    *application.RetryBudget
+	maxRetryBody   int64
+	defaultTimeout time.Duration
+	maxTimeout     time.Duration
+	transports     *transportPool
+	buffers        *bufferPool
+	rewrites       *regexCache
+	zones          application.ZoneAffinity
+	mirrors        *mirrorDispatcher
+
+	upgradeIdleTimeout time.Duration
+	tunnels            *tunnelSet
+}
+
+type Option func(*ProxyHandler)
+
+// WithRateLimiter enforces the RateLimit declared by each matched route.
+func WithRateLimiter(limiter ratelimit.RateLimiter) Option {
+	return func(p *ProxyHandler) {
+		p.rateLimiter = limiter
+	}
+}
+
+// WithQuotas enforces the gateway-wide quotas once the caller is
+// authenticated, so they can be keyed on the user rather than the IP.
+func WithQuotas(quotas *middleware.QuotaLimiter) Option {
+	return func(p *ProxyHandler) {
+		p.quotas = quotas
+	}
+}
+
+// WithCircuitBreakers stops sending traffic to instances whose breaker is open
+// and feeds every upstream outcome back into it.
+func WithCircuitBreakers(breakers *application.CircuitBreakers) Option {
+	return func(p *ProxyHandler) {
+		p.breakers = breakers
+	}
+}
+
+// WithRetryBudget bounds the retries declared by route retry policies.
+// Without it retries are limited only by each policy's max_attempts.
+func WithRetryBudget(budget *application.RetryBudget) Option {
+	return func(p *ProxyHandler) {
+		p.retryBudget = budget
+	}
+}
+
+// WithMaxRetryBodyBytes sets how much of a request body is buffered so it can
+// be replayed on retry or mirrored. Larger requests are sent once.
+func WithMaxRetryBodyBytes(limit int64) Option {
+	return func(p *ProxyHandler) {
+		p.maxRetryBody = limit
+	}
 }
 
-func NewProxyHandler(registry *application.Registry, lb application.LoadBalancer, auth *middleware.AuthMiddleware) *ProxyHandler {
-	return &ProxyHandler{
+// WithTimeouts bounds each proxied request by its route's timeout_ms, or
+// defaultTimeout when the route declares none, capped at maxTimeout. Zero
+// values leave the corresponding bound off.
+func WithTimeouts(defaultTimeout, maxTimeout time.Duration) Option {
+	return func(p *ProxyHandler) {
+		p.defaultTimeout = defaultTimeout
+		p.maxTimeout = maxTimeout
+	}
+}
+
+// WithTransportConfig tunes the connection pools kept to upstream services.
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(p *ProxyHandler) {
+		p.transports = newTransportPool(cfg)
+	}
+}
+
+// WithZoneAffinity prefers the instances in the gateway's zone while enough
+// of them are available.
+func WithZoneAffinity(affinity application.ZoneAffinity) Option {
+	return func(p *ProxyHandler) {
+		p.zones = affinity
+	}
+}
+
+func NewProxyHandler(registry *application.Registry, lb application.LoadBalancer, auth *middleware.AuthMiddleware, opts ...Option) *ProxyHandler {
+	p := &ProxyHandler{
 		registry:       registry,
 		loadBalancer:   lb,
 		authMiddleware: auth,
+		maxRetryBody:   defaultMaxRetryBodyBytes,
+		transports:     newTransportPool(TransportConfig{}),
+		buffers:        newBufferPool(),
+		rewrites:       &regexCache{},
+		mirrors:        newMirrorDispatcher(0, 0),
+		tunnels:        newTunnelSet(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Close releases the idle upstream connections.
+func (p *ProxyHandler) Close() {
+	p.transports.closeIdle()
+}
+
+// Shutdown stops accepting upgrade requests and waits for the tunnelled
+// connections and mirrored requests to end until ctx is done, closing the
+// connections still open, then releases the idle upstream connections.
+func (p *ProxyHandler) Shutdown(ctx context.Context) {
+	if closed := p.tunnels.shutdown(ctx); closed > 0 {
+		slog.Warn("closed upgraded connections still open at shutdown", slog.Int("connections", closed))
 	}
+	p.mirrors.wait(ctx)
+	p.Close()
 }
 
 func (p *ProxyHandler) Handle(c *gin.Context) {
-	match := MatchRoute(p.registry, c.Request.Method, c.Request.URL.Path)
+	if isGRPCContentType(c.GetHeader("Content-Type")) {
+		writer := newGRPCErrorWriter(c.Writer)
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+	}
+
+	match := MatchRoute(p.registry, c.Request)
 	if match == nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "route_not_found",
@@ -45,6 +176,7 @@ func (p *ProxyHandler) Handle(c *gin.Context) {
 		})
 		return
 	}
+	c.Set(contextKeyPathParams, match.Params)
 
 	if p.authMiddleware != nil {
 		if !p.authMiddleware.AuthenticateRequest(c, match.Entry, match.Entry.ServiceName) {
@@ -52,7 +184,26 @@ func (p *ProxyHandler) Handle(c *gin.Context) {
 		}
 	}
 
+	if p.quotas != nil && !p.quotas.Allow(c) {
+		return
+	}
+
+	if p.rateLimiter != nil && match.Entry.Route.RateLimit > 0 {
+		key := middleware.RouteRateLimitKey(c, match.Entry.Route.Key(match.Entry.BasePath))
+		if !middleware.AllowRequest(c, p.rateLimiter, key, match.Entry.Route.RateLimit,
+			"too many requests for this endpoint, please try again later") {
+			return
+		}
+	}
+
 	instances := p.registry.GetHealthyInstances(match.Entry.ServiceName)
+	instances = application.FilterBySelector(instances, match.Entry.Route.InstanceSelector)
+	if p.breakers != nil {
+		instances = p.breakers.Filter(instances)
+	}
+	traffic := p.registry.TrafficPolicy(match.Entry.ServiceName)
+	instances = application.SplitTraffic(traffic, c.Request, instances, rand.Float64())
+	instances = p.zones.Prefer(instances)
 	if len(instances) == 0 {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error":   "service_unavailable",
@@ -62,82 +213,296 @@ func (p *ProxyHandler) Handle(c *gin.Context) {
 		return
 	}
 
-	instance := p.loadBalancer.Select(instances)
-	if instance == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "service_unavailable",
-			"message": "failed to select instance",
-		})
+	if match.Entry.Route.Streaming && isUpgradeRequest(c.Request) {
+		p.upgrade(c, match.Entry, instances)
 		return
 	}
 
-	targetURL := &url.URL{
-		Scheme: "http",
-		Host:   instance.Address(),
+	if timeout := p.routeTimeout(match.Entry.Route); timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
 	}
 
-	proxy := &httputil.ReverseProxy{
-		Director: func(req *http.Request) {
-			req.URL.Scheme = targetURL.Scheme
-			req.URL.Host = targetURL.Host
-			req.Host = targetURL.Host
+	serviceName := match.Entry.ServiceName
+	if p.retryBudget != nil {
+		p.retryBudget.Request(serviceName)
+	}
 
-			req.URL.Path = c.Request.URL.Path
+	policy := match.Entry.Route.Retry
+	if policy != nil && !policy.AppliesTo(c.Request.Method) {
+		policy = nil
+	}
+	mirror := sampleMirror(match.Entry.Route.Mirror)
 
-			if c.Request.URL.RawQuery != "" {
-				req.URL.RawQuery = c.Request.URL.RawQuery
-			}
+	var body []byte
+	if policy != nil || mirror {
+		var replayable bool
+		var err error
+		body, replayable, err = p.bufferBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "failed to read request body",
+			})
+			return
+		}
+		if !replayable {
+			policy, mirror = nil, false
+		}
+	}
+	if mirror {
+		p.mirror(c, match.Entry, body)
+	}
 
-			for _, h := range hopByHopHeaders {
-				req.Header.Del(h)
+	var failure *attemptFailure
+	for attempt := 1; ; attempt++ {
+		instance := p.acquireInstance(c, instances)
+		if instance == nil {
+			if failure != nil {
+				failure.write(c)
+				return
 			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "service_unavailable",
+				"message": "failed to select instance",
+			})
+			return
+		}
 
-			clientIP := c.ClientIP()
-			if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
-				clientIP = prior + ", " + clientIP
-			}
-			req.Header.Set("X-Forwarded-For", clientIP)
+		retryable := policy != nil && attempt < policy.MaxAttempts
+		failure = p.forward(c, match.Entry, instance, policy, body, retryable)
+		if failure == nil {
+			return
+		}
 
-			if c.Request.Host != "" {
-				req.Header.Set("X-Forwarded-Host", c.Request.Host)
+		// Retries go to an instance that has not failed this request yet.
+		instances = slices.DeleteFunc(slices.Clone(instances), func(candidate *domain.ServiceInstance) bool {
+			return candidate.ID == instance.ID
+		})
+		if len(instances) == 0 || (p.retryBudget != nil && !p.retryBudget.TryRetry(serviceName)) {
+			failure.write(c)
+			return
+		}
+		if !waitBackoff(c.Request.Context(), policy, attempt) {
+			if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+				failure = &attemptFailure{err: errRequestTimeout}
 			}
+			failure.write(c)
+			return
+		}
+	}
+}
+
+// forward proxies one attempt to instance, replaying body when the request
+// body was buffered. When retryable is set, a failure the policy retries is
+// not written to the client but returned, so the caller can try another
+// instance or write it once it gives up.
+func (p *ProxyHandler) forward(c *gin.Context, entry *domain.RouteEntry, instance *domain.ServiceInstance, policy *domain.RetryPolicy, body []byte, retryable bool) *attemptFailure {
+	ctx, cancel := context.WithCancelCause(c.Request.Context())
+	defer cancel(nil)
+
+	stopTimer := func() bool { return true }
+	if policy != nil && policy.PerTryTimeoutMs > 0 {
+		timer := time.AfterFunc(time.Duration(policy.PerTryTimeoutMs)*time.Millisecond, func() {
+			cancel(errPerTryTimeout)
+		})
+		stopTimer = timer.Stop
+		defer timer.Stop()
+	}
+
+	var failure *attemptFailure
+	var outcome upstreamOutcome
+	if p.breakers != nil {
+		defer func() { p.recordOutcome(instance, outcome) }()
+	}
 
-			proto := "http"
-			if c.Request.TLS != nil {
-				proto = "https"
+	proxy := &httputil.ReverseProxy{
+		Director:   p.director(c, entry, instance),
+		Transport:  p.transports.forInstance(instance),
+		BufferPool: p.buffers,
+		ModifyResponse: func(resp *http.Response) error {
+			stopTimer()
+			outcome.status = resp.StatusCode
+			if retryable && policy.RetriesStatus(resp.StatusCode) {
+				failure = newStatusFailure(resp)
+				return errRetryableStatus
 			}
-			if forwardedProto := c.GetHeader("X-Forwarded-Proto"); forwardedProto != "" {
-				proto = forwardedProto
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, errRetryableStatus) {
+				return
 			}
-			req.Header.Set("X-Forwarded-Proto", proto)
 
-			if requestID := c.GetString("request_id"); requestID != "" {
-				req.Header.Set("X-Request-ID", requestID)
+			condition := domain.RetryOnConnectFailure
+			switch {
+			case context.Cause(ctx) == errPerTryTimeout:
+				condition = domain.RetryOnTimeout
+				err = errPerTryTimeout
+			case errors.Is(c.Request.Context().Err(), context.DeadlineExceeded):
+				err = errRequestTimeout
+			}
+			outcome.err = err
+			if retryable && c.Request.Context().Err() == nil && policy.RetriesOn(condition) {
+				failure = &attemptFailure{err: err}
+				return
 			}
+			(&attemptFailure{err: err}).write(c)
+		},
+	}
+
+	if tracker, ok := p.loadBalancer.(application.RequestTracker); ok {
+		tracker.Begin(instance)
+		defer tracker.End(instance)
+	}
+
+	if body != nil {
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	return failure
+}
+
+func (p *ProxyHandler) director(c *gin.Context, entry *domain.RouteEntry, instance *domain.ServiceInstance) func(req *http.Request) {
+	targetURL := &url.URL{
+		Scheme: instance.URLScheme(),
+		Host:   instance.Address(),
+	}
+
+	return func(req *http.Request) {
+		req.URL.Scheme = targetURL.Scheme
+		req.URL.Host = targetURL.Host
+		req.Host = targetURL.Host
+
+		req.URL.Path = c.Request.URL.Path
+		params := c.GetStringMapString(contextKeyPathParams)
+		if entry.Route.Rewrite != nil {
+			req.URL.Path = p.rewritePath(entry, c.Request.URL.Path, params)
+			req.URL.RawPath = ""
+		}
+		setPathParamHeaders(req.Header, entry.Route, params)
+		req.Header.Del(HeaderMirroredFrom)
+
+		if c.Request.URL.RawQuery != "" {
+			req.URL.RawQuery = c.Request.URL.RawQuery
+		}
+
+		// Tell the upstream how long the gateway will wait, so it can give up
+		// on work nobody will receive.
+		req.Header.Del(HeaderRequestTimeout)
+		if deadline, ok := req.Context().Deadline(); ok {
+			remaining := max(time.Until(deadline).Milliseconds(), 1)
+			req.Header.Set(HeaderRequestTimeout, strconv.FormatInt(remaining, 10))
+		}
+
+		for _, h := range hopByHopHeaders {
+			req.Header.Del(h)
+		}
+
+		clientIP := c.ClientIP()
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			clientIP = prior + ", " + clientIP
+		}
+		req.Header.Set("X-Forwarded-For", clientIP)
+
+		if c.Request.Host != "" {
+			req.Header.Set("X-Forwarded-Host", c.Request.Host)
+		}
 
-			if traceID := c.GetString("trace_id"); traceID != "" {
-				spanID := c.GetString("span_id")
-				flags := c.GetString("trace_flags")
-				if flags == "" {
-					flags = "01"
-				}
-				req.Header.Set("Traceparent", fmt.Sprintf("00-%s-%s-%s", traceID, spanID, flags))
+		proto := "http"
+		if c.Request.TLS != nil {
+			proto = "https"
+		}
+		if forwardedProto := c.GetHeader("X-Forwarded-Proto"); forwardedProto != "" {
+			proto = forwardedProto
+		}
+		req.Header.Set("X-Forwarded-Proto", proto)
+
+		if requestID := c.GetString("request_id"); requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+
+		if traceID := c.GetString("trace_id"); traceID != "" {
+			spanID := c.GetString("span_id")
+			flags := c.GetString("trace_flags")
+			if flags == "" {
+				flags = "01"
 			}
-			if tracestate, exists := c.Get("trace_state"); exists {
-				if ts, ok := tracestate.(string); ok && ts != "" {
-					req.Header.Set("Tracestate", ts)
-				}
+			req.Header.Set("Traceparent", fmt.Sprintf("00-%s-%s-%s", traceID, spanID, flags))
+		}
+		if tracestate, exists := c.Get("trace_state"); exists {
+			if ts, ok := tracestate.(string); ok && ts != "" {
+				req.Header.Set("Tracestate", ts)
 			}
+		}
 
-			req.Header.Set("X-Forwarded-Service", match.Entry.ServiceName)
-		},
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			c.JSON(http.StatusBadGateway, gin.H{
-				"error":   "upstream_error",
-				"message": fmt.Sprintf("failed to connect to upstream: %v", err),
-			})
-		},
+		req.Header.Set("X-Forwarded-Service", entry.ServiceName)
+	}
+}
+
+func (p *ProxyHandler) routeTimeout(route domain.Route) time.Duration {
+	timeout := p.defaultTimeout
+	if route.TimeoutMs > 0 {
+		timeout = time.Duration(route.TimeoutMs) * time.Millisecond
 	}
+	if p.maxTimeout > 0 && (timeout <= 0 || timeout > p.maxTimeout) {
+		timeout = p.maxTimeout
+	}
+	return timeout
+}
+
+// upstreamOutcome is what the circuit breaker learns from one proxied request.
+type upstreamOutcome struct {
+	status int
+	err    error
+}
+
+// acquireInstance selects an instance and reserves a request on its circuit
+// breaker, moving on to another candidate when a concurrent request took the
+// last half-open probe first.
+func (p *ProxyHandler) acquireInstance(c *gin.Context, instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	for len(instances) > 0 {
+		instance := p.selectInstance(c, instances)
+		if instance == nil || p.breakers == nil || p.breakers.Acquire(instance.ID) {
+			return instance
+		}
+		instances = slices.DeleteFunc(slices.Clone(instances), func(candidate *domain.ServiceInstance) bool {
+			return candidate.ID == instance.ID
+		})
+	}
+	return nil
+}
 
-	proxy.ServeHTTP(c.Writer, c.Request)
+func (p *ProxyHandler) recordOutcome(instance *domain.ServiceInstance, outcome upstreamOutcome) {
+	switch {
+	case errors.Is(outcome.err, context.Canceled):
+		p.breakers.Release(instance.ID)
+	case outcome.err != nil:
+		p.breakers.Record(instance.ID, false)
+	case outcome.status == 0:
+		// The response never reached ModifyResponse, e.g. the handler panicked.
+		p.breakers.Release(instance.ID)
+	default:
+		p.breakers.Record(instance.ID, outcome.status < http.StatusInternalServerError)
+	}
+}
+
+func (p *ProxyHandler) selectInstance(c *gin.Context, instances []*domain.ServiceInstance) *domain.ServiceInstance {
+	aware, ok := p.loadBalancer.(application.RequestAwareBalancer)
+	if !ok {
+		return p.loadBalancer.Select(instances)
+	}
+
+	info := &application.RequestInfo{
+		Request:           c.Request,
+		ClientIP:          c.ClientIP(),
+		ClientCertSubject: c.GetString(middleware.ContextKeyClientCertSubject),
+	}
+	if value, exists := c.Get(middleware.ContextKeyClaims); exists {
+		if claims, ok := value.(*domain.ExternalClaims); ok {
+			info.Subject = claims.Subject
+		}
+	}
+	return aware.SelectForRequest(info, instances)
 }
@@ -4,16 +4,23 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/apascualco/gotway/internal/application"
 	"github.com/apascualco/gotway/internal/domain"
+	"github.com/apascualco/gotway/internal/infrastructure/config"
 	"github.com/apascualco/gotway/internal/infrastructure/http/middleware"
 	"github.com/apascualco/gotway/internal/infrastructure/jwt"
+	"github.com/apascualco/gotway/internal/infrastructure/ratelimit"
 	"github.com/gin-gonic/gin"
 	gojwt "github.com/golang-jwt/jwt/v5"
 )
@@ -365,7 +372,7 @@ func TestProxy_RemovesHopByHopHeaders(t *testing.T) {
 	}
 }
 
-func setupProxyTestServerWithAuth(privateKey *rsa.PrivateKey) (*application.Registry, *httptest.Server, *jwt.Service) {
+func setupProxyTestServerWithAuth(privateKey *rsa.PrivateKey, opts ...Option) (*application.Registry, *httptest.Server, *jwt.Service) {
 	registry := application.NewRegistry(application.RegistryConfig{
 		HeartbeatTTL: 30 * time.Second,
 	})
@@ -380,7 +387,7 @@ func setupProxyTestServerWithAuth(privateKey *rsa.PrivateKey) (*application.Regi
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
 	lb := application.NewRoundRobinBalancer()
-	proxyHandler := NewProxyHandler(registry, lb, authMiddleware)
+	proxyHandler := NewProxyHandler(registry, lb, authMiddleware, opts...)
 
 	router := gin.New()
 	router.NoRoute(proxyHandler.Handle)
@@ -570,3 +577,577 @@ func TestProxy_ProtectedRouteInsufficientScopes(t *testing.T) {
 		t.Errorf("expected status 403, got %d: %s", resp.StatusCode, string(body))
 	}
 }
+
+func TestProxy_StickySessionsByHeader(t *testing.T) {
+	var backends []*httptest.Server
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("backend-%d", i)
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(id))
+		}))
+		defer backend.Close()
+		backends = append(backends, backend)
+	}
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	lb, err := application.NewServiceBalancer(application.ServiceBalancerConfig{})
+	if err != nil {
+		t.Fatalf("failed to create balancer: %v", err)
+	}
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, lb, nil).Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	for _, backend := range backends {
+		host, port := parseHostPort(backend.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "sticky-service",
+			Host:        host,
+			Port:        port,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{{Method: "GET", Path: "/cart"}},
+			Metadata: map[string]string{
+				domain.MetadataLoadBalancer: string(application.StrategyRingHash),
+				domain.MetadataHashKey:      "header=X-User-ID",
+			},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+
+	fetch := func(user string) string {
+		req, _ := http.NewRequest("GET", gateway.URL+"/api/v1/cart", nil)
+		req.Header.Set("X-User-ID", user)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		first := fetch(user)
+		for i := 0; i < 5; i++ {
+			if got := fetch(user); got != first {
+				t.Errorf("user %s: expected sticky backend %s, got %s", user, first, got)
+			}
+		}
+	}
+}
+
+func TestProxy_SplitsTrafficByVersion(t *testing.T) {
+	registry, gateway := setupProxyTestServer()
+	defer gateway.Close()
+
+	for _, version := range []string{"v1", "v2"} {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(version))
+		}))
+		defer backend.Close()
+
+		host, port := parseHostPort(backend.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "versioned-service",
+			Host:        host,
+			Port:        port,
+			Version:     version,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{{Method: "GET", Path: "/orders"}},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+	err := registry.SetTrafficPolicy("versioned-service", &domain.TrafficPolicy{
+		Rules:   []domain.VersionRule{{Header: &domain.MatchPredicate{Name: "X-Canary", Value: "true"}, Versions: []string{"v2"}}},
+		Weights: []domain.VersionWeight{{Version: "v1", Weight: 100}, {Version: "v2", Weight: 0}},
+	})
+	if err != nil {
+		t.Fatalf("set traffic policy failed: %v", err)
+	}
+
+	fetch := func(canary bool) string {
+		req, _ := http.NewRequest("GET", gateway.URL+"/api/v1/orders", nil)
+		if canary {
+			req.Header.Set("X-Canary", "true")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	for range 5 {
+		if got := fetch(false); got != "v1" {
+			t.Errorf("expected regular traffic on v1, got %s", got)
+		}
+		if got := fetch(true); got != "v2" {
+			t.Errorf("expected canary traffic on v2, got %s", got)
+		}
+	}
+}
+
+func TestProxy_SelectsInstancesByZoneAndSelector(t *testing.T) {
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	proxyHandler := NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil,
+		WithZoneAffinity(application.ZoneAffinity{Zone: "zone-b"}))
+	router := gin.New()
+	router.NoRoute(proxyHandler.Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	instances := []struct{ id, zone, pool string }{
+		{"web-a", "zone-a", "web"},
+		{"web-b", "zone-b", "web"},
+		{"batch-a", "zone-a", "batch"},
+	}
+	for _, instance := range instances {
+		id := instance.id
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(id))
+		}))
+		defer backend.Close()
+
+		host, port := parseHostPort(backend.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "reports-service",
+			Host:        host,
+			Port:        port,
+			BasePath:    "/api/v1",
+			Routes: []domain.Route{
+				{Method: "GET", Path: "/pages"},
+				{Method: "GET", Path: "/exports", InstanceSelector: map[string]string{"pool": "batch"}},
+				{Method: "GET", Path: "/renders", InstanceSelector: map[string]string{"pool": "gpu"}},
+			},
+			Metadata: map[string]string{domain.MetadataZone: instance.zone, "pool": instance.pool},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+
+	fetch := func(path string) (int, string) {
+		resp, err := http.Get(gateway.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for range 4 {
+		if _, got := fetch("/api/v1/pages"); got != "web-b" {
+			t.Errorf("expected the instance in the gateway's zone, got %s", got)
+		}
+		if _, got := fetch("/api/v1/exports"); got != "batch-a" {
+			t.Errorf("expected the selected pool to spill over to another zone, got %s", got)
+		}
+	}
+	if status, _ := fetch("/api/v1/renders"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when no instance matches the selector, got %d", status)
+	}
+}
+
+func TestProxy_EnforcesRouteRateLimit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	proxyHandler := NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil,
+		WithRateLimiter(ratelimit.NewInMemoryLimiter()))
+	router := gin.New()
+	router.NoRoute(proxyHandler.Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, _ = registry.Register(&domain.RegisterRequest{
+		ServiceName: "limited-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes: []domain.Route{
+			{Method: "GET", Path: "/orders/:id", RateLimit: 2},
+			{Method: "GET", Path: "/catalog"},
+		},
+	})
+
+	for i, path := range []string{"/api/v1/orders/1", "/api/v1/orders/2"} {
+		resp, err := http.Get(gateway.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, resp.StatusCode)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("expected X-RateLimit-Limit 2, got %q", resp.Header.Get("X-RateLimit-Limit"))
+		}
+	}
+
+	resp, err := http.Get(gateway.URL + "/api/v1/orders/3")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for third request on the same route pattern, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("expected X-RateLimit-Remaining 0, got %q", resp.Header.Get("X-RateLimit-Remaining"))
+	}
+
+	for i := 0; i < 5; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/catalog")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("route without rate limit should not be limited, got %d", resp.StatusCode)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "" {
+			t.Error("route without rate limit should not report rate limit headers")
+		}
+	}
+}
+
+func TestProxy_QuotasKeyedOnAuthenticatedUser(t *testing.T) {
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	quotas := middleware.NewQuotaLimiter(ratelimit.NewInMemoryLimiter(), &config.Config{
+		RateLimitIPRPM:   1,
+		RateLimitUserRPM: 3,
+	})
+	registry, gateway, _ := setupProxyTestServerWithAuth(privateKey, WithQuotas(quotas))
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, _ = registry.Register(&domain.RegisterRequest{
+		ServiceName: "quota-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/me"}},
+	})
+
+	fetch := func(token string) int {
+		req, _ := http.NewRequest("GET", gateway.URL+"/api/v1/me", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := fetch(""); code != http.StatusUnauthorized {
+			t.Fatalf("unauthenticated request %d: expected 401 before any quota, got %d", i, code)
+		}
+	}
+
+	alice := generateTestToken(t, privateKey, "alice", "alice@example.com", nil)
+	bob := generateTestToken(t, privateKey, "bob", "bob@example.com", nil)
+	for _, token := range []string{alice, bob} {
+		for i := 0; i < 3; i++ {
+			if code := fetch(token); code != http.StatusOK {
+				t.Fatalf("request %d: expected 200 under the user quota, got %d", i, code)
+			}
+		}
+		if code := fetch(token); code != http.StatusTooManyRequests {
+			t.Errorf("expected 429 once the user quota is spent, got %d", code)
+		}
+	}
+}
+
+func TestProxy_CircuitBreakerSkipsFailingInstance(t *testing.T) {
+	var badHits int
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badHits++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer good.Close()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	breakers := application.NewCircuitBreakers(application.CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         time.Minute,
+	})
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil,
+		WithCircuitBreakers(breakers)).Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	var badID string
+	for _, backend := range []*httptest.Server{bad, good} {
+		host, port := parseHostPort(backend.URL)
+		resp, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "flaky-service",
+			Host:        host,
+			Port:        port,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{{Method: "GET", Path: "/items"}},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+		if backend == bad {
+			badID = resp.InstanceID
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/items")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	if badHits != 2 {
+		t.Errorf("expected the failing instance to be skipped after 2 failures, got %d hits", badHits)
+	}
+	if got := breakers.State(badID); got != application.BreakerOpen {
+		t.Errorf("expected the failing instance's breaker to be open, got %s", got)
+	}
+}
+
+func setupRetryGateway(t *testing.T, backends []*httptest.Server, route domain.Route) *httptest.Server {
+	t.Helper()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil).Handle)
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+
+	for _, backend := range backends {
+		host, port := parseHostPort(backend.URL)
+		_, err := registry.Register(&domain.RegisterRequest{
+			ServiceName: "retry-service",
+			Host:        host,
+			Port:        port,
+			BasePath:    "/api/v1",
+			Routes:      []domain.Route{route},
+		})
+		if err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	}
+	return gateway
+}
+
+func TestProxy_RetriesConnectFailureOnAnotherInstance(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	dead.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer good.Close()
+
+	gateway := setupRetryGateway(t, []*httptest.Server{dead, good}, domain.Route{
+		Method: "GET", Path: "/items", Retry: &domain.RetryPolicy{MaxAttempts: 2, BackoffBaseMs: 1},
+	})
+
+	for i := 0; i < 4; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/items")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d: expected the retry to reach the healthy instance, got %d", i, resp.StatusCode)
+		}
+	}
+}
+
+func TestProxy_RetriesReplayBody(t *testing.T) {
+	var mu sync.Mutex
+	var failed bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := !failed
+		failed = true
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.Copy(w, r.Body)
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	gateway := setupRetryGateway(t, []*httptest.Server{first, second}, domain.Route{
+		Method: "PUT", Path: "/items/:id", Retry: &domain.RetryPolicy{MaxAttempts: 2, BackoffBaseMs: 1},
+	})
+
+	req, _ := http.NewRequest("PUT", gateway.URL+"/api/v1/items/1", strings.NewReader(`{"name":"widget"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 after retry, got %d", resp.StatusCode)
+	}
+	if string(body) != `{"name":"widget"}` {
+		t.Errorf("expected the retried request to carry the original body, got %q", body)
+	}
+}
+
+func TestProxy_RetriesOnlyIdempotentMethods(t *testing.T) {
+	var hits atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("down for maintenance"))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	route := domain.Route{Method: "POST", Path: "/orders", Retry: &domain.RetryPolicy{MaxAttempts: 3, BackoffBaseMs: 1}}
+	gateway := setupRetryGateway(t, []*httptest.Server{first, second}, route)
+
+	resp, err := http.Post(gateway.URL+"/api/v1/orders", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := hits.Load(); got != 1 {
+		t.Errorf("POST should not be retried by default, got %d attempts", got)
+	}
+
+	hits.Store(0)
+	route.Method = "GET"
+	gateway = setupRetryGateway(t, []*httptest.Server{first, second}, route)
+	resp, err = http.Get(gateway.URL + "/api/v1/orders")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if got := hits.Load(); got != 2 {
+		t.Errorf("GET should be retried once per other instance, got %d attempts", got)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != "down for maintenance" {
+		t.Errorf("expected the last upstream response once retries are exhausted, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestProxy_RouteTimeoutReturnsGatewayTimeout(t *testing.T) {
+	received := make(chan string, 1)
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(HeaderRequestTimeout)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil,
+		WithTimeouts(time.Minute, time.Minute)).Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, _ = registry.Register(&domain.RegisterRequest{
+		ServiceName: "slow-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/report", TimeoutMs: 100}},
+	})
+
+	req, _ := http.NewRequest("GET", gateway.URL+"/api/v1/report", nil)
+	req.Header.Set(HeaderRequestTimeout, "999999")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", resp.StatusCode)
+	}
+	var body map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body["error"] != "upstream_timeout" {
+		t.Errorf("expected upstream_timeout error, got %q", body["error"])
+	}
+
+	requestTimeout := <-received
+	remaining, err := strconv.Atoi(requestTimeout)
+	if err != nil || remaining <= 0 || remaining > 100 {
+		t.Errorf("expected the upstream to receive the remaining route timeout, got %q", requestTimeout)
+	}
+}
+
+func TestProxy_PerTryTimeoutRetriesAnotherInstance(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	gateway := setupRetryGateway(t, []*httptest.Server{slow, fast}, domain.Route{
+		Method: "GET", Path: "/items",
+		Retry: &domain.RetryPolicy{
+			MaxAttempts:     2,
+			PerTryTimeoutMs: 50,
+			RetryOn:         []string{domain.RetryOnTimeout},
+			BackoffBaseMs:   1,
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/items")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d: expected the slow attempt to be retried, got %d", i, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaxRetryBodyBytes = 1 << 20
+	// maxFailureBodyBytes bounds how much of a retryable error response is
+	// kept to be replayed if no retry succeeds.
+	maxFailureBodyBytes = 64 << 10
+
+	defaultBackoffBase = 25 * time.Millisecond
+	defaultBackoffMax  = 250 * time.Millisecond
+)
+
+var (
+	errPerTryTimeout   = errors.New("upstream did not respond within the per-try timeout")
+	errRequestTimeout  = errors.New("upstream did not respond within the route timeout")
+	errRetryableStatus = errors.New("upstream returned a retryable status")
+)
+
+// attemptFailure is a failed attempt held back while the request is retried:
+// either a transport error or a retryable response.
+type attemptFailure struct {
+	err    error
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newStatusFailure(resp *http.Response) *attemptFailure {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxFailureBodyBytes))
+	return &attemptFailure{
+		status: resp.StatusCode,
+		header: resp.Header.Clone(),
+		body:   body,
+	}
+}
+
+// write sends the failure to the client as the final response.
+func (f *attemptFailure) write(c *gin.Context) {
+	if errors.Is(f.err, errPerTryTimeout) || errors.Is(f.err, errRequestTimeout) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":   "upstream_timeout",
+			"message": f.err.Error(),
+		})
+		return
+	}
+	if f.err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "upstream_error",
+			"message": fmt.Sprintf("failed to connect to upstream: %v", f.err),
+		})
+		return
+	}
+
+	for key, values := range f.header {
+		for _, h := range hopByHopHeaders {
+			if http.CanonicalHeaderKey(h) == key {
+				values = nil
+			}
+		}
+		for _, value := range values {
+			c.Writer.Header().Add(key, value)
+		}
+	}
+	c.Writer.Header().Set("Content-Length", strconv.Itoa(len(f.body)))
+	c.Status(f.status)
+	_, _ = c.Writer.Write(f.body)
+}
+
+// bufferBody reads the request body into memory so it can be replayed on
+// another instance. Bodies over maxRetryBody are left streaming, with what
+// was already read put back in front, and reported as not replayable.
+func (p *ProxyHandler) bufferBody(c *gin.Context) ([]byte, bool, error) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if c.Request.ContentLength > p.maxRetryBody {
+		return nil, false, nil
+	}
+
+	original := c.Request.Body
+	body, err := io.ReadAll(io.LimitReader(original, p.maxRetryBody+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(body)) > p.maxRetryBody {
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return nil, false, nil
+	}
+	_ = original.Close()
+	return body, true, nil
+}
+
+// waitBackoff sleeps before the retry following attempt, using exponential
+// backoff with full jitter. It returns false if ctx ends first.
+func waitBackoff(ctx context.Context, policy *domain.RetryPolicy, attempt int) bool {
+	base := defaultBackoffBase
+	if policy.BackoffBaseMs > 0 {
+		base = time.Duration(policy.BackoffBaseMs) * time.Millisecond
+	}
+	ceiling := defaultBackoffMax
+	if policy.BackoffMaxMs > 0 {
+		ceiling = time.Duration(policy.BackoffMaxMs) * time.Millisecond
+	}
+
+	delay := min(ceiling, base<<min(attempt-1, 16))
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(rand.N(delay) + 1)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
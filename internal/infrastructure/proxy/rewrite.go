@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// HeaderPathParamPrefix prefixes the headers that carry the path parameters
+// of routes registered with forward_params, e.g. X-Path-Param-Id.
+const HeaderPathParamPrefix = "X-Path-Param-"
+
+// contextKeyPathParams holds the path parameters captured by the matched
+// route.
+const contextKeyPathParams = "path_params"
+
+// regexCache compiles each rewrite expression once. Expressions are
+// validated at registration, so failing to compile is not expected.
+type regexCache struct {
+	compiled sync.Map
+}
+
+func (r *regexCache) get(expr string) *regexp.Regexp {
+	if re, ok := r.compiled.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	r.compiled.Store(expr, re)
+	return re
+}
+
+// rewritePath applies the rule of entry's route to path.
+func (p *ProxyHandler) rewritePath(entry *domain.RouteEntry, path string, params map[string]string) string {
+	rule := entry.Route.Rewrite
+	if rule == nil {
+		return path
+	}
+
+	if rule.Template != "" {
+		path = rule.ExpandTemplate(params)
+	}
+	if rule.StripBasePath {
+		path = stripBasePath(path, entry.BasePath)
+	}
+	if rule.Regex != "" {
+		if re := p.rewrites.get(rule.Regex); re != nil {
+			path = re.ReplaceAllString(path, rule.Replacement)
+		}
+	}
+	if rule.Prefix != "" {
+		path = strings.TrimSuffix(rule.Prefix, "/") + path
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// stripBasePath removes from path as many segments as basePath has, which
+// may be parameters.
+func stripBasePath(path, basePath string) string {
+	n := 0
+	if strings.Trim(basePath, "/") != "" {
+		n = len(domain.SplitPath(basePath))
+	}
+
+	rest := strings.TrimPrefix(path, "/")
+	for ; n > 0 && rest != ""; n-- {
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			return "/"
+		}
+		rest = rest[i+1:]
+	}
+	return "/" + rest
+}
+
+// setPathParamHeaders replaces any X-Path-Param-* header sent by the client
+// with the parameters captured by the route, when it forwards them.
+func setPathParamHeaders(header http.Header, route domain.Route, params map[string]string) {
+	for key := range header {
+		if strings.HasPrefix(key, HeaderPathParamPrefix) {
+			header.Del(key)
+		}
+	}
+	if !route.ForwardParams {
+		return
+	}
+	for name, value := range params {
+		header.Set(HeaderPathParamPrefix+name, value)
+	}
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+func TestProxy_RewritesPath(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"path":     r.URL.Path,
+			"query":    r.URL.RawQuery,
+			"param_id": r.Header.Get("X-Path-Param-Id"),
+		})
+	}))
+	defer backend.Close()
+
+	registry, gateway := setupProxyTestServer()
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "order-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/shop/{tenant}/orders",
+		Routes: []domain.Route{
+			{Method: "GET", Path: "/{id}", Public: true, ForwardParams: true,
+				Rewrite: &domain.RewriteRule{StripBasePath: true, Prefix: "/internal"}},
+			{Method: "GET", Path: "/{id}/items", Public: true,
+				Rewrite: &domain.RewriteRule{Template: "/v2/{tenant}/orders/{id}/lines"}},
+			{Method: "GET", Path: "/search/*rest", Public: true,
+				Rewrite: &domain.RewriteRule{Regex: `^/shop/[^/]+/orders/search/(.*)$`, Replacement: "/find/$1"}},
+			{Method: "GET", Path: "/", Public: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantPath  string
+		wantParam string
+	}{
+		{"strip base path and prefix", "/shop/acme/orders/42?full=1", "/internal/42", "42"},
+		{"template", "/shop/acme/orders/42/items", "/v2/acme/orders/42/lines", ""},
+		{"regex", "/shop/acme/orders/search/by/date", "/find/by/date", ""},
+		{"no rule", "/shop/acme/orders/", "/shop/acme/orders/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, gateway.URL+tt.path, nil)
+			req.Header.Set("X-Path-Param-Id", "spoofed")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			var got map[string]string
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got["path"] != tt.wantPath {
+				t.Errorf("expected path %s, got %s", tt.wantPath, got["path"])
+			}
+			if got["param_id"] != tt.wantParam {
+				t.Errorf("expected X-Path-Param-Id %q, got %q", tt.wantParam, got["param_id"])
+			}
+			if tt.name == "strip base path and prefix" && got["query"] != "full=1" {
+				t.Errorf("expected the query to be forwarded, got %q", got["query"])
+			}
+		})
+	}
+}
+
+func TestStripBasePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		basePath string
+		want     string
+	}{
+		{"/api/v1/users/1", "/api/v1", "/users/1"},
+		{"/api/v1", "/api/v1", "/"},
+		{"/api/v1/", "/api/v1", "/"},
+		{"/tenants/acme/users", "/tenants/:tenant", "/users"},
+		{"/users", "/", "/users"},
+	}
+
+	for _, tt := range tests {
+		if got := stripBasePath(tt.path, tt.basePath); got != tt.want {
+			t.Errorf("stripBasePath(%q, %q) = %q, want %q", tt.path, tt.basePath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+)
+
+// TransportConfig tunes the connections the gateway keeps to upstream
+// services. Zero values fall back to the net/http defaults.
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration
+	TLSHandshakeTimeout time.Duration
+	// TLS is the base configuration for https instances, carrying the CAs
+	// trusted for upstream certificates and the gateway's client certificate
+	// for mTLS. Nil trusts the system roots and presents no certificate.
+	TLS *tls.Config
+}
+
+// defaultIdleConnTimeout matches http.DefaultTransport.
+const defaultIdleConnTimeout = 90 * time.Second
+
+// transportPool keeps one long-lived transport per upstream service,
+// protocol and TLS server name, so connections are pooled and reused across
+// requests instead of sharing http.DefaultTransport with its small per-host
+// idle pool. A transport unused for longer than the idle connection timeout,
+// such as one of a deregistered service, is closed and dropped.
+type transportPool struct {
+	config     TransportConfig
+	idleTTL    time.Duration
+	mu         sync.RWMutex
+	transports map[transportKey]*pooledTransport
+	nextSweep  atomic.Int64
+}
+
+type pooledTransport struct {
+	*http.Transport
+	lastUsed atomic.Int64
+}
+
+type transportKey struct {
+	service    string
+	protocol   string
+	serverName string
+}
+
+func newTransportPool(cfg TransportConfig) *transportPool {
+	idleTTL := cfg.IdleConnTimeout
+	if idleTTL <= 0 {
+		idleTTL = defaultIdleConnTimeout
+	}
+	return &transportPool{
+		config:     cfg,
+		idleTTL:    idleTTL,
+		transports: make(map[transportKey]*pooledTransport),
+	}
+}
+
+// forInstance returns the transport for the instance's service, upstream
+// protocol and TLS server name, creating it on first use.
+func (t *transportPool) forInstance(instance *domain.ServiceInstance) *http.Transport {
+	key := transportKey{
+		service:    instance.ServiceName,
+		protocol:   instance.UpstreamProtocol(),
+		serverName: instance.TLSServerName,
+	}
+
+	now := time.Now()
+	t.maybeEvict(now)
+
+	t.mu.RLock()
+	pooled, exists := t.transports[key]
+	t.mu.RUnlock()
+	if !exists {
+		t.mu.Lock()
+		if pooled, exists = t.transports[key]; !exists {
+			pooled = &pooledTransport{Transport: t.newTransport(key)}
+			t.transports[key] = pooled
+		}
+		t.mu.Unlock()
+	}
+	pooled.lastUsed.Store(now.UnixNano())
+	return pooled.Transport
+}
+
+// maybeEvict runs evictIdle at most once per idle TTL.
+func (t *transportPool) maybeEvict(now time.Time) {
+	next := t.nextSweep.Load()
+	if now.UnixNano() < next || !t.nextSweep.CompareAndSwap(next, now.Add(t.idleTTL).UnixNano()) {
+		return
+	}
+	t.evictIdle(now)
+}
+
+// evictIdle drops the transports unused for longer than the idle TTL and
+// closes their idle connections. Requests still using one finish normally.
+func (t *transportPool) evictIdle(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	evicted := 0
+	for key, pooled := range t.transports {
+		if now.Sub(time.Unix(0, pooled.lastUsed.Load())) > t.idleTTL {
+			delete(t.transports, key)
+			pooled.CloseIdleConnections()
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func (t *transportPool) newTransport(key transportKey) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if t.config.DialTimeout > 0 {
+		dialer.Timeout = t.config.DialTimeout
+	}
+	if t.config.KeepAlive != 0 {
+		dialer.KeepAlive = t.config.KeepAlive
+	}
+	transport.DialContext = dialer.DialContext
+
+	if t.config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = t.config.MaxIdleConns
+	}
+	if t.config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = t.config.MaxIdleConnsPerHost
+	}
+	if t.config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = t.config.MaxConnsPerHost
+	}
+	if t.config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = t.config.IdleConnTimeout
+	}
+	if t.config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = t.config.TLSHandshakeTimeout
+	}
+
+	if t.config.TLS != nil {
+		transport.TLSClientConfig = t.config.TLS.Clone()
+	}
+	if key.serverName != "" {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.ServerName = key.serverName
+	}
+
+	if key.protocol == domain.UpstreamProtocolH2C {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = &protocols
+	}
+	return transport
+}
+
+// closeIdle releases the idle connections of every transport.
+func (t *transportPool) closeIdle() {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, transport := range t.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// bufferPool recycles the buffers ReverseProxy copies response bodies
+// through, instead of allocating 32KB per request.
+type bufferPool struct {
+	pool sync.Pool
+}
+
+func newBufferPool() *bufferPool {
+	return &bufferPool{pool: sync.Pool{
+		New: func() any {
+			buf := make([]byte, 32<<10)
+			return &buf
+		},
+	}}
+}
+
+func (b *bufferPool) Get() []byte {
+	return *b.pool.Get().(*[]byte)
+}
+
+func (b *bufferPool) Put(buf []byte) {
+	b.pool.Put(&buf)
+}
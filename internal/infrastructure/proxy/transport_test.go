@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTransportPool_ReusesTransportPerServiceAndProtocol(t *testing.T) {
+	pool := newTransportPool(TransportConfig{MaxIdleConnsPerHost: 7, DialTimeout: time.Second})
+
+	a := pool.forInstance(&domain.ServiceInstance{ID: "1", ServiceName: "orders"})
+	b := pool.forInstance(&domain.ServiceInstance{ID: "2", ServiceName: "orders"})
+	if a != b {
+		t.Error("instances of the same service should share a transport")
+	}
+	if a.MaxIdleConnsPerHost != 7 {
+		t.Errorf("expected MaxIdleConnsPerHost 7, got %d", a.MaxIdleConnsPerHost)
+	}
+
+	h2c := pool.forInstance(&domain.ServiceInstance{
+		ID:          "3",
+		ServiceName: "orders",
+		Metadata:    map[string]string{domain.MetadataUpstreamProtocol: domain.UpstreamProtocolH2C},
+	})
+	if h2c == a {
+		t.Error("h2c instances should get their own transport")
+	}
+	if h2c.Protocols == nil || !h2c.Protocols.UnencryptedHTTP2() {
+		t.Error("h2c transport should speak HTTP/2 over cleartext")
+	}
+	if other := pool.forInstance(&domain.ServiceInstance{ID: "4", ServiceName: "payments"}); other == a {
+		t.Error("each service should get its own transport")
+	}
+}
+
+func TestTransportPool_EvictsIdleTransports(t *testing.T) {
+	pool := newTransportPool(TransportConfig{IdleConnTimeout: time.Minute})
+
+	orders := pool.forInstance(&domain.ServiceInstance{ID: "1", ServiceName: "orders"})
+	pool.forInstance(&domain.ServiceInstance{ID: "2", ServiceName: "payments"})
+	pool.transports[transportKey{service: "payments", protocol: domain.UpstreamProtocolHTTP1}].lastUsed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+
+	if evicted := pool.evictIdle(time.Now()); evicted != 1 {
+		t.Fatalf("expected the idle payments transport to be evicted, got %d", evicted)
+	}
+	if _, exists := pool.transports[transportKey{service: "payments", protocol: domain.UpstreamProtocolHTTP1}]; exists {
+		t.Error("expected the evicted transport to be dropped")
+	}
+	if got := pool.forInstance(&domain.ServiceInstance{ID: "1", ServiceName: "orders"}); got != orders {
+		t.Error("expected the transport in use to be kept")
+	}
+
+	if evicted := pool.evictIdle(time.Now().Add(2 * time.Minute)); evicted != 1 || len(pool.transports) != 0 {
+		t.Errorf("expected every transport to be evicted once idle, got %d evicted and %d left", evicted, len(pool.transports))
+	}
+}
+
+func TestProxy_ReusesUpstreamConnections(t *testing.T) {
+	var connections atomic.Int32
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	registry, gateway := setupProxyTestServer()
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, _ = registry.Register(&domain.RegisterRequest{
+		ServiceName: "pooled-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/items"}},
+	})
+
+	for i := 0; i < 10; i++ {
+		resp, err := http.Get(gateway.URL + "/api/v1/items")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	if got := connections.Load(); got != 1 {
+		t.Errorf("expected sequential requests to reuse one upstream connection, got %d", got)
+	}
+}
+
+func TestProxy_H2CUpstream(t *testing.T) {
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	backend.Config.Protocols = &http.Protocols{}
+	backend.Config.Protocols.SetHTTP1(true)
+	backend.Config.Protocols.SetUnencryptedHTTP2(true)
+	backend.Start()
+	defer backend.Close()
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	router := gin.New()
+	router.NoRoute(NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil).Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	host, port := parseHostPort(backend.URL)
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "h2c-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/proto"}},
+		Metadata:    map[string]string{domain.MetadataUpstreamProtocol: domain.UpstreamProtocolH2C},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	resp, err := http.Get(gateway.URL + "/api/v1/proto")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body := make([]byte, 16)
+	n, _ := resp.Body.Read(body)
+
+	if got := string(body[:n]); got != "HTTP/2.0" {
+		t.Errorf("expected the upstream to be reached over HTTP/2, got %q", got)
+	}
+}
+
+func TestProxy_HTTPSUpstreamWithClientCertificate(t *testing.T) {
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	backend.StartTLS()
+	defer backend.Close()
+
+	// The httptest certificate is valid for "example.com" and is reused as
+	// the gateway's client certificate.
+	roots := x509.NewCertPool()
+	roots.AddCert(backend.Certificate())
+	clientCert := backend.TLS.Certificates[0]
+
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	handler := NewProxyHandler(registry, application.NewRoundRobinBalancer(), nil, WithTransportConfig(TransportConfig{
+		TLS: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert},
+		},
+	}))
+	router := gin.New()
+	router.NoRoute(handler.Handle)
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	host, port := parseHostPort("http://" + backend.Listener.Addr().String())
+	_, err := registry.Register(&domain.RegisterRequest{
+		ServiceName:   "tls-service",
+		Host:          host,
+		Port:          port,
+		Scheme:        domain.SchemeHTTPS,
+		TLSServerName: "example.com",
+		BasePath:      "/api/v1",
+		Routes:        []domain.Route{{Method: "GET", Path: "/secure"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	resp, err := http.Get(gateway.URL + "/api/v1/secure")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the upstream to accept the gateway certificate, got %d", resp.StatusCode)
+	}
+}
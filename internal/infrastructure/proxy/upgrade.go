@@ -0,0 +1,331 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+var errUpgradeHandshakeTimeout = errors.New("upgrade handshake timed out")
+
+// WithUpgradeIdleTimeout closes tunnelled connections, such as WebSockets,
+// once no data has crossed them in either direction for timeout. Zero keeps
+// them open until one side closes.
+func WithUpgradeIdleTimeout(timeout time.Duration) Option {
+	return func(p *ProxyHandler) {
+		p.upgradeIdleTimeout = timeout
+	}
+}
+
+// isUpgradeRequest reports whether the request asks to switch protocols, as a
+// WebSocket handshake does.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgrade forwards an upgrade request to an instance and, once it switches
+// protocols, tunnels the client connection to it until either side closes,
+// the tunnel goes idle or the gateway shuts down. The route timeout bounds
+// only the handshake.
+func (p *ProxyHandler) upgrade(c *gin.Context, entry *domain.RouteEntry, instances []*domain.ServiceInstance) {
+	if !p.tunnels.accepting() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "service_unavailable",
+			"message": "gateway is shutting down",
+		})
+		return
+	}
+
+	instance := p.acquireInstance(c, instances)
+	if instance == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "service_unavailable",
+			"message": "failed to select instance",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(c.Request.Context())
+	defer cancel(nil)
+
+	resp, err := p.handshake(ctx, cancel, c, entry, instance)
+	if err != nil {
+		(&attemptFailure{err: err}).write(c)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// The instance refused the upgrade; relay its answer as is.
+		for _, h := range hopByHopHeaders {
+			resp.Header.Del(h)
+		}
+		for key, values := range resp.Header {
+			c.Writer.Header()[key] = values
+		}
+		c.Status(resp.StatusCode)
+		_, _ = io.Copy(c.Writer, resp.Body)
+		return
+	}
+
+	backend, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		(&attemptFailure{err: fmt.Errorf("upstream connection cannot be tunnelled")}).write(c)
+		return
+	}
+
+	client, buffered, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		slog.Error("failed to hijack upgraded connection", "error", err)
+		(&attemptFailure{err: err}).write(c)
+		return
+	}
+
+	t := newTunnel(client, backend)
+	if !p.tunnels.track(t) {
+		t.close()
+		return
+	}
+	defer p.tunnels.untrack(t)
+
+	if tracker, ok := p.loadBalancer.(application.ConnectionTracker); ok {
+		tracker.Connect(instance)
+		defer tracker.Disconnect(instance)
+	}
+
+	if err := writeSwitchingProtocols(buffered.Writer, resp); err != nil {
+		t.close()
+		return
+	}
+
+	if p.upgradeIdleTimeout > 0 {
+		go t.closeWhenIdle(p.upgradeIdleTimeout)
+	}
+
+	// Bytes the client sent right after the handshake may already sit in the
+	// server's read buffer, so the client side is read through it.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.pipe(backend, buffered.Reader, p.buffers)
+	}()
+	go func() {
+		defer wg.Done()
+		t.pipe(client, backend, p.buffers)
+	}()
+	wg.Wait()
+
+	slog.Debug("upgraded connection closed",
+		slog.String("service", entry.ServiceName),
+		slog.String("instance_id", instance.ID),
+		slog.String("protocol", resp.Header.Get("Upgrade")),
+	)
+}
+
+// handshake sends the upgrade request to instance and waits for its answer,
+// bounded by the route timeout. Once the response arrives the connection is
+// no longer subject to it, only to ctx.
+func (p *ProxyHandler) handshake(ctx context.Context, cancel context.CancelCauseFunc, c *gin.Context, entry *domain.RouteEntry, instance *domain.ServiceInstance) (*http.Response, error) {
+	if timeout := p.routeTimeout(entry.Route); timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { cancel(errUpgradeHandshakeTimeout) })
+		defer timer.Stop()
+	}
+
+	outreq := c.Request.Clone(ctx)
+	outreq.RequestURI = ""
+	p.director(c, entry, instance)(outreq)
+	outreq.Header.Set("Connection", "Upgrade")
+	outreq.Header.Set("Upgrade", c.Request.Header.Get("Upgrade"))
+
+	if tracker, ok := p.loadBalancer.(application.RequestTracker); ok {
+		tracker.Begin(instance)
+		defer tracker.End(instance)
+	}
+
+	resp, err := p.transports.forInstance(instance).RoundTrip(outreq)
+	if err != nil && context.Cause(ctx) == errUpgradeHandshakeTimeout {
+		err = errRequestTimeout
+	}
+	if p.breakers != nil {
+		outcome := upstreamOutcome{err: err}
+		if resp != nil {
+			outcome.status = resp.StatusCode
+		}
+		p.recordOutcome(instance, outcome)
+	}
+	return resp, err
+}
+
+func writeSwitchingProtocols(w *bufio.Writer, resp *http.Response) error {
+	if _, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode)); err != nil {
+		return err
+	}
+	if err := resp.Header.Write(w); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\r\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// tunnel joins a hijacked client connection to the upgraded upstream one.
+type tunnel struct {
+	client     net.Conn
+	backend    io.ReadWriteCloser
+	lastActive atomic.Int64
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func newTunnel(client net.Conn, backend io.ReadWriteCloser) *tunnel {
+	t := &tunnel{client: client, backend: backend, done: make(chan struct{})}
+	t.touch()
+	return t
+}
+
+func (t *tunnel) touch() {
+	t.lastActive.Store(time.Now().UnixNano())
+}
+
+// pipe copies src to dst until either fails, then closes the whole tunnel so
+// the opposite direction stops too.
+func (t *tunnel) pipe(dst io.Writer, src io.Reader, buffers *bufferPool) {
+	defer t.close()
+
+	buf := buffers.Get()
+	defer buffers.Put(buf)
+
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			t.touch()
+			if _, writeErr := dst.Write(buf[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *tunnel) closeWhenIdle(timeout time.Duration) {
+	ticker := time.NewTicker(max(timeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(time.Unix(0, t.lastActive.Load())) >= timeout {
+				t.close()
+				return
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+func (t *tunnel) close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		_ = t.client.Close()
+		_ = t.backend.Close()
+	})
+}
+
+// tunnelSet tracks the open tunnels so shutdown can wait for them to end and
+// close those that do not.
+type tunnelSet struct {
+	mu      sync.Mutex
+	active  map[*tunnel]struct{}
+	closed  bool
+	drained chan struct{}
+}
+
+func newTunnelSet() *tunnelSet {
+	return &tunnelSet{active: make(map[*tunnel]struct{})}
+}
+
+func (s *tunnelSet) accepting() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.closed
+}
+
+func (s *tunnelSet) track(t *tunnel) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.active[t] = struct{}{}
+	return true
+}
+
+func (s *tunnelSet) untrack(t *tunnel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.active, t)
+	if s.closed && len(s.active) == 0 && s.drained != nil {
+		close(s.drained)
+		s.drained = nil
+	}
+}
+
+// shutdown stops new tunnels and waits for the open ones to end until ctx is
+// done, then closes the rest. It reports how many had to be closed.
+func (s *tunnelSet) shutdown(ctx context.Context) int {
+	s.mu.Lock()
+	s.closed = true
+	if len(s.active) == 0 {
+		s.mu.Unlock()
+		return 0
+	}
+	drained := make(chan struct{})
+	s.drained = drained
+	s.mu.Unlock()
+
+	select {
+	case <-drained:
+		return 0
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closed := len(s.active)
+	for t := range s.active {
+		t.close()
+	}
+	return closed
+}
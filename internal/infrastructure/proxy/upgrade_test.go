@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// newEchoUpgradeBackend switches to an "echo" protocol that sends back every
+// line it reads. Requests that do not ask for it get a 426 naming the headers
+// that reached it.
+func newEchoUpgradeBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusUpgradeRequired)
+			_, _ = fmt.Fprintf(w, "upgrade=%q", r.Header.Get("Upgrade"))
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			_, _ = rw.WriteString(line)
+			_ = rw.Flush()
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func setupUpgradeGateway(t *testing.T, lb application.LoadBalancer, streaming bool, opts ...Option) (*ProxyHandler, *httptest.Server, *domain.ServiceInstance) {
+	t.Helper()
+
+	backend := newEchoUpgradeBackend(t)
+	registry := application.NewRegistry(application.RegistryConfig{HeartbeatTTL: 30 * time.Second})
+	handler := NewProxyHandler(registry, lb, nil, opts...)
+	router := gin.New()
+	router.NoRoute(handler.Handle)
+	gateway := httptest.NewServer(router)
+	t.Cleanup(gateway.Close)
+
+	host, port := parseHostPort(backend.URL)
+	resp, err := registry.Register(&domain.RegisterRequest{
+		ServiceName: "echo-service",
+		Host:        host,
+		Port:        port,
+		BasePath:    "/api/v1",
+		Routes:      []domain.Route{{Method: "GET", Path: "/echo", Streaming: streaming}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	return handler, gateway, registry.GetInstance(resp.InstanceID)
+}
+
+// dialUpgrade sends an echo upgrade request to the gateway and returns the
+// connection with the handshake response.
+func dialUpgrade(t *testing.T, gateway *httptest.Server) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_, _ = io.WriteString(conn, "GET /api/v1/echo HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake response: %v", err)
+	}
+	return conn, reader, resp
+}
+
+func TestProxy_UpgradeTunnelsStreamingRoute(t *testing.T) {
+	tracker := application.NewInflightTracker()
+	_, gateway, instance := setupUpgradeGateway(t, application.NewLeastConnectionsBalancer(tracker), true)
+
+	conn, reader, resp := dialUpgrade(t, gateway)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	for _, message := range []string{"ping\n", "pong\n"} {
+		_, _ = io.WriteString(conn, message)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read echo: %v", err)
+		}
+		if line != message {
+			t.Errorf("expected %q echoed, got %q", message, line)
+		}
+	}
+	if got := tracker.Connections(instance.ID); got != 1 {
+		t.Errorf("expected 1 open connection on the instance, got %d", got)
+	}
+
+	_ = conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for tracker.Connections(instance.ID) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("closed tunnel should no longer count as a connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProxy_UpgradeStrippedOnNonStreamingRoute(t *testing.T) {
+	_, gateway, _ := setupUpgradeGateway(t, application.NewRoundRobinBalancer(), false)
+
+	_, reader, resp := dialUpgrade(t, gateway)
+	body, _ := io.ReadAll(io.LimitReader(reader, resp.ContentLength))
+
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("expected the upstream 426, got %d", resp.StatusCode)
+	}
+	if string(body) != `upgrade=""` {
+		t.Errorf("upgrade headers should not reach the upstream, got %s", body)
+	}
+}
+
+func TestProxy_UpgradeIdleTimeoutClosesTunnel(t *testing.T) {
+	_, gateway, _ := setupUpgradeGateway(t, application.NewRoundRobinBalancer(), true,
+		WithUpgradeIdleTimeout(50*time.Millisecond))
+
+	conn, reader, resp := dialUpgrade(t, gateway)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("expected the idle tunnel to be closed, got %v", err)
+	}
+}
+
+func TestProxy_ShutdownClosesTunnels(t *testing.T) {
+	handler, gateway, _ := setupUpgradeGateway(t, application.NewRoundRobinBalancer(), true)
+
+	conn, reader, resp := dialUpgrade(t, gateway)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	handler.Shutdown(ctx)
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("expected the tunnel to be closed at shutdown, got %v", err)
+	}
+
+	_, _, resp = dialUpgrade(t, gateway)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected upgrades to be refused after shutdown, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm. It stores a single
+// theoretical arrival time (TAT) per key: each request pushes the TAT one
+// emission interval forward and is rejected when that would put it more than
+// burst intervals ahead of now.
+//
+// KEYS[1] TAT key
+// ARGV[1] emission interval in microseconds
+// ARGV[2] burst
+//
+// Returns {allowed, remaining, microseconds until the full burst is available}.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local tolerance = interval * tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+if new_tat - tolerance > now then
+	return {0, 0, math.ceil(tat - now)}
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.ceil((new_tat - now) / 1000))
+return {1, math.floor((tolerance - (new_tat - now)) / interval), math.ceil(new_tat - now)}
+`)
+
+// GCRALimiter implements rate limiting with the generic cell rate algorithm
+// in Redis. It behaves like a token bucket of size burst that earns back
+// limit requests per window, but needs a single value per key.
+type GCRALimiter struct {
+	client *redis.Client
+	window time.Duration
+	burst  int
+}
+
+// NewGCRALimiter creates a GCRA limiter. A burst of zero allows the whole
+// limit back to back.
+func NewGCRALimiter(client *redis.Client, window time.Duration, burst int) *GCRALimiter {
+	return &GCRALimiter{
+		client: client,
+		window: window,
+		burst:  burst,
+	}
+}
+
+// Allow checks the request against the TAT stored for key.
+func (l *GCRALimiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
+	now := time.Now()
+	if limit <= 0 {
+		return denied(limit, now), nil
+	}
+
+	interval := float64(emissionInterval(l.window, limit)) / float64(time.Microsecond)
+	values, err := gcraScript.Run(ctx, l.client, []string{key},
+		strconv.FormatFloat(interval, 'f', -1, 64),
+		burstFor(l.burst, limit),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("redis gcra failed: %w", err)
+	}
+
+	return scriptResult(values, limit, now)
+}
+
+// InMemoryGCRALimiter is the in-process counterpart of GCRALimiter, for
+// testing or when Redis is not available. Keys are forgotten once their TAT
+// has passed.
+type InMemoryGCRALimiter struct {
+	store  *shardedStore[time.Time]
+	window time.Duration
+	burst  int
+	now    func() time.Time
+}
+
+// NewInMemoryGCRALimiter creates an in-memory GCRA limiter.
+func NewInMemoryGCRALimiter(window time.Duration, burst int) *InMemoryGCRALimiter {
+	return newInMemoryGCRALimiter(window, burst, DefaultMaxKeys)
+}
+
+func newInMemoryGCRALimiter(window time.Duration, burst, maxKeys int) *InMemoryGCRALimiter {
+	return &InMemoryGCRALimiter{
+		store:  newShardedStore[time.Time](maxKeys),
+		window: window,
+		burst:  burst,
+		now:    time.Now,
+	}
+}
+
+// Allow checks the request against the TAT stored for key.
+func (l *InMemoryGCRALimiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
+	now := l.now()
+	if limit <= 0 {
+		return denied(limit, now), nil
+	}
+
+	interval := emissionInterval(l.window, limit)
+	tolerance := interval * time.Duration(burstFor(l.burst, limit))
+
+	var result *Result
+	l.store.update(key, now, func(tat *time.Time) time.Time {
+		if tat.Before(now) {
+			*tat = now
+		}
+
+		newTAT := tat.Add(interval)
+		if newTAT.Add(-tolerance).After(now) {
+			result = &Result{Allowed: false, Limit: limit, Remaining: 0, ResetAt: *tat}
+			return *tat
+		}
+
+		*tat = newTAT
+		result = &Result{
+			Allowed:   true,
+			Limit:     limit,
+			Remaining: int((tolerance - newTAT.Sub(now)) / interval),
+			ResetAt:   newTAT,
+		}
+		return newTAT
+	})
+	return result, nil
+}
+
+// Stop ends the background eviction of idle keys.
+func (l *InMemoryGCRALimiter) Stop() {
+	l.store.stop()
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryGCRA_AllowsBurstThenRejects(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := NewInMemoryGCRALimiter(time.Minute, 3)
+	limiter.now = clock.Now
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "key", 60)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d should be allowed within the burst", i)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("request %d: remaining = %d, want %d", i, result.Remaining, 2-i)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "key", 60)
+	if result.Allowed {
+		t.Error("request beyond the burst should be rejected")
+	}
+	if want := clock.now.Add(3 * time.Second); !result.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", result.ResetAt, want)
+	}
+}
+
+func TestInMemoryGCRA_EmissionInterval(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := NewInMemoryGCRALimiter(time.Minute, 1)
+	limiter.now = clock.Now
+	ctx := context.Background()
+
+	if result, _ := limiter.Allow(ctx, "key", 60); !result.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "key", 60); result.Allowed {
+		t.Fatal("request before the emission interval should be rejected")
+	}
+
+	clock.Advance(time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "key", 60); !result.Allowed {
+		t.Fatal("request after the emission interval should be allowed")
+	}
+}
+
+func TestInMemoryGCRA_DifferentKeys(t *testing.T) {
+	limiter := NewInMemoryGCRALimiter(time.Minute, 1)
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "key1", 10)
+	if result, _ := limiter.Allow(ctx, "key1", 10); result.Allowed {
+		t.Error("key1 should be rate limited")
+	}
+	if result, _ := limiter.Allow(ctx, "key2", 10); !result.Allowed {
+		t.Error("key2 should be allowed (different key)")
+	}
+}
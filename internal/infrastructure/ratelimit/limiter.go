@@ -8,6 +8,62 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Algorithm selects how requests are counted against a limit.
+type Algorithm string
+
+const (
+	AlgorithmSlidingWindow Algorithm = "sliding_window"
+	AlgorithmTokenBucket   Algorithm = "token_bucket"
+	AlgorithmGCRA          Algorithm = "gcra"
+)
+
+// Options configures the limiters built by New.
+type Options struct {
+	Algorithm Algorithm
+	// Window is the period over which limit requests are allowed. Defaults to one minute.
+	Window time.Duration
+	// Burst is how many requests token bucket and GCRA accept back to back.
+	// Zero means the burst equals the limit.
+	Burst int
+	// MaxKeys caps how many keys the in-memory limiters track. Defaults to
+	// DefaultMaxKeys.
+	MaxKeys int
+}
+
+// New creates a limiter for the configured algorithm. It is backed by Redis
+// when client is not nil and by process memory otherwise.
+func New(client *redis.Client, opts Options) (RateLimiter, error) {
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.Burst < 0 {
+		return nil, fmt.Errorf("rate limit burst must not be negative, got %d", opts.Burst)
+	}
+	if opts.MaxKeys < 0 {
+		return nil, fmt.Errorf("rate limit max keys must not be negative, got %d", opts.MaxKeys)
+	}
+
+	switch opts.Algorithm {
+	case AlgorithmSlidingWindow, "":
+		if client != nil {
+			return &Limiter{client: client, window: opts.Window}, nil
+		}
+		return newInMemoryLimiter(opts.Window, opts.MaxKeys), nil
+	case AlgorithmTokenBucket:
+		if client != nil {
+			return NewTokenBucketLimiter(client, opts.Window, opts.Burst), nil
+		}
+		return newInMemoryTokenBucketLimiter(opts.Window, opts.Burst, opts.MaxKeys), nil
+	case AlgorithmGCRA:
+		if client != nil {
+			return NewGCRALimiter(client, opts.Window, opts.Burst), nil
+		}
+		return newInMemoryGCRALimiter(opts.Window, opts.Burst, opts.MaxKeys), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", opts.Algorithm)
+	}
+}
+
 // Result contains the rate limit check result.
 type Result struct {
 	Allowed   bool
@@ -75,48 +131,56 @@ func (l *Limiter) Allow(ctx context.Context, key string, limit int) (*Result, er
 	return result, nil
 }
 
-// InMemoryLimiter provides a simple in-memory rate limiter for testing
-// or when Redis is not available.
+// InMemoryLimiter is the in-process sliding window limiter, for testing or
+// when Redis is not available. It is safe for concurrent use, tracks at most
+// DefaultMaxKeys keys and forgets a key once its window has passed.
 type InMemoryLimiter struct {
-	requests map[string][]time.Time
-	window   time.Duration
+	store  *shardedStore[[]time.Time]
+	window time.Duration
+	now    func() time.Time
 }
 
 // NewInMemoryLimiter creates a new in-memory rate limiter.
 func NewInMemoryLimiter() *InMemoryLimiter {
+	return newInMemoryLimiter(time.Minute, DefaultMaxKeys)
+}
+
+func newInMemoryLimiter(window time.Duration, maxKeys int) *InMemoryLimiter {
 	return &InMemoryLimiter{
-		requests: make(map[string][]time.Time),
-		window:   time.Minute,
+		store:  newShardedStore[[]time.Time](maxKeys),
+		window: window,
+		now:    time.Now,
 	}
 }
 
 // Allow checks if a request is allowed under the rate limit.
 func (l *InMemoryLimiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
-	now := time.Now()
+	now := l.now()
 	windowStart := now.Add(-l.window)
 
-	timestamps := l.requests[key]
-	var validTimestamps []time.Time
-	for _, ts := range timestamps {
-		if ts.After(windowStart) {
-			validTimestamps = append(validTimestamps, ts)
+	var count int
+	var allowed bool
+	l.store.update(key, now, func(timestamps *[]time.Time) time.Time {
+		valid := 0
+		for valid < len(*timestamps) && !(*timestamps)[valid].After(windowStart) {
+			valid++
 		}
-	}
-
-	count := len(validTimestamps)
-	allowed := count < limit
+		*timestamps = append((*timestamps)[:0], (*timestamps)[valid:]...)
 
-	if allowed {
-		validTimestamps = append(validTimestamps, now)
-	}
+		count = len(*timestamps)
+		allowed = count < limit
+		if allowed {
+			*timestamps = append(*timestamps, now)
+		}
 
-	l.requests[key] = validTimestamps
+		if len(*timestamps) == 0 {
+			return now
+		}
+		return (*timestamps)[len(*timestamps)-1].Add(l.window)
+	})
 
 	remaining := limit - count - 1
-	if remaining < 0 {
-		remaining = 0
-	}
-	if !allowed {
+	if remaining < 0 || !allowed {
 		remaining = 0
 	}
 
@@ -128,7 +192,30 @@ func (l *InMemoryLimiter) Allow(ctx context.Context, key string, limit int) (*Re
 	}, nil
 }
 
+// Stop ends the background eviction of idle keys.
+func (l *InMemoryLimiter) Stop() {
+	l.store.stop()
+}
+
 // RateLimiter is the interface for rate limiters.
 type RateLimiter interface {
 	Allow(ctx context.Context, key string, limit int) (*Result, error)
 }
+
+// burstFor returns the bucket capacity used for limit.
+func burstFor(burst, limit int) int {
+	if burst > 0 {
+		return burst
+	}
+	return limit
+}
+
+// emissionInterval is the time it takes to earn back one request.
+func emissionInterval(window time.Duration, limit int) time.Duration {
+	return window / time.Duration(limit)
+}
+
+// denied is the result for a non-positive limit, which blocks every request.
+func denied(limit int, now time.Time) *Result {
+	return &Result{Allowed: false, Limit: limit, Remaining: 0, ResetAt: now}
+}
@@ -2,6 +2,7 @@ package ratelimit
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -99,3 +100,45 @@ func TestInMemoryLimiter_RateLimiterInterface(t *testing.T) {
 	var _ RateLimiter = (*InMemoryLimiter)(nil)
 	var _ RateLimiter = (*Limiter)(nil)
 }
+
+func TestNew_SelectsAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm Algorithm
+		want      RateLimiter
+	}{
+		{"", &InMemoryLimiter{}},
+		{AlgorithmSlidingWindow, &InMemoryLimiter{}},
+		{AlgorithmTokenBucket, &InMemoryTokenBucketLimiter{}},
+		{AlgorithmGCRA, &InMemoryGCRALimiter{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			limiter, err := New(nil, Options{Algorithm: tt.algorithm})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := fmt.Sprintf("%T", limiter), fmt.Sprintf("%T", tt.want); got != want {
+				t.Errorf("New(%q) = %s, want %s", tt.algorithm, got, want)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(nil, Options{Algorithm: "leaky"}); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+	if _, err := New(nil, Options{Algorithm: AlgorithmGCRA, Burst: -1}); err == nil {
+		t.Error("expected error for negative burst")
+	}
+}
+
+func TestNew_UsesWindow(t *testing.T) {
+	limiter, _ := New(nil, Options{Window: time.Second})
+
+	result, _ := limiter.Allow(context.Background(), "key", 1)
+	if result.ResetAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("ResetAt %v should be within the configured window", result.ResetAt)
+	}
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxKeys caps how many keys an in-memory limiter tracks when no
+	// limit is configured.
+	DefaultMaxKeys = 100_000
+
+	storeShards      = 32
+	evictionInterval = 30 * time.Second
+	// evictionSample is how many entries are inspected to pick a victim when a
+	// shard is full, approximating least-recently-expiring eviction without
+	// keeping an ordered index.
+	evictionSample = 8
+)
+
+// shardedStore holds per-key limiter state for the in-memory limiters. Keys
+// are spread over independently locked shards so concurrent requests rarely
+// contend, each shard holds at most its share of maxKeys, and a background
+// sweep drops entries once their state is indistinguishable from a fresh key.
+type shardedStore[T any] struct {
+	seed     maphash.Seed
+	shards   [storeShards]storeShard[T]
+	perShard int
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+type storeShard[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*storeEntry[T]
+}
+
+type storeEntry[T any] struct {
+	state     T
+	expiresAt time.Time
+}
+
+func newShardedStore[T any](maxKeys int) *shardedStore[T] {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+
+	s := &shardedStore[T]{
+		seed:     maphash.MakeSeed(),
+		perShard: max(1, (maxKeys+storeShards-1)/storeShards),
+		stopCh:   make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*storeEntry[T])
+	}
+	go s.evictLoop()
+	return s
+}
+
+// update runs fn on the state stored for key, creating a zero state if the key
+// is new. fn returns the time after which the state can be dropped because
+// it no longer limits anything.
+func (s *shardedStore[T]) update(key string, now time.Time, fn func(state *T) time.Time) {
+	shard := &s.shards[maphash.String(s.seed, key)%storeShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, exists := shard.entries[key]
+	if !exists {
+		if len(shard.entries) >= s.perShard {
+			shard.evictOne(now)
+		}
+		entry = &storeEntry[T]{}
+		shard.entries[key] = entry
+	}
+	entry.expiresAt = fn(&entry.state)
+}
+
+// len returns the number of tracked keys.
+func (s *shardedStore[T]) len() int {
+	total := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		total += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return total
+}
+
+func (s *shardedStore[T]) stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+func (s *shardedStore[T]) evictLoop() {
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.evictExpired(now)
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *shardedStore[T]) evictExpired(now time.Time) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, entry := range shard.entries {
+			if !entry.expiresAt.After(now) {
+				delete(shard.entries, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// evictOne makes room in a full shard, preferring an expired entry and
+// otherwise the sampled entry closest to expiring. Callers must hold mu.
+func (sh *storeShard[T]) evictOne(now time.Time) {
+	var victim string
+	var victimExpiry time.Time
+	sampled := 0
+
+	for key, entry := range sh.entries {
+		if !entry.expiresAt.After(now) {
+			victim = key
+			break
+		}
+		if sampled == 0 || entry.expiresAt.Before(victimExpiry) {
+			victim, victimExpiry = key, entry.expiresAt
+		}
+		if sampled++; sampled >= evictionSample {
+			break
+		}
+	}
+	delete(sh.entries, victim)
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedStore_CapsKeys(t *testing.T) {
+	store := newShardedStore[int](64)
+	defer store.stop()
+	now := time.Now()
+
+	for i := 0; i < 1000; i++ {
+		store.update(fmt.Sprintf("key-%d", i), now, func(state *int) time.Time {
+			*state++
+			return now.Add(time.Minute)
+		})
+	}
+
+	if n := store.len(); n > 64 {
+		t.Errorf("store holds %d keys, want at most 64", n)
+	}
+}
+
+func TestShardedStore_EvictsExpired(t *testing.T) {
+	store := newShardedStore[int](0)
+	defer store.stop()
+	now := time.Now()
+
+	store.update("short", now, func(state *int) time.Time { return now.Add(time.Second) })
+	store.update("long", now, func(state *int) time.Time { return now.Add(time.Hour) })
+
+	store.evictExpired(now.Add(time.Minute))
+	if n := store.len(); n != 1 {
+		t.Fatalf("expected 1 key after eviction, got %d", n)
+	}
+
+	var count int
+	store.update("long", now, func(state *int) time.Time {
+		*state++
+		count = *state
+		return now.Add(time.Hour)
+	})
+	if count != 1 {
+		t.Errorf("unexpired key should keep its state, got count %d", count)
+	}
+}
+
+func TestShardedStore_FullShardPrefersExpiredVictim(t *testing.T) {
+	var shard storeShard[int]
+	shard.entries = map[string]*storeEntry[int]{
+		"live":    {expiresAt: time.Now().Add(time.Hour)},
+		"expired": {expiresAt: time.Now().Add(-time.Second)},
+	}
+
+	shard.evictOne(time.Now())
+
+	if _, ok := shard.entries["expired"]; ok {
+		t.Error("expired entry should be evicted first")
+	}
+	if _, ok := shard.entries["live"]; !ok {
+		t.Error("live entry should be kept")
+	}
+}
+
+func TestInMemoryLimiters_ConcurrentAllow(t *testing.T) {
+	limiters := map[string]RateLimiter{
+		"sliding_window": NewInMemoryLimiter(),
+		"token_bucket":   NewInMemoryTokenBucketLimiter(time.Hour, 0),
+		"gcra":           NewInMemoryGCRALimiter(time.Hour, 0),
+	}
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			var allowed atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 20; j++ {
+						result, err := limiter.Allow(context.Background(), "shared", 100)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if result.Allowed {
+							allowed.Add(1)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := allowed.Load(); got != 100 {
+				t.Errorf("allowed %d of 1000 concurrent requests, want exactly 100", got)
+			}
+		})
+	}
+}
+
+func TestInMemoryLimiter_ForgetsKeyAfterWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := newInMemoryLimiter(time.Minute, 0)
+	defer limiter.Stop()
+	limiter.now = clock.Now
+
+	limiter.Allow(context.Background(), "client", 5)
+	clock.Advance(2 * time.Minute)
+	limiter.store.evictExpired(clock.now)
+
+	if n := limiter.store.len(); n != 0 {
+		t.Errorf("expected idle key to be evicted, got %d keys", n)
+	}
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills the bucket for the time elapsed since the last
+// call and takes one token if available. Time comes from the Redis server so
+// every gateway replica shares the same clock. Numbers are formatted
+// explicitly because Lua would otherwise store microsecond timestamps with
+// only 14 significant digits.
+//
+// KEYS[1] bucket key
+// ARGV[1] capacity
+// ARGV[2] microseconds needed to earn one token
+//
+// Returns {allowed, remaining, microseconds until the bucket is full}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+if now > updated then
+	tokens = math.min(capacity, tokens + (now - updated) / interval)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+local refill = math.ceil((capacity - tokens) * interval)
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'updated', string.format('%.0f', now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(refill / 1000) + 1000)
+return {allowed, math.floor(tokens), refill}
+`)
+
+// TokenBucketLimiter implements rate limiting with a Redis token bucket.
+// Buckets hold up to burst tokens and refill at limit tokens per window.
+type TokenBucketLimiter struct {
+	client *redis.Client
+	window time.Duration
+	burst  int
+}
+
+// NewTokenBucketLimiter creates a token bucket limiter. A burst of zero
+// makes the bucket capacity equal to the limit.
+func NewTokenBucketLimiter(client *redis.Client, window time.Duration, burst int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		client: client,
+		window: window,
+		burst:  burst,
+	}
+}
+
+// Allow takes one token from the bucket for key.
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
+	now := time.Now()
+	if limit <= 0 {
+		return denied(limit, now), nil
+	}
+
+	interval := float64(emissionInterval(l.window, limit)) / float64(time.Microsecond)
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{key},
+		burstFor(l.burst, limit),
+		strconv.FormatFloat(interval, 'f', -1, 64),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("redis token bucket failed: %w", err)
+	}
+
+	return scriptResult(values, limit, now)
+}
+
+// InMemoryTokenBucketLimiter is the in-process counterpart of
+// TokenBucketLimiter, for testing or when Redis is not available. Keys are
+// forgotten once their bucket has refilled.
+type InMemoryTokenBucketLimiter struct {
+	store  *shardedStore[tokenBucket]
+	window time.Duration
+	burst  int
+	now    func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewInMemoryTokenBucketLimiter creates an in-memory token bucket limiter.
+func NewInMemoryTokenBucketLimiter(window time.Duration, burst int) *InMemoryTokenBucketLimiter {
+	return newInMemoryTokenBucketLimiter(window, burst, DefaultMaxKeys)
+}
+
+func newInMemoryTokenBucketLimiter(window time.Duration, burst, maxKeys int) *InMemoryTokenBucketLimiter {
+	return &InMemoryTokenBucketLimiter{
+		store:  newShardedStore[tokenBucket](maxKeys),
+		window: window,
+		burst:  burst,
+		now:    time.Now,
+	}
+}
+
+// Allow takes one token from the bucket for key.
+func (l *InMemoryTokenBucketLimiter) Allow(ctx context.Context, key string, limit int) (*Result, error) {
+	now := l.now()
+	if limit <= 0 {
+		return denied(limit, now), nil
+	}
+
+	capacity := float64(burstFor(l.burst, limit))
+	interval := emissionInterval(l.window, limit)
+
+	var allowed bool
+	var tokens float64
+	var full time.Time
+	l.store.update(key, now, func(bucket *tokenBucket) time.Time {
+		// A new key starts from the zero time, so it refills to capacity.
+		if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+			bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)/float64(interval))
+			bucket.updated = now
+		}
+
+		allowed = bucket.tokens >= 1
+		if allowed {
+			bucket.tokens--
+		}
+
+		tokens = bucket.tokens
+		full = now.Add(time.Duration(math.Ceil((capacity - bucket.tokens) * float64(interval))))
+		return full
+	})
+
+	return &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(tokens),
+		ResetAt:   full,
+	}, nil
+}
+
+// Stop ends the background eviction of idle keys.
+func (l *InMemoryTokenBucketLimiter) Stop() {
+	l.store.stop()
+}
+
+// scriptResult converts the {allowed, remaining, reset in microseconds}
+// reply shared by the Lua scripts.
+func scriptResult(values []int64, limit int, now time.Time) (*Result, error) {
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", values)
+	}
+
+	return &Result{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: int(values[1]),
+		ResetAt:   now.Add(time.Duration(values[2]) * time.Microsecond),
+	}, nil
+}
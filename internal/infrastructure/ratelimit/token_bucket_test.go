@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestInMemoryTokenBucket_AllowsBurstThenRejects(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := NewInMemoryTokenBucketLimiter(time.Minute, 3)
+	limiter.now = clock.Now
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "key", 60)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("request %d should be allowed within the burst", i)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("request %d: remaining = %d, want %d", i, result.Remaining, 2-i)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "key", 60)
+	if result.Allowed {
+		t.Error("request beyond the burst should be rejected")
+	}
+	if want := clock.now.Add(3 * time.Second); !result.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", result.ResetAt, want)
+	}
+}
+
+func TestInMemoryTokenBucket_Refills(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	limiter := NewInMemoryTokenBucketLimiter(time.Minute, 2)
+	limiter.now = clock.Now
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "key", 60)
+	limiter.Allow(ctx, "key", 60)
+
+	clock.Advance(500 * time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "key", 60); result.Allowed {
+		t.Fatal("half a token should not allow a request")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "key", 60); !result.Allowed {
+		t.Fatal("one refilled token should allow a request")
+	}
+
+	clock.Advance(time.Hour)
+	result, _ := limiter.Allow(ctx, "key", 60)
+	if result.Remaining != 1 {
+		t.Errorf("bucket should refill up to the burst only, remaining = %d", result.Remaining)
+	}
+}
+
+func TestInMemoryTokenBucket_BurstDefaultsToLimit(t *testing.T) {
+	limiter := NewInMemoryTokenBucketLimiter(time.Minute, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if result, _ := limiter.Allow(ctx, "key", 5); !result.Allowed {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if result, _ := limiter.Allow(ctx, "key", 5); result.Allowed {
+		t.Error("request beyond the limit should be rejected")
+	}
+}
+
+func TestInMemoryTokenBucket_ZeroLimitDenies(t *testing.T) {
+	limiter := NewInMemoryTokenBucketLimiter(time.Minute, 0)
+
+	result, err := limiter.Allow(context.Background(), "key", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Allowed {
+		t.Error("zero limit should deny")
+	}
+}
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireLock takes the lock when it is free and extends it when the caller
+// already owns it.
+var acquireLock = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 1
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// Lock implements domain.LeaderLock with a Redis key holding the owner's
+// random token. The lock is released by letting it expire.
+type Lock struct {
+	client *redis.Client
+	key    string
+	owner  string
+}
+
+// NewLock creates a lock named name, owned by this process.
+func NewLock(client *Client, name string) *Lock {
+	return &Lock{
+		client: client.Client,
+		key:    "gotway:lock:" + name,
+		owner:  uuid.New().String(),
+	}
+}
+
+func (l *Lock) TryAcquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLock.Run(ctx, l.client, []string{l.key}, l.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", l.key, err)
+	}
+	return acquired == 1, nil
+}
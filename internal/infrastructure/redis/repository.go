@@ -0,0 +1,535 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "gotway:registry:"
+
+	fieldData          = "data"
+	fieldStatus        = "status"
+	fieldLastHeartbeat = "last_heartbeat"
+)
+
+// setFieldIfExists updates a single hash field only when the hash exists, so a
+// late heartbeat or status change never resurrects a deleted instance.
+var setFieldIfExists = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// swapFieldIfEqual replaces a hash field only while it still holds the value
+// it was read with. It returns 0 when the hash is gone and -1 when the field
+// changed meanwhile.
+var swapFieldIfEqual = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[2] then
+	return -1
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+// maxSwapAttempts bounds how often a compare-and-swap is retried when other
+// writers keep changing the field.
+const maxSwapAttempts = 5
+
+// deleteInstance removes the instance. Once the last instance of the service
+// is gone it also drops the service and the routes it still owns, in the same
+// step, so a registration racing on another replica never loses its routes.
+var deleteInstance = redis.NewScript(`
+if redis.call('DEL', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+if redis.call('ZCARD', KEYS[2]) == 0 then
+	redis.call('SREM', KEYS[3], ARGV[2])
+	for _, key in ipairs(redis.call('SMEMBERS', KEYS[5])) do
+		local entry = redis.call('HGET', KEYS[4], key)
+		if entry and cjson.decode(entry)['service_name'] == ARGV[2] then
+			redis.call('HDEL', KEYS[4], key)
+		end
+	end
+	redis.call('DEL', KEYS[5])
+end
+return 1
+`)
+
+// saveRoutes stores route entries only when none of the keys is owned by
+// another service. On conflict it writes nothing and returns the conflicting
+// entries.
+//
+// KEYS[1] routes hash, KEYS[2] service routes set
+// ARGV[1] service name, followed by route key and entry pairs
+var saveRoutes = redis.NewScript(`
+local conflicts = {}
+for i = 2, #ARGV, 2 do
+	local entry = redis.call('HGET', KEYS[1], ARGV[i])
+	if entry and cjson.decode(entry)['service_name'] ~= ARGV[1] then
+		table.insert(conflicts, entry)
+	end
+end
+if #conflicts > 0 then
+	return conflicts
+end
+for i = 2, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+	redis.call('SADD', KEYS[2], ARGV[i])
+end
+return conflicts
+`)
+
+// deleteServiceRoutes removes the routes of a service, skipping any key that
+// has since been claimed by another service.
+var deleteServiceRoutes = redis.NewScript(`
+for _, key in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	local entry = redis.call('HGET', KEYS[1], key)
+	if entry and cjson.decode(entry)['service_name'] == ARGV[1] then
+		redis.call('HDEL', KEYS[1], key)
+	end
+end
+redis.call('DEL', KEYS[2])
+return 1
+`)
+
+// Repository implements domain.Repository on top of Redis so that every gateway
+// replica shares the same instances, routes and heartbeats.
+//
+// Layout:
+//
+//	gotway:registry:instance:<id>            hash with data, status and last_heartbeat
+//	gotway:registry:service:<name>:instances sorted set of instance IDs by registration time
+//	gotway:registry:service:<name>:routes    set of route keys owned by the service
+//	gotway:registry:services                 set of service names
+//	gotway:registry:routes                   hash of route key to route entry
+//	gotway:registry:traffic                  hash of service name to traffic policy
+type Repository struct {
+	client *redis.Client
+}
+
+// NewRepository creates a Redis-backed registry repository.
+func NewRepository(client *Client) *Repository {
+	return &Repository{client: client.Client}
+}
+
+func instanceKey(instanceID string) string {
+	return keyPrefix + "instance:" + instanceID
+}
+
+func serviceInstancesKey(serviceName string) string {
+	return keyPrefix + "service:" + serviceName + ":instances"
+}
+
+func serviceRoutesKey(serviceName string) string {
+	return keyPrefix + "service:" + serviceName + ":routes"
+}
+
+func servicesKey() string {
+	return keyPrefix + "services"
+}
+
+func routesKey() string {
+	return keyPrefix + "routes"
+}
+
+func trafficKey() string {
+	return keyPrefix + "traffic"
+}
+
+func (r *Repository) SaveInstance(ctx context.Context, instance *domain.ServiceInstance) error {
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return fmt.Errorf("failed to encode instance: %w", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, instanceKey(instance.ID),
+			fieldData, data,
+			fieldStatus, string(instance.Status),
+			fieldLastHeartbeat, strconv.FormatInt(instance.LastHeartbeat.UnixNano(), 10),
+		)
+		pipe.ZAdd(ctx, serviceInstancesKey(instance.ServiceName), redis.Z{
+			Score:  float64(instance.RegisteredAt.UnixNano()),
+			Member: instance.ID,
+		})
+		pipe.SAdd(ctx, servicesKey(), instance.ServiceName)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save instance: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetInstance(ctx context.Context, instanceID string) (*domain.ServiceInstance, error) {
+	fields, err := r.client.HGetAll(ctx, instanceKey(instanceID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, domain.ErrInstanceNotFound
+	}
+	return decodeInstance(fields)
+}
+
+func (r *Repository) GetInstancesByService(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	ids, err := r.client.ZRange(ctx, serviceInstancesKey(serviceName), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, instanceKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	instances := make([]*domain.ServiceInstance, 0, len(ids))
+	for _, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		instance, err := decodeInstance(fields)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (r *Repository) DeleteInstance(ctx context.Context, instanceID string) error {
+	instance, err := r.GetInstance(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{
+		instanceKey(instanceID),
+		serviceInstancesKey(instance.ServiceName),
+		servicesKey(),
+		routesKey(),
+		serviceRoutesKey(instance.ServiceName),
+	}
+	deleted, err := deleteInstance.Run(ctx, r.client, keys, instanceID, instance.ServiceName).Int()
+	if err != nil {
+		return fmt.Errorf("failed to delete instance: %w", err)
+	}
+	if deleted == 0 {
+		return domain.ErrInstanceNotFound
+	}
+	return nil
+}
+
+func (r *Repository) UpdateInstanceStatus(ctx context.Context, instanceID string, status domain.ServiceStatus) error {
+	return r.setInstanceField(ctx, instanceID, fieldStatus, string(status))
+}
+
+// UpdateInstanceWeight rewrites the weight inside the instance data with a
+// compare-and-swap, so it neither resurrects a deleted instance nor undoes a
+// concurrent write. Status and heartbeat live in their own fields and are
+// left alone.
+func (r *Repository) UpdateInstanceWeight(ctx context.Context, instanceID string, weight int) error {
+	key := instanceKey(instanceID)
+	for range maxSwapAttempts {
+		data, err := r.client.HGet(ctx, key, fieldData).Result()
+		if errors.Is(err, redis.Nil) {
+			return domain.ErrInstanceNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get instance: %w", err)
+		}
+
+		var instance domain.ServiceInstance
+		if err := json.Unmarshal([]byte(data), &instance); err != nil {
+			return fmt.Errorf("failed to decode instance: %w", err)
+		}
+		instance.Weight = weight
+		updated, err := json.Marshal(instance)
+		if err != nil {
+			return fmt.Errorf("failed to encode instance: %w", err)
+		}
+
+		swapped, err := swapFieldIfEqual.Run(ctx, r.client, []string{key}, fieldData, data, updated).Int()
+		if err != nil {
+			return fmt.Errorf("failed to update instance: %w", err)
+		}
+		switch swapped {
+		case 0:
+			return domain.ErrInstanceNotFound
+		case 1:
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to update instance: changed concurrently %d times", maxSwapAttempts)
+}
+
+func (r *Repository) UpdateInstanceStatusIf(ctx context.Context, instanceID string, from, to domain.ServiceStatus) (bool, error) {
+	swapped, err := swapFieldIfEqual.Run(ctx, r.client, []string{instanceKey(instanceID)}, fieldStatus, string(from), string(to)).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to update instance: %w", err)
+	}
+	if swapped == 0 {
+		return false, domain.ErrInstanceNotFound
+	}
+	return swapped == 1, nil
+}
+
+func (r *Repository) UpdateHeartbeat(ctx context.Context, instanceID string) error {
+	return r.setInstanceField(ctx, instanceID, fieldLastHeartbeat, strconv.FormatInt(time.Now().UnixNano(), 10))
+}
+
+func (r *Repository) setInstanceField(ctx context.Context, instanceID, field, value string) error {
+	updated, err := setFieldIfExists.Run(ctx, r.client, []string{instanceKey(instanceID)}, field, value).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update instance: %w", err)
+	}
+	if updated == 0 {
+		return domain.ErrInstanceNotFound
+	}
+	return nil
+}
+
+func (r *Repository) SaveRoutes(ctx context.Context, serviceName string, basePath string, routes []domain.Route) error {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	args := make([]any, 0, len(routes)*2+1)
+	args = append(args, serviceName)
+	for _, route := range routes {
+		data, err := json.Marshal(domain.RouteEntry{
+			ServiceName:  serviceName,
+			BasePath:     basePath,
+			Route:        route,
+			RegisteredAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode route: %w", err)
+		}
+		args = append(args, route.Key(basePath), data)
+	}
+
+	conflicts, err := saveRoutes.Run(ctx, r.client, []string{routesKey(), serviceRoutesKey(serviceName)}, args...).StringSlice()
+	if err != nil {
+		return fmt.Errorf("failed to save routes: %w", err)
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	collisions := make([]domain.RouteCollision, 0, len(conflicts))
+	for _, data := range conflicts {
+		var entry domain.RouteEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return fmt.Errorf("failed to decode route: %w", err)
+		}
+		collisions = append(collisions, domain.RouteCollision{
+			Method:        entry.Route.Method,
+			Path:          entry.Route.FullPath(entry.BasePath),
+			CollisionType: domain.ExactCollision,
+			RegisteredBy:  entry.ServiceName,
+			RegisteredAt:  entry.RegisteredAt,
+		})
+	}
+	return &domain.CollisionError{Collisions: collisions}
+}
+
+func (r *Repository) GetRoute(ctx context.Context, method, path string) (*domain.RouteEntry, error) {
+	data, err := r.client.HGet(ctx, routesKey(), method+":"+path).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrRouteNotFound
+		}
+		return nil, fmt.Errorf("failed to get route: %w", err)
+	}
+
+	var entry domain.RouteEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode route: %w", err)
+	}
+	return &entry, nil
+}
+
+func (r *Repository) GetAllRoutes(ctx context.Context) ([]domain.RouteEntry, error) {
+	all, err := r.client.HGetAll(ctx, routesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	entries := make([]domain.RouteEntry, 0, len(all))
+	for _, data := range all {
+		var entry domain.RouteEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode route: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *Repository) DeleteRoutesByService(ctx context.Context, serviceName string) error {
+	keys := []string{routesKey(), serviceRoutesKey(serviceName)}
+	if err := deleteServiceRoutes.Run(ctx, r.client, keys, serviceName).Err(); err != nil {
+		return fmt.Errorf("failed to delete service routes: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CheckRouteCollisions(ctx context.Context, basePath string, routes []domain.Route) ([]domain.RouteCollision, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(routes))
+	for i, route := range routes {
+		keys[i] = route.Key(basePath)
+	}
+
+	values, err := r.client.HMGet(ctx, routesKey(), keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check route collisions: %w", err)
+	}
+
+	var collisions []domain.RouteCollision
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var entry domain.RouteEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode route: %w", err)
+		}
+		collisions = append(collisions, domain.RouteCollision{
+			Method:        routes[i].Method,
+			Path:          routes[i].FullPath(basePath),
+			CollisionType: domain.ExactCollision,
+			RegisteredBy:  entry.ServiceName,
+			RegisteredAt:  entry.RegisteredAt,
+		})
+	}
+	return collisions, nil
+}
+
+func (r *Repository) GetAllServices(ctx context.Context) ([]string, error) {
+	names, err := r.client.SMembers(ctx, servicesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *Repository) GetHealthyInstances(ctx context.Context, serviceName string) ([]*domain.ServiceInstance, error) {
+	instances, err := r.GetInstancesByService(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var healthy []*domain.ServiceInstance
+	for _, instance := range instances {
+		if instance.IsHealthy() {
+			healthy = append(healthy, instance)
+		}
+	}
+	return healthy, nil
+}
+
+func (r *Repository) SaveTrafficPolicy(ctx context.Context, serviceName string, policy *domain.TrafficPolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to encode traffic policy: %w", err)
+	}
+	if err := r.client.HSet(ctx, trafficKey(), serviceName, data).Err(); err != nil {
+		return fmt.Errorf("failed to save traffic policy: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetTrafficPolicy(ctx context.Context, serviceName string) (*domain.TrafficPolicy, error) {
+	data, err := r.client.HGet(ctx, trafficKey(), serviceName).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrTrafficPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get traffic policy: %w", err)
+	}
+
+	var policy domain.TrafficPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode traffic policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (r *Repository) GetAllTrafficPolicies(ctx context.Context) (map[string]*domain.TrafficPolicy, error) {
+	all, err := r.client.HGetAll(ctx, trafficKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list traffic policies: %w", err)
+	}
+
+	policies := make(map[string]*domain.TrafficPolicy, len(all))
+	for serviceName, data := range all {
+		var policy domain.TrafficPolicy
+		if err := json.Unmarshal([]byte(data), &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode traffic policy: %w", err)
+		}
+		policies[serviceName] = &policy
+	}
+	return policies, nil
+}
+
+func (r *Repository) DeleteTrafficPolicy(ctx context.Context, serviceName string) error {
+	deleted, err := r.client.HDel(ctx, trafficKey(), serviceName).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete traffic policy: %w", err)
+	}
+	if deleted == 0 {
+		return domain.ErrTrafficPolicyNotFound
+	}
+	return nil
+}
+
+func decodeInstance(fields map[string]string) (*domain.ServiceInstance, error) {
+	var instance domain.ServiceInstance
+	if err := json.Unmarshal([]byte(fields[fieldData]), &instance); err != nil {
+		return nil, fmt.Errorf("failed to decode instance: %w", err)
+	}
+
+	if status, ok := fields[fieldStatus]; ok {
+		instance.Status = domain.ServiceStatus(status)
+	}
+	if heartbeat, ok := fields[fieldLastHeartbeat]; ok {
+		nanos, err := strconv.ParseInt(heartbeat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode heartbeat: %w", err)
+		}
+		instance.LastHeartbeat = time.Unix(0, nanos)
+	}
+	return &instance, nil
+}
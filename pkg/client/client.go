@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -36,10 +37,16 @@ type RegistryClient struct {
 	mu                sync.RWMutex
 	registered        bool
 	stopped           bool
+	draining          bool
+
+	drainTimeout time.Duration
+	inFlight     atomic.Int64
 
 	logger *slog.Logger
 }
 
+const drainPollInterval = 100 * time.Millisecond
+
 type Option func(*RegistryClient)
 
 func WithLogger(logger *slog.Logger) Option {
@@ -48,6 +55,14 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithDrainTimeout makes Shutdown drain the instance and wait up to timeout
+// for the requests counted by TrackInFlight to finish before deregistering.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(c *RegistryClient) {
+		c.drainTimeout = timeout
+	}
+}
+
 func NewRegistryClient(gatewayURL, privateKeyPEM, serviceName string, opts ...Option) (*RegistryClient, error) {
 	privKey, err := parseRSAPrivateKey(privateKeyPEM)
 	if err != nil {
@@ -189,6 +204,7 @@ func (c *RegistryClient) reregister(ctx context.Context) error {
 	c.mu.Lock()
 	c.instanceID = resp.InstanceID
 	c.heartbeatInterval = time.Duration(resp.HeartbeatInterval) * time.Second
+	draining := c.draining
 	c.mu.Unlock()
 
 	c.logger.Info("service re-registered",
@@ -196,6 +212,10 @@ func (c *RegistryClient) reregister(ctx context.Context) error {
 		"heartbeat_interval", resp.HeartbeatInterval,
 	)
 
+	// A new registration starts healthy; keep it out of rotation.
+	if draining {
+		return c.Drain(ctx)
+	}
 	return nil
 }
 
@@ -248,6 +268,84 @@ func (c *RegistryClient) Deregister(ctx context.Context) error {
 	return nil
 }
 
+// Drain asks the gateway to stop sending new requests to the instance. It
+// keeps its routes and heartbeat, so requests in flight can finish before it
+// deregisters.
+func (c *RegistryClient) Drain(ctx context.Context) error {
+	c.mu.RLock()
+	instanceID := c.instanceID
+	c.mu.RUnlock()
+
+	if instanceID == "" {
+		return fmt.Errorf("not registered")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"instance_id": instanceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.gatewayURL+"/internal/registry/drain", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	token, err := c.serviceToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate service token: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Service-Token", token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send drain: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+		}
+	}(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrInstanceNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("drain failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	c.mu.Lock()
+	c.draining = true
+	c.mu.Unlock()
+
+	c.logger.Info("service draining", "instance_id", instanceID)
+	return nil
+}
+
+// TrackInFlight counts the requests next is serving, which Shutdown waits
+// for when a drain timeout is set.
+func (c *RegistryClient) TrackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.inFlight.Add(1)
+		defer c.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InFlight returns the number of requests TrackInFlight is serving.
+func (c *RegistryClient) InFlight() int64 {
+	return c.inFlight.Load()
+}
+
+// Shutdown stops the heartbeat and deregisters the instance. With a drain
+// timeout set it drains the instance first and waits for the requests in
+// flight to finish, for up to the timeout.
 func (c *RegistryClient) Shutdown(ctx context.Context) error {
 	c.mu.Lock()
 	if c.stopped {
@@ -255,8 +353,17 @@ func (c *RegistryClient) Shutdown(ctx context.Context) error {
 		return nil
 	}
 	c.stopped = true
+	registered := c.registered
 	c.mu.Unlock()
 
+	if registered && c.drainTimeout > 0 {
+		if err := c.Drain(ctx); err != nil {
+			c.logger.Warn("drain failed, deregistering without waiting", "error", err)
+		} else {
+			c.waitInFlight(ctx)
+		}
+	}
+
 	close(c.stopCh)
 	if c.stopCancel != nil {
 		c.stopCancel()
@@ -277,6 +384,26 @@ func (c *RegistryClient) Shutdown(ctx context.Context) error {
 	return c.Deregister(ctx)
 }
 
+// waitInFlight waits until no request is in flight, the drain timeout passes
+// or ctx ends.
+func (c *RegistryClient) waitInFlight(ctx context.Context) {
+	timeout := time.NewTimer(c.drainTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for c.inFlight.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			c.logger.Warn("drain timed out with requests in flight", "in_flight", c.inFlight.Load())
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (c *RegistryClient) InstanceID() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -369,6 +369,94 @@ func TestShutdown_Deregisters(t *testing.T) {
 	}
 }
 
+func TestShutdown_DrainsAndWaitsForInFlight(t *testing.T) {
+	privPEM, _ := generateTestKeyPEM()
+	var drainedAt, deregisteredAt atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/internal/registry/register":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(RegisterResponse{InstanceID: "instance-123", HeartbeatInterval: 60})
+		case "/internal/registry/drain":
+			drainedAt.Store(time.Now().UnixNano())
+			w.WriteHeader(http.StatusOK)
+		case "/internal/registry/deregister":
+			deregisteredAt.Store(time.Now().UnixNano())
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewRegistryClient(server.URL, privPEM, "test-service", WithDrainTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("NewRegistryClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Register(ctx, RegisterRequest{ServiceName: "test-service", Host: "localhost", Port: 8081}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var finishedAt atomic.Int64
+	handler := client.TrackInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		finishedAt.Store(time.Now().UnixNano())
+	}))
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-started
+	if got := client.InFlight(); got != 1 {
+		t.Fatalf("expected 1 request in flight, got %d", got)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		close(release)
+	}()
+
+	if err := client.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if drainedAt.Load() == 0 || deregisteredAt.Load() == 0 {
+		t.Fatal("expected drain and deregister to be called")
+	}
+	if deregisteredAt.Load() < finishedAt.Load() {
+		t.Error("expected deregister only after the request in flight finished")
+	}
+	if drainedAt.Load() > finishedAt.Load() {
+		t.Error("expected the drain before waiting for the request in flight")
+	}
+}
+
+func TestDrain_ReturnsErrInstanceNotFound(t *testing.T) {
+	privPEM, _ := generateTestKeyPEM()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewRegistryClient(server.URL, privPEM, "test-service")
+	if err != nil {
+		t.Fatalf("NewRegistryClient() error = %v", err)
+	}
+	if err := client.Drain(context.Background()); err == nil {
+		t.Error("expected an error before registering")
+	}
+
+	client.instanceID = "instance-123"
+	if err := client.Drain(context.Background()); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
 func TestRetry_EventuallySucceeds(t *testing.T) {
 	privPEM, _ := generateTestKeyPEM()
 	var attemptCount int32
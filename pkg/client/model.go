@@ -7,23 +7,116 @@ import (
 
 var ErrInstanceNotFound = errors.New("instance not found")
 
+// MetadataLoadBalancer selects the gateway load balancing strategy for the
+// service: round_robin, weighted_round_robin, least_requests,
+// least_connections, power_of_two, random or ring_hash.
+const MetadataLoadBalancer = "lb_strategy"
+
+// MetadataHashKey selects what ring_hash keys on: "header=<name>",
+// "cookie=<name>", "ip" or "sub" (the authenticated user).
+const MetadataHashKey = "lb_hash_key"
+
+// MetadataUpstreamProtocol selects how the gateway talks to the instance:
+// "http1" (default) or "h2c" for HTTP/2 over cleartext.
+const MetadataUpstreamProtocol = "upstream_protocol"
+
+// MetadataZone names the zone the instance runs in; gateways configured with
+// the same zone prefer it.
+const MetadataZone = "zone"
+
 type Route struct {
-	Method    string   `json:"method"`
-	Path      string   `json:"path"`
-	Public    bool     `json:"public"`
-	RateLimit int      `json:"rate_limit,omitempty"`
-	Scopes    []string `json:"scopes,omitempty"`
+	Method        string       `json:"method"`
+	Path          string       `json:"path"`
+	Public        bool         `json:"public"`
+	RateLimit     int          `json:"rate_limit,omitempty"`
+	Scopes        []string     `json:"scopes,omitempty"`
+	TimeoutMs     int          `json:"timeout_ms,omitempty"`
+	Retry         *RetryPolicy `json:"retry,omitempty"`
+	Streaming     bool         `json:"streaming,omitempty"`
+	Rewrite       *RewriteRule `json:"rewrite,omitempty"`
+	ForwardParams bool         `json:"forward_params,omitempty"`
+	// Hosts, Headers and Query restrict the route to matching requests;
+	// "*.example.com" matches every subdomain of example.com.
+	Hosts   []string         `json:"hosts,omitempty"`
+	Headers []MatchPredicate `json:"headers,omitempty"`
+	Query   []MatchPredicate `json:"query,omitempty"`
+	// InstanceSelector sends the route only to instances whose Metadata has
+	// all of these labels.
+	InstanceSelector map[string]string `json:"instance_selector,omitempty"`
+	Mirror           *MirrorPolicy     `json:"mirror,omitempty"`
+}
+
+// MirrorPolicy copies Percent of the route's requests to Service, or to the
+// route's own service when empty, restricted to Version when set. Copies
+// carry an X-Mirrored-From header and their responses are discarded.
+type MirrorPolicy struct {
+	Service string `json:"service,omitempty"`
+	Version string `json:"version,omitempty"`
+	Percent int    `json:"percent"`
+}
+
+// MatchPredicate requires a header or query parameter, with Value or, when
+// empty, with any value.
+type MatchPredicate struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
 }
 
+// RetryPolicy asks the gateway to retry failed requests to the route on
+// another instance. RetryOn accepts "connect-failure", "timeout",
+// "gateway-error" (502, 503, 504), "5xx" and individual status codes; it
+// defaults to connect-failure and gateway-error. Only idempotent methods are
+// retried unless RetryNonIdempotent is set.
+type RetryPolicy struct {
+	MaxAttempts        int      `json:"max_attempts"`
+	PerTryTimeoutMs    int      `json:"per_try_timeout_ms,omitempty"`
+	RetryOn            []string `json:"retry_on,omitempty"`
+	BackoffBaseMs      int      `json:"backoff_base_ms,omitempty"`
+	BackoffMaxMs       int      `json:"backoff_max_ms,omitempty"`
+	RetryNonIdempotent bool     `json:"retry_non_idempotent,omitempty"`
+}
+
+// RewriteRule changes the path the gateway forwards to the service. Either
+// Template, filling {name} with the path parameter of that name, or any of
+// StripBasePath and Regex (with ReplaceAllString semantics) builds the new
+// path, in that order; Prefix is then prepended.
+type RewriteRule struct {
+	StripBasePath bool   `json:"strip_base_path,omitempty"`
+	Regex         string `json:"regex,omitempty"`
+	Replacement   string `json:"replacement,omitempty"`
+	Template      string `json:"template,omitempty"`
+	Prefix        string `json:"prefix,omitempty"`
+}
+
+// Schemes the gateway can reach an instance on.
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+// Protocols a service can speak. gRPC services register their methods as
+// POST routes, e.g. BasePath "/orders.v1.Orders" and Path "/GetOrder".
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// RegisterRequest describes the instance to register. Scheme defaults to
+// http; with https, TLSServerName sets the name the gateway verifies the
+// instance certificate against when it differs from Host.
 type RegisterRequest struct {
-	ServiceName string            `json:"service_name"`
-	Host        string            `json:"host"`
-	Port        int               `json:"port"`
-	HealthURL   string            `json:"health_url,omitempty"`
-	Version     string            `json:"version,omitempty"`
-	BasePath    string            `json:"base_path"`
-	Routes      []Route           `json:"routes"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	ServiceName   string            `json:"service_name"`
+	Host          string            `json:"host"`
+	Port          int               `json:"port"`
+	Scheme        string            `json:"scheme,omitempty"`
+	TLSServerName string            `json:"tls_server_name,omitempty"`
+	Protocol      string            `json:"protocol,omitempty"`
+	HealthURL     string            `json:"health_url,omitempty"`
+	Version       string            `json:"version,omitempty"`
+	Weight        int               `json:"weight,omitempty"`
+	BasePath      string            `json:"base_path"`
+	Routes        []Route           `json:"routes"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
 }
 
 type RegisterResponse struct {
@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/infrastructure/ratelimit"
+	"github.com/apascualco/gotway/internal/infrastructure/redis"
+)
+
+func newRedisLimiter(t *testing.T, algorithm ratelimit.Algorithm, window time.Duration, burst int) ratelimit.RateLimiter {
+	t.Helper()
+
+	client, err := redis.NewClient(os.Getenv("REDIS_URL"))
+	if err != nil {
+		t.Fatalf("failed to create redis client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiter, err := ratelimit.New(client.Client, ratelimit.Options{
+		Algorithm: algorithm,
+		Window:    window,
+		Burst:     burst,
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	return limiter
+}
+
+func TestRedisLimiters_BurstAndRefill(t *testing.T) {
+	for _, algorithm := range []ratelimit.Algorithm{ratelimit.AlgorithmTokenBucket, ratelimit.AlgorithmGCRA} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter := newRedisLimiter(t, algorithm, time.Second, 3)
+			ctx := context.Background()
+			key := fmt.Sprintf("ratelimit:test:%s:%d", algorithm, time.Now().UnixNano())
+
+			for i := 0; i < 3; i++ {
+				result, err := limiter.Allow(ctx, key, 10)
+				if err != nil {
+					t.Fatalf("allow failed: %v", err)
+				}
+				if !result.Allowed {
+					t.Fatalf("request %d should be allowed within the burst", i)
+				}
+				if result.Limit != 10 {
+					t.Errorf("Limit = %d, want 10", result.Limit)
+				}
+				if result.Remaining != 2-i {
+					t.Errorf("request %d: remaining = %d, want %d", i, result.Remaining, 2-i)
+				}
+				if !result.ResetAt.After(time.Now()) || result.ResetAt.After(time.Now().Add(time.Second)) {
+					t.Errorf("request %d: ResetAt %v should be within the window", i, result.ResetAt)
+				}
+			}
+
+			result, err := limiter.Allow(ctx, key, 10)
+			if err != nil {
+				t.Fatalf("allow failed: %v", err)
+			}
+			if result.Allowed {
+				t.Fatal("request beyond the burst should be rejected")
+			}
+			if result.Remaining != 0 {
+				t.Errorf("remaining = %d, want 0", result.Remaining)
+			}
+
+			// 10 per second earns one request back every 100ms.
+			time.Sleep(150 * time.Millisecond)
+			if result, _ := limiter.Allow(ctx, key, 10); !result.Allowed {
+				t.Error("request should be allowed after one emission interval")
+			}
+			if result, _ := limiter.Allow(ctx, key, 10); result.Allowed {
+				t.Error("only one request should have been earned back")
+			}
+		})
+	}
+}
+
+func TestRedisLimiters_SharedAcrossReplicas(t *testing.T) {
+	for _, algorithm := range []ratelimit.Algorithm{ratelimit.AlgorithmTokenBucket, ratelimit.AlgorithmGCRA} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			replicaA := newRedisLimiter(t, algorithm, time.Minute, 0)
+			replicaB := newRedisLimiter(t, algorithm, time.Minute, 0)
+			ctx := context.Background()
+			key := fmt.Sprintf("ratelimit:test:shared:%s:%d", algorithm, time.Now().UnixNano())
+
+			for i := 0; i < 2; i++ {
+				if result, _ := replicaA.Allow(ctx, key, 2); !result.Allowed {
+					t.Fatalf("request %d should be allowed", i)
+				}
+			}
+			if result, _ := replicaB.Allow(ctx, key, 2); result.Allowed {
+				t.Error("limit consumed on replica A should apply on replica B")
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apascualco/gotway/internal/application"
+	"github.com/apascualco/gotway/internal/domain"
+	"github.com/apascualco/gotway/internal/infrastructure/redis"
+)
+
+func newRedisRegistry(t *testing.T) *application.Registry {
+	t.Helper()
+
+	client, err := redis.NewClient(os.Getenv("REDIS_URL"))
+	if err != nil {
+		t.Fatalf("failed to create redis client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return application.NewRegistryWithRepository(application.RegistryConfig{
+		HeartbeatTTL: 30 * time.Second,
+	}, redis.NewRepository(client))
+}
+
+func TestRedisRegistry_SharedAcrossReplicas(t *testing.T) {
+	replicaA := newRedisRegistry(t)
+	replicaB := newRedisRegistry(t)
+
+	resp, err := replicaA.Register(&domain.RegisterRequest{
+		ServiceName: "replicated-service",
+		Host:        "localhost",
+		Port:        9101,
+		BasePath:    "/api/v1/replicated",
+		Routes:      []domain.Route{{Method: "GET", Path: "/items/:id"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	if instance := replicaB.GetInstance(resp.InstanceID); instance == nil {
+		t.Fatal("instance registered on replica A not visible on replica B")
+	}
+	if route := replicaB.GetRoute("GET", "/api/v1/replicated/items/:id"); route == nil {
+		t.Fatal("route registered on replica A not visible on replica B")
+	}
+	if healthy := replicaB.GetHealthyInstances("replicated-service"); len(healthy) != 1 {
+		t.Fatalf("expected 1 healthy instance on replica B, got %d", len(healthy))
+	}
+
+	before := replicaA.GetInstance(resp.InstanceID).LastHeartbeat
+	time.Sleep(10 * time.Millisecond)
+	if err := replicaB.Heartbeat(resp.InstanceID); err != nil {
+		t.Fatalf("heartbeat failed: %v", err)
+	}
+	if after := replicaA.GetInstance(resp.InstanceID).LastHeartbeat; !after.After(before) {
+		t.Error("heartbeat on replica B not visible on replica A")
+	}
+
+	_, err = replicaB.Register(&domain.RegisterRequest{
+		ServiceName: "other-service",
+		Host:        "localhost",
+		Port:        9102,
+		BasePath:    "/api/v1/replicated",
+		Routes:      []domain.Route{{Method: "GET", Path: "/items/:id"}},
+	})
+	if _, ok := err.(*domain.CollisionError); !ok {
+		t.Fatalf("expected collision error across replicas, got %v", err)
+	}
+
+	if err := replicaB.Deregister(resp.InstanceID); err != nil {
+		t.Fatalf("deregister failed: %v", err)
+	}
+	if instance := replicaA.GetInstance(resp.InstanceID); instance != nil {
+		t.Error("deregistered instance still visible on replica A")
+	}
+	if route := replicaA.GetRoute("GET", "/api/v1/replicated/items/:id"); route != nil {
+		t.Error("routes of last instance should be removed")
+	}
+}
+
+func TestRedisRegistry_HeartbeatUnknownInstance(t *testing.T) {
+	registry := newRedisRegistry(t)
+
+	if err := registry.Heartbeat("does-not-exist"); err != domain.ErrInstanceNotFound {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+func TestRedisRegistry_SetInstanceWeightKeepsOtherFields(t *testing.T) {
+	replicaA := newRedisRegistry(t)
+	replicaB := newRedisRegistry(t)
+
+	resp, err := replicaA.Register(&domain.RegisterRequest{
+		ServiceName: "weighted-service",
+		Host:        "localhost",
+		Port:        9103,
+		BasePath:    "/api/v1/weighted",
+		Metadata:    map[string]string{"zone": "eu-west-1a"},
+		Routes:      []domain.Route{{Method: "GET", Path: "/items"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	t.Cleanup(func() { _ = replicaA.Deregister(resp.InstanceID) })
+
+	if err := replicaB.SetInstanceStatus(resp.InstanceID, domain.StatusDisabled); err != nil {
+		t.Fatalf("set status failed: %v", err)
+	}
+	if err := replicaA.SetInstanceWeight(resp.InstanceID, 7); err != nil {
+		t.Fatalf("set weight failed: %v", err)
+	}
+
+	instance := replicaB.GetInstance(resp.InstanceID)
+	if instance.Weight != 7 || instance.Status != domain.StatusDisabled {
+		t.Errorf("expected weight 7 and disabled, got %d and %s", instance.Weight, instance.Status)
+	}
+	if instance.Port != 9103 || instance.Zone() != "eu-west-1a" {
+		t.Errorf("expected the rest of the instance unchanged, got %+v", instance)
+	}
+
+	if err := replicaB.Deregister(resp.InstanceID); err != nil {
+		t.Fatalf("deregister failed: %v", err)
+	}
+	if err := replicaA.SetInstanceWeight(resp.InstanceID, 3); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+	if replicaA.GetInstance(resp.InstanceID) != nil {
+		t.Error("setting the weight resurrected a deleted instance")
+	}
+}
+
+func TestRedisRegistry_SetInstanceStatusIfKeepsDrain(t *testing.T) {
+	replicaA := newRedisRegistry(t)
+	replicaB := newRedisRegistry(t)
+
+	resp, err := replicaA.Register(&domain.RegisterRequest{
+		ServiceName: "draining-service",
+		Host:        "localhost",
+		Port:        9104,
+		BasePath:    "/api/v1/draining",
+		Routes:      []domain.Route{{Method: "GET", Path: "/items"}},
+	})
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	t.Cleanup(func() { _ = replicaA.Deregister(resp.InstanceID) })
+
+	if err := replicaB.DrainInstance(resp.InstanceID); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	updated, err := replicaA.SetInstanceStatusIf(resp.InstanceID, domain.StatusHealthy, domain.StatusUnhealthy)
+	if err != nil || updated {
+		t.Errorf("expected no update from a stale status, got %v %v", updated, err)
+	}
+	if got := replicaA.GetInstance(resp.InstanceID).Status; got != domain.StatusDraining {
+		t.Errorf("expected draining, got %s", got)
+	}
+
+	updated, err = replicaA.SetInstanceStatusIf(resp.InstanceID, domain.StatusDraining, domain.StatusHealthy)
+	if err != nil || !updated {
+		t.Errorf("expected the update from the current status, got %v %v", updated, err)
+	}
+
+	_ = replicaB.Deregister(resp.InstanceID)
+	if _, err := replicaA.SetInstanceStatusIf(resp.InstanceID, domain.StatusHealthy, domain.StatusUnhealthy); !errors.Is(err, domain.ErrInstanceNotFound) {
+		t.Errorf("expected ErrInstanceNotFound, got %v", err)
+	}
+	if replicaA.GetInstance(resp.InstanceID) != nil {
+		t.Error("a status update resurrected a deleted instance")
+	}
+}
+
+func TestRedisRegistry_ConcurrentRegistrationKeepsSingleOwner(t *testing.T) {
+	replicas := []*application.Registry{newRedisRegistry(t), newRedisRegistry(t), newRedisRegistry(t)}
+
+	type outcome struct {
+		resp *domain.RegisterResponse
+		err  error
+	}
+	results := make([]outcome, len(replicas))
+
+	var wg sync.WaitGroup
+	for i, replica := range replicas {
+		wg.Add(1)
+		go func(i int, replica *application.Registry) {
+			defer wg.Done()
+			resp, err := replica.Register(&domain.RegisterRequest{
+				ServiceName: fmt.Sprintf("racing-service-%d", i),
+				Host:        "localhost",
+				Port:        9200 + i,
+				BasePath:    "/api/v1/racing",
+				Routes:      []domain.Route{{Method: "GET", Path: "/items"}},
+			})
+			results[i] = outcome{resp, err}
+		}(i, replica)
+	}
+	wg.Wait()
+
+	var winner *domain.RegisterResponse
+	for _, result := range results {
+		if result.err == nil {
+			if winner != nil {
+				t.Fatal("more than one service registered the same route")
+			}
+			winner = result.resp
+			continue
+		}
+		if _, ok := result.err.(*domain.CollisionError); !ok {
+			t.Fatalf("expected collision error, got %v", result.err)
+		}
+	}
+	if winner == nil {
+		t.Fatal("expected one registration to succeed")
+	}
+
+	route := replicas[0].GetRoute("GET", "/api/v1/racing/items")
+	if route == nil {
+		t.Fatal("route of the winning service is missing")
+	}
+	if owner := replicas[0].GetInstance(winner.InstanceID); owner == nil || owner.ServiceName != route.ServiceName {
+		t.Errorf("route owned by %s, which is not the registered service", route.ServiceName)
+	}
+	for i := range replicas {
+		name := fmt.Sprintf("racing-service-%d", i)
+		if name != route.ServiceName && len(replicas[0].GetInstances(name)) > 0 {
+			t.Errorf("losing service %s left an instance behind", name)
+		}
+	}
+}
+
+func TestRedisLock_SingleHolder(t *testing.T) {
+	client, err := redis.NewClient(os.Getenv("REDIS_URL"))
+	if err != nil {
+		t.Fatalf("failed to create redis client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	name := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	replicaA, replicaB := redis.NewLock(client, name), redis.NewLock(client, name)
+	ctx := context.Background()
+
+	if held, err := replicaA.TryAcquire(ctx, 200*time.Millisecond); err != nil || !held {
+		t.Fatalf("replica A should acquire a free lock, got %v, %v", held, err)
+	}
+	if held, _ := replicaB.TryAcquire(ctx, 200*time.Millisecond); held {
+		t.Fatal("replica B should not acquire a held lock")
+	}
+	if held, _ := replicaA.TryAcquire(ctx, 200*time.Millisecond); !held {
+		t.Fatal("replica A should renew its own lock")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if held, _ := replicaB.TryAcquire(ctx, 200*time.Millisecond); !held {
+		t.Error("replica B should acquire the lock once it expires")
+	}
+}
@@ -125,6 +125,9 @@ func setupGateway() error {
 	if err := os.Setenv("RATE_LIMIT_IP_RPM", "60"); err != nil {
 		return fmt.Errorf("failed to set RATE_LIMIT_IP_RPM: %w", err)
 	}
+	if err := os.Setenv("REGISTRY_STORE", "redis"); err != nil {
+		return fmt.Errorf("failed to set REGISTRY_STORE: %w", err)
+	}
 	if err := os.Setenv("JWT_PUBLIC_KEY", string(pubPEM)); err != nil {
 		return fmt.Errorf("failed to set JWT_PUBLIC_KEY: %w", err)
 	}